@@ -0,0 +1,35 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ccpeng/kube-replay/internal/auth"
+)
+
+// authorizeWrite returns an error unless the caller may record snapshots
+func authorizeWrite(ctx context.Context) error {
+	identity := auth.IdentityFrom(ctx)
+	if identity == nil {
+		return auth.ErrUnauthenticated
+	}
+	if !identity.HasRole(auth.RoleWriter) {
+		return fmt.Errorf("%w: %s may not record snapshots", auth.ErrForbidden, identity.Subject)
+	}
+
+	return nil
+}
+
+// authorizeRead returns an error unless the caller may replay the cluster. What they see of it is up to the policy
+// applied by the replayer.
+func (r *Resolver) authorizeRead(ctx context.Context) error {
+	identity := auth.IdentityFrom(ctx)
+	if identity == nil {
+		return auth.ErrUnauthenticated
+	}
+	if !identity.CanRead(r.ClusterName) {
+		return fmt.Errorf("%w: %s may not replay cluster %s", auth.ErrForbidden, identity.Subject, r.ClusterName)
+	}
+
+	return nil
+}
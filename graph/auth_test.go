@@ -0,0 +1,92 @@
+package graph_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/graph"
+	"github.com/ccpeng/kube-replay/internal/auth"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/policy"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+// fixedIdentity authenticates every request as the same identity
+type fixedIdentity struct {
+	*auth.Identity
+}
+
+func (f fixedIdentity) Authenticate(*http.Request) (*auth.Identity, error) {
+	return f.Identity, nil
+}
+
+func TestAuthorization(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	store := repositories.NewMemoryStore()
+	err := store.Upsert(context.Background(), &data.NodeMeta{
+		ID:        "node-a",
+		Snapshots: data.NodeSnapshots{{Timestamp: t0}},
+		Pods: []*data.PodMeta{
+			{ID: "pod-a", Name: "api", Namespace: "team-a", Snapshots: data.PodSnapshots{{Timestamp: t0}}},
+			{ID: "pod-b", Name: "db", Namespace: "team-b", Snapshots: data.PodSnapshots{{Timestamp: t0}}},
+		},
+	})
+	g.Expect(err).To(gomega.BeNil())
+
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers: &graph.Resolver{Replayer: policy.NewReplayer(services.NewReplayerWithStore(store)), ClusterName: "prod"},
+	}))
+	srv.AddTransport(transport.POST{})
+	as := func(identity *auth.Identity) *client.Client {
+		return client.New(auth.Middleware(fixedIdentity{identity})(srv))
+	}
+
+	teamA := as(&auth.Identity{Subject: "team-a", Roles: []auth.Role{auth.RoleReader}, Clusters: []string{"prod"}, Namespaces: []string{"team-a"}})
+	collector := as(&auth.Identity{Subject: "collector", Roles: []auth.Role{auth.RoleWriter}})
+	staging := as(&auth.Identity{Subject: "staging", Roles: []auth.Role{auth.RoleReader}, Clusters: []string{"staging"}, Namespaces: []string{auth.Wildcard}})
+
+	var resp struct {
+		NodeStatesAtTimestamp struct {
+			Nodes []struct {
+				Pods []struct {
+					Namespace string
+				}
+			}
+		}
+	}
+	query := `{ nodeStatesAtTimestamp(timestamp: "2025-04-27T00:00:00Z") { nodes { pods { namespace } } } }`
+
+	// readers only see pods in their namespaces
+	err = teamA.Post(query, &resp)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(resp.NodeStatesAtTimestamp.Nodes).To(gomega.HaveLen(1))
+	g.Expect(resp.NodeStatesAtTimestamp.Nodes[0].Pods).To(gomega.HaveLen(1))
+	g.Expect(resp.NodeStatesAtTimestamp.Nodes[0].Pods[0].Namespace).To(gomega.Equal("team-a"))
+
+	err = teamA.Post(`{ podHistory(namespace: "team-b", name: "db", start: "2025-04-27T00:00:00Z", end: "2025-04-27T01:00:00Z") { name } }`, &resp)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("forbidden: team-a may not replay namespace team-b")))
+
+	err = teamA.Post(`mutation { recordNodeDeletion(input: {id: "node-a", deletedAt: "2025-04-27T01:00:00Z"}) }`, &resp)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("forbidden: team-a may not record snapshots")))
+
+	// writers don't read, and readers only read their clusters
+	err = collector.Post(query, &resp)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("forbidden: collector may not replay cluster prod")))
+
+	err = staging.Post(query, &resp)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("forbidden: staging may not replay cluster prod")))
+
+	var mutation map[string]interface{}
+	err = collector.Post(`mutation { recordNodeDeletion(input: {id: "node-a", deletedAt: "2025-04-27T01:00:00Z"}) }`, &mutation)
+	g.Expect(err).To(gomega.BeNil())
+}
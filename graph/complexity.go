@@ -0,0 +1,52 @@
+package graph
+
+import (
+	"math"
+	"time"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+// unboundedPageSize is the page size assumed for a connection queried without first
+const unboundedPageSize = 100
+
+// NewComplexityRoot returns complexity functions weighing range queries by the number of frames they replay and
+// connections by their page size, for extension.FixedComplexityLimit to reject queries building huge payloads
+func NewComplexityRoot() ComplexityRoot {
+	var complexity ComplexityRoot
+
+	complexity.Query.NodeStatesRange = func(childComplexity int, start time.Time, end time.Time, step int64, filter *model.SnapshotFilter) int {
+		frames, err := services.FrameCount(start, end, step)
+		if err != nil {
+			// left for the resolver to reject with a clearer error
+			return childComplexity
+		}
+
+		return multiply(childComplexity, frames)
+	}
+	complexity.TimedNodeSnapshots.NodesConnection = connectionComplexity
+	complexity.NodeSnapshot.PodsConnection = connectionComplexity
+
+	return complexity
+}
+
+func connectionComplexity(childComplexity int, first *int32, after *string) int {
+	if first == nil {
+		return multiply(childComplexity, unboundedPageSize)
+	}
+
+	return multiply(childComplexity, int64(*first))
+}
+
+// multiply returns complexity times n, saturating instead of overflowing
+func multiply(complexity int, n int64) int {
+	if n <= 0 {
+		return complexity
+	}
+	if int64(complexity) > math.MaxInt/n {
+		return math.MaxInt
+	}
+
+	return complexity * int(n)
+}
@@ -0,0 +1,49 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/graph"
+	"github.com/ccpeng/kube-replay/internal/auth"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+func TestComplexityLimit(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  &graph.Resolver{Replayer: services.NewReplayerWithStore(repositories.NewMemoryStore())},
+		Complexity: graph.NewComplexityRoot(),
+	}))
+	srv.AddTransport(transport.POST{})
+	srv.Use(extension.FixedComplexityLimit(1000))
+	c := client.New(auth.Middleware(auth.Disabled)(srv))
+
+	var resp struct {
+		NodeStatesRange []struct {
+			Timestamp string
+			Nodes     []struct {
+				ID string
+			}
+		}
+	}
+
+	// 1 hour every minute is 61 frames of 2 fields each
+	err := c.Post(`{ nodeStatesRange(start: "2025-04-27T00:00:00Z", end: "2025-04-27T01:00:00Z", step: 60) { timestamp nodes { id } } }`, &resp)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(resp.NodeStatesRange).To(gomega.HaveLen(61))
+
+	// 1 hour every second is 3601 frames
+	err = c.Post(`{ nodeStatesRange(start: "2025-04-27T00:00:00Z", end: "2025-04-27T01:00:00Z", step: 1) { timestamp nodes { id } } }`, &resp)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("exceeds the limit of 1000")))
+
+	err = c.Post(`{ nodeStatesRange(start: "2025-04-27T00:00:00Z", end: "2025-04-27T01:00:00Z", step: 0) { timestamp } }`, &resp)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("invalid range: step must be")))
+}
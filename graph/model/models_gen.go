@@ -10,6 +10,86 @@ import (
 	"time"
 )
 
+// A Pod running on a Node when an incident started.
+type AffectedPod struct {
+	PodID     string `json:"podID"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Phase when the incident started.
+	Phase PodPhase `json:"phase"`
+	// Phase changes until the end of the window.
+	Changes []*PodPhaseChange `json:"changes"`
+	// Null unless deleted before the end of the window.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
+
+// Summary of the affinity of a Pod, with terms written as label selectors.
+type Affinity struct {
+	// Node selector terms, any of which a Node must match.
+	RequiredNodeTerms []string `json:"requiredNodeTerms"`
+	// Node selector terms Nodes are preferred by.
+	PreferredNodeTerms []string        `json:"preferredNodeTerms"`
+	PodAffinity        []*AffinityTerm `json:"podAffinity"`
+	PodAntiAffinity    []*AffinityTerm `json:"podAntiAffinity"`
+}
+
+// Summary of the affinity of a Pod. Terms are written as label selectors, e.g.
+// `topology.kubernetes.io/zone in (us-west-2a),!spot` for a node selector term.
+type AffinityInput struct {
+	RequiredNodeTerms  []string             `json:"requiredNodeTerms,omitempty"`
+	PreferredNodeTerms []string             `json:"preferredNodeTerms,omitempty"`
+	PodAffinity        []*AffinityTermInput `json:"podAffinity,omitempty"`
+	PodAntiAffinity    []*AffinityTermInput `json:"podAntiAffinity,omitempty"`
+}
+
+// Pods a Pod is (anti-)affine to, within the same value of a Node label.
+type AffinityTerm struct {
+	LabelSelector string `json:"labelSelector"`
+	TopologyKey   string `json:"topologyKey"`
+	// Whether it's required for scheduling, rather than preferred.
+	Required bool `json:"required"`
+}
+
+type AffinityTermInput struct {
+	LabelSelector string `json:"labelSelector"`
+	TopologyKey   string `json:"topologyKey"`
+	Required      *bool  `json:"required,omitempty"`
+}
+
+type CapacityPlan struct {
+	Start  time.Time                  `json:"start"`
+	End    time.Time                  `json:"end"`
+	Groups []*NodeGroupRecommendation `json:"groups"`
+}
+
+// Change to a Node, or a Pod bound to it, first seen at *timestamp*.
+type ClusterEvent struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Kind      ClusterEventKind `json:"kind"`
+	NodeID    string           `json:"nodeID"`
+	NodeName  string           `json:"nodeName"`
+	PodID     *string          `json:"podID,omitempty"`
+	Namespace *string          `json:"namespace,omitempty"`
+	PodName   *string          `json:"podName,omitempty"`
+	Message   string           `json:"message"`
+}
+
+type ComponentVersions struct {
+	Component NodeComponent `json:"component"`
+	// Oldest first.
+	Versions []*VersionCount `json:"versions"`
+}
+
+// A container image, by reference and the digest it was resolved to.
+type ContainerImage struct {
+	// Reference the containers were created with, e.g. `nginx:1.27`.
+	Image string `json:"image"`
+	// Empty until a container of the image started.
+	ImageID string `json:"imageID"`
+	// Ordered by namespace and name.
+	Pods []*ImagePod `json:"pods"`
+}
+
 type ContainerLastState struct {
 	ExitCode   *int64     `json:"exitCode,omitempty"`
 	StartedAt  *time.Time `json:"startedAt,omitempty"`
@@ -82,9 +162,108 @@ type ContainerStateInput struct {
 	Reason     *string    `json:"reason,omitempty"`
 }
 
+// Cost of the Nodes over a window, from their hourly prices by instance type,
+// split between the Pods bound to them by their share of the CPU and memory
+// requested of each Node.
+type CostReport struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Cost of every priced Node.
+	TotalCost float64 `json:"totalCost"`
+	// Cost of Nodes while nothing was requested of them.
+	IdleCost float64 `json:"idleCost"`
+	// Instance types missing from the price table, whose Nodes aren't counted. Nodes
+	// without an instance type label are listed as `unknown`.
+	UnpricedInstanceTypes []string `json:"unpricedInstanceTypes"`
+	// Ordered by namespace.
+	Namespaces []*NamespaceCost `json:"namespaces"`
+}
+
+type DrainSimulation struct {
+	Timestamp time.Time `json:"timestamp"`
+	NodeID    string    `json:"nodeID"`
+	NodeName  string    `json:"nodeName"`
+	// Whether every Pod of the Node could be rescheduled onto the others.
+	Feasible bool `json:"feasible"`
+	// Pods in order of rescheduling, those requesting the most first.
+	Placements []*PodPlacement `json:"placements"`
+}
+
+// Pod to simulate the scheduling of, by what the scheduler considers for it.
+type HypotheticalPodInput struct {
+	Name      string  `json:"name"`
+	Namespace *string `json:"namespace,omitempty"`
+	// Sum of the requests of its containers.
+	Requests    *ContainerResourceInput `json:"requests"`
+	Tolerations []*TolerationInput      `json:"tolerations,omitempty"`
+	// Labels a Node must have for the Pod to fit on it.
+	NodeSelector []*LabelInput `json:"nodeSelector,omitempty"`
+}
+
+// A Pod running containers of an image.
+type ImagePod struct {
+	PodID     string `json:"podID"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	NodeName  string `json:"nodeName"`
+}
+
+// When a Pod was seen running an image.
+type ImagePodSighting struct {
+	PodID     string    `json:"podID"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	NodeName  string    `json:"nodeName"`
+	Image     string    `json:"image"`
+	ImageID   string    `json:"imageID"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+type ImageSightings struct {
+	// First time any Pod was seen running the image, null if none was.
+	FirstSeen *time.Time `json:"firstSeen,omitempty"`
+	// Last time any Pod was seen running the image, null if none was.
+	LastSeen *time.Time `json:"lastSeen,omitempty"`
+	// Ordered by when they were first seen.
+	Pods []*ImagePodSighting `json:"pods"`
+}
+
+type ImageVersion struct {
+	Image string `json:"image"`
+	Pods  int32  `json:"pods"`
+}
+
+type Label struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type LabelInput struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Nodes and Pods at *timestamp* as Kubernetes API objects, with their status,
+// for tools expecting those such as kubectl plugins or scheduler simulators.
+type Manifests struct {
+	Timestamp time.Time `json:"timestamp"`
+	// `v1.NodeList` encoded as JSON.
+	NodeList string `json:"nodeList"`
+	// `v1.PodList` encoded as JSON.
+	PodList string `json:"podList"`
+}
+
 type Mutation struct {
 }
 
+type NamespaceCost struct {
+	Namespace string  `json:"namespace"`
+	Cost      float64 `json:"cost"`
+	// Ordered by name.
+	Pods []*PodCost `json:"pods"`
+}
+
 // CPU and memory capacity/allocatable for a node.
 type NodeCapacity struct {
 	CPU              string `json:"cpu"`
@@ -100,6 +279,80 @@ type NodeCapacityInput struct {
 	Pods             *int64 `json:"pods,omitempty"`
 }
 
+// Tombstone recording that a Node left the cluster.
+type NodeDeletionInput struct {
+	ID        string    `json:"id"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+type NodeFilter struct {
+	Names []string `json:"names,omitempty"`
+	// Matches nodes having any of the roles.
+	Roles []string `json:"roles,omitempty"`
+	// Kubernetes label selector, e.g. `topology.kubernetes.io/zone in (us-west-2a,us-west-2b),!spot`.
+	LabelSelector *string `json:"labelSelector,omitempty"`
+}
+
+// Whether a Pod fits on a Node, and if not why.
+type NodeFit struct {
+	NodeID   string   `json:"nodeID"`
+	NodeName string   `json:"nodeName"`
+	Fits     bool     `json:"fits"`
+	Reasons  []string `json:"reasons"`
+	// Share of allocatable CPU and memory requested once the Pod is placed, from 0 to 1.
+	Score float64 `json:"score"`
+}
+
+// Capacity a group of interchangeable Nodes needed over a window.
+type NodeGroupRecommendation struct {
+	// Roles of the Nodes, comma-separated.
+	Roles        string `json:"roles"`
+	Architecture string `json:"architecture"`
+	// Scheme of the provider ID of the Nodes, e.g. `aws`.
+	Provider string `json:"provider"`
+	// From the `node.kubernetes.io/instance-type` label.
+	InstanceType string `json:"instanceType"`
+	// Most Nodes of the group at once.
+	NodeCount int32 `json:"nodeCount"`
+	// Fewest Nodes of the smallest allocatable in the group that would have fit what was requested at every instant.
+	RecommendedNodeCount int32 `json:"recommendedNodeCount"`
+	// Smallest allocatable of a Node of the group.
+	NodeAllocatable *ResourceQuantities `json:"nodeAllocatable"`
+	PeakRequested   *ResourceQuantities `json:"peakRequested"`
+	// Requested for at least 95% of the window.
+	P95Requested *ResourceQuantities `json:"p95Requested"`
+	PeakLimits   *ResourceQuantities `json:"peakLimits"`
+	// Allocatable never requested at any instant of the window.
+	Stranded *ResourceQuantities `json:"stranded"`
+}
+
+// How many Nodes of a group ran each version of a component, and how many ran
+// the target or newer.
+type NodeGroupUpgrade struct {
+	Roles        string `json:"roles"`
+	Architecture string `json:"architecture"`
+	Provider     string `json:"provider"`
+	InstanceType string `json:"instanceType"`
+	Nodes        int32  `json:"nodes"`
+	Upgraded     int32  `json:"upgraded"`
+	// Oldest first.
+	Versions []*VersionCount `json:"versions"`
+}
+
+// Something going wrong with a Node, and the Pods running on it when it did.
+type NodeIncident struct {
+	NodeID    string           `json:"nodeID"`
+	NodeName  string           `json:"nodeName"`
+	Kind      NodeIncidentKind `json:"kind"`
+	StartedAt time.Time        `json:"startedAt"`
+	// Null if still ongoing at the end of the window.
+	EndedAt *time.Time `json:"endedAt,omitempty"`
+	Detail  string     `json:"detail"`
+	// Times the Node went NotReady, for NotReady and Flapping incidents.
+	Transitions int32          `json:"transitions"`
+	Pods        []*AffectedPod `json:"pods"`
+}
+
 type NodeInfo struct {
 	Architecture            string  `json:"architecture"`
 	ContainerRuntimeVersion string  `json:"containerRuntimeVersion"`
@@ -132,10 +385,25 @@ type NodeSnapshot struct {
 	Timestamp  time.Time      `json:"timestamp"`
 	Name       string         `json:"name"`
 	Roles      []string       `json:"roles,omitempty"`
+	Labels     []*Label       `json:"labels,omitempty"`
 	ProviderID *string        `json:"providerID,omitempty"`
 	Info       *NodeInfo      `json:"info"`
 	State      *NodeState     `json:"state"`
 	Pods       []*PodSnapshot `json:"pods"`
+	// Relay‑style pages of *pods*, ordered by namespace and name.
+	PodsConnection *PodSnapshotConnection `json:"podsConnection"`
+	DeletedAt      *time.Time             `json:"deletedAt,omitempty"`
+}
+
+type NodeSnapshotConnection struct {
+	TotalCount int32               `json:"totalCount"`
+	Edges      []*NodeSnapshotEdge `json:"edges"`
+	PageInfo   *PageInfo           `json:"pageInfo"`
+}
+
+type NodeSnapshotEdge struct {
+	Cursor string        `json:"cursor"`
+	Node   *NodeSnapshot `json:"node"`
 }
 
 type NodeSnapshotInput struct {
@@ -143,6 +411,7 @@ type NodeSnapshotInput struct {
 	Timestamp  time.Time           `json:"timestamp"`
 	Name       string              `json:"name"`
 	Roles      []string            `json:"roles,omitempty"`
+	Labels     []*LabelInput       `json:"labels,omitempty"`
 	ProviderID *string             `json:"providerID,omitempty"`
 	Info       *NodeInfoInput      `json:"info"`
 	State      *NodeStateInput     `json:"state"`
@@ -181,6 +450,132 @@ type NodeTaintInput struct {
 	TimeAdded *time.Time `json:"timeAdded,omitempty"`
 }
 
+type OwnerReference struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	UID        string `json:"uid"`
+	Controller bool   `json:"controller"`
+}
+
+type OwnerReferenceInput struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	UID        string `json:"uid"`
+	Controller *bool  `json:"controller,omitempty"`
+}
+
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
+type PendingCauseCount struct {
+	Cause PendingCause `json:"cause"`
+	Nodes int32        `json:"nodes"`
+}
+
+// A Pod that was Pending for a while. One the scheduler hadn't bound is
+// explained by fitting it onto the replayed state of the cluster.
+type PendingPod struct {
+	PodID     string `json:"podID"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Node it was bound to when explained, null if it wasn't bound to one.
+	NodeID   *string   `json:"nodeID,omitempty"`
+	NodeName *string   `json:"nodeName,omitempty"`
+	Since    time.Time `json:"since"`
+	// When it left Pending, was deleted or the window closed.
+	Until          time.Time `json:"until"`
+	PendingSeconds int64     `json:"pendingSeconds"`
+	// Still Pending when the window closed.
+	Ongoing bool `json:"ongoing"`
+	// Instant of the replayed state explaining it, when it was first Pending within the window.
+	ExplainedAt time.Time `json:"explainedAt"`
+	// How many Nodes couldn't take it for each cause. Empty for a Pod bound to a
+	// Node, which was Pending for something other than scheduling.
+	Causes []*PendingCauseCount `json:"causes"`
+	// Why each Node couldn't take it, prefixed by the Node name.
+	Reasons []string `json:"reasons"`
+}
+
+type PhaseCount struct {
+	Phase PodPhase `json:"phase"`
+	Pods  int32    `json:"pods"`
+}
+
+// Span of time a Pod UID was observed bound to a Node.
+type PodBinding struct {
+	PodID  string    `json:"podID"`
+	NodeID string    `json:"nodeID"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+}
+
+// Cost attributed to the Pods named *namespace*/*name*, across the UIDs they
+// were recreated with.
+type PodCost struct {
+	Namespace string  `json:"namespace"`
+	Name      string  `json:"name"`
+	Cost      float64 `json:"cost"`
+}
+
+// Tombstone recording that a Pod was deleted from the Node it was bound to.
+type PodDeletionInput struct {
+	ID string `json:"id"`
+	// Null for a Pod deleted before it was bound.
+	NodeID    *string   `json:"nodeID,omitempty"`
+	DeletedAt time.Time `json:"deletedAt"`
+	DeletedBy *string   `json:"deletedBy,omitempty"`
+}
+
+type PodFilter struct {
+	Namespaces []string      `json:"namespaces,omitempty"`
+	Phases     []PodPhase    `json:"phases,omitempty"`
+	QosClasses []PodQOSClass `json:"qosClasses,omitempty"`
+	// Matches pods with any container whose image contains the substring.
+	Image *string `json:"image,omitempty"`
+	// Matches pods with any container restarted at least this many times.
+	MinRestartCount *int64 `json:"minRestartCount,omitempty"`
+	// Kubernetes label selector, e.g. `app=web,tier!=cache`.
+	LabelSelector *string `json:"labelSelector,omitempty"`
+}
+
+type PodFitSimulation struct {
+	Timestamp time.Time `json:"timestamp"`
+	Fits      bool      `json:"fits"`
+	// Node the Pod would be packed onto, the one fitting it with the highest score.
+	NodeID   *string    `json:"nodeID,omitempty"`
+	NodeName *string    `json:"nodeName,omitempty"`
+	Nodes    []*NodeFit `json:"nodes"`
+}
+
+// History of a Pod followed by namespace/name across recreations (new UIDs) and
+// reschedules onto other nodes.
+type PodHistory struct {
+	Namespace string         `json:"namespace"`
+	Name      string         `json:"name"`
+	Bindings  []*PodBinding  `json:"bindings"`
+	Snapshots []*PodSnapshot `json:"snapshots"`
+}
+
+type PodPhaseChange struct {
+	Timestamp time.Time `json:"timestamp"`
+	From      PodPhase  `json:"from"`
+	To        PodPhase  `json:"to"`
+}
+
+// Node a Pod of a drained Node was rescheduled onto, or why it couldn't be if none.
+type PodPlacement struct {
+	PodID     string   `json:"podID"`
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	NodeID    *string  `json:"nodeID,omitempty"`
+	NodeName  *string  `json:"nodeName,omitempty"`
+	Reasons   []string `json:"reasons"`
+}
+
 // Point‑in‑time view of a single Pod.
 type PodSnapshot struct {
 	ID                  string               `json:"id"`
@@ -197,11 +592,36 @@ type PodSnapshot struct {
 	FinishedAt          *time.Time           `json:"finishedAt,omitempty"`
 	DeletedBy           *string              `json:"deletedBy,omitempty"`
 	QosClass            PodQOSClass          `json:"qosClass"`
+	Labels              []*Label             `json:"labels"`
+	// Those selected to be recorded.
+	Annotations     []*Label          `json:"annotations"`
+	OwnerReferences []*OwnerReference `json:"ownerReferences"`
+	Workload        *Workload         `json:"workload"`
+	Tolerations     []*Toleration     `json:"tolerations"`
+	NodeSelector    []*Label          `json:"nodeSelector"`
+	// Null if the Pod has no affinity.
+	Affinity          *Affinity `json:"affinity,omitempty"`
+	Priority          *int32    `json:"priority,omitempty"`
+	PriorityClassName *string   `json:"priorityClassName,omitempty"`
+	// `PreemptLowerPriority` or `Never`.
+	PreemptionPolicy *string `json:"preemptionPolicy,omitempty"`
+}
+
+type PodSnapshotConnection struct {
+	TotalCount int32              `json:"totalCount"`
+	Edges      []*PodSnapshotEdge `json:"edges"`
+	PageInfo   *PageInfo          `json:"pageInfo"`
+}
+
+type PodSnapshotEdge struct {
+	Cursor string       `json:"cursor"`
+	Node   *PodSnapshot `json:"node"`
 }
 
 type PodSnapshotInput struct {
-	ID                  string                    `json:"id"`
-	NodeID              string                    `json:"nodeID"`
+	ID string `json:"id"`
+	// Null for a Pod the scheduler hasn't bound yet, recorded under the reserved `unscheduled` Node ID.
+	NodeID              *string                   `json:"nodeID,omitempty"`
 	Timestamp           time.Time                 `json:"timestamp"`
 	Name                string                    `json:"name"`
 	Namespace           *string                   `json:"namespace,omitempty"`
@@ -214,16 +634,282 @@ type PodSnapshotInput struct {
 	FinishedAt          *time.Time                `json:"finishedAt,omitempty"`
 	DeletedBy           *string                   `json:"deletedBy,omitempty"`
 	QosClass            PodQOSClass               `json:"qosClass"`
+	Labels              []*LabelInput             `json:"labels,omitempty"`
+	// Only the keys selected by RECORDED_POD_ANNOTATIONS are recorded.
+	Annotations       []*LabelInput          `json:"annotations,omitempty"`
+	OwnerReferences   []*OwnerReferenceInput `json:"ownerReferences,omitempty"`
+	Tolerations       []*TolerationInput     `json:"tolerations,omitempty"`
+	NodeSelector      []*LabelInput          `json:"nodeSelector,omitempty"`
+	Affinity          *AffinityInput         `json:"affinity,omitempty"`
+	Priority          *int32                 `json:"priority,omitempty"`
+	PriorityClassName *string                `json:"priorityClassName,omitempty"`
+	PreemptionPolicy  *string                `json:"preemptionPolicy,omitempty"`
 }
 
 type Query struct {
 }
 
+// CPU and memory as Kubernetes quantities.
+type ResourceQuantities struct {
+	CPU    string `json:"cpu"`
+	Memory string `json:"memory"`
+}
+
+// A container of a Pod that crash looped, was OOMKilled repeatedly, or
+// restarted at least 3 times within 10 minutes.
+type RestartAnomaly struct {
+	PodID     string `json:"podID"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Name of the Workload of the Pod.
+	Workload  string `json:"workload"`
+	Container string `json:"container"`
+	NodeID    string `json:"nodeID"`
+	// Most severe kind of the anomaly.
+	Severity RestartAnomalyKind `json:"severity"`
+	// Ordered by severity.
+	Occurrences []*RestartAnomalyOccurrence `json:"occurrences"`
+	// Restarts within the window.
+	Restarts int32 `json:"restarts"`
+	OomKills int32 `json:"oomKills"`
+}
+
+type RestartAnomalyOccurrence struct {
+	Kind      RestartAnomalyKind `json:"kind"`
+	FirstSeen time.Time          `json:"firstSeen"`
+}
+
+// How many Pods of a workload ran each image from *timestamp* on.
+type RolloutFrame struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Ordered by image.
+	Versions []*ImageVersion `json:"versions"`
+}
+
+// Filters evaluated by the server while replaying. Omitted fields match everything.
+type SnapshotFilter struct {
+	Nodes *NodeFilter `json:"nodes,omitempty"`
+	Pods  *PodFilter  `json:"pods,omitempty"`
+}
+
 // Snapshot of an entire *cluster* at a specific instant.
 // Returned by nodeStateRange / nodeStateAtTimestamp.
 type TimedNodeSnapshots struct {
 	Timestamp time.Time       `json:"timestamp"`
 	Nodes     []*NodeSnapshot `json:"nodes"`
+	// Relay‑style pages of *nodes*, ordered by name.
+	NodesConnection *NodeSnapshotConnection `json:"nodesConnection"`
+}
+
+type Toleration struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+	Effect   string `json:"effect"`
+	// How long a NoExecute taint is tolerated for before eviction, forever if null.
+	TolerationSeconds *int64 `json:"tolerationSeconds,omitempty"`
+}
+
+type TolerationInput struct {
+	// Taint key to tolerate, all if not set along with the `Exists` operator.
+	Key *string `json:"key,omitempty"`
+	// `Equal` (default) or `Exists`.
+	Operator *string `json:"operator,omitempty"`
+	Value    *string `json:"value,omitempty"`
+	// Taint effect to tolerate, all if not set.
+	Effect *string `json:"effect,omitempty"`
+	// How long a NoExecute taint is tolerated for, recorded but not simulated.
+	TolerationSeconds *int64 `json:"tolerationSeconds,omitempty"`
+}
+
+type UpgradeFrame struct {
+	Timestamp time.Time           `json:"timestamp"`
+	Groups    []*NodeGroupUpgrade `json:"groups"`
+}
+
+type UpgradeProgress struct {
+	Component NodeComponent `json:"component"`
+	Target    string        `json:"target"`
+	// At *start* and at every change.
+	Frames []*UpgradeFrame `json:"frames"`
+}
+
+type VersionCount struct {
+	Version string `json:"version"`
+	Nodes   int32  `json:"nodes"`
+}
+
+type VersionReport struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Kubelet version the kubelets and kube-proxies are compared to.
+	Reference    string               `json:"reference"`
+	MaxMinorSkew int32                `json:"maxMinorSkew"`
+	Components   []*ComponentVersions `json:"components"`
+	// Ordered by Node name.
+	Skewed []*VersionSkew `json:"skewed"`
+}
+
+// A Node running a component more minor versions behind the reference than allowed.
+type VersionSkew struct {
+	NodeID       string        `json:"nodeID"`
+	NodeName     string        `json:"nodeName"`
+	Component    NodeComponent `json:"component"`
+	Version      string        `json:"version"`
+	MinorsBehind int32         `json:"minorsBehind"`
+}
+
+// Object managing a Pod, following its controller from a ReplicaSet to the
+// Deployment that created it. Kind is empty when the Pod has no owners recorded
+// and the name is taken from the Pod name.
+type Workload struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// The Pods of a Workload at an instant, with what they requested.
+type WorkloadSummary struct {
+	Namespace string        `json:"namespace"`
+	Kind      string        `json:"kind"`
+	Name      string        `json:"name"`
+	Pods      int32         `json:"pods"`
+	Phases    []*PhaseCount `json:"phases"`
+	// Requests of the Pods that hadn't terminated.
+	CPURequests    string   `json:"cpuRequests"`
+	MemoryRequests string   `json:"memoryRequests"`
+	NodeNames      []string `json:"nodeNames"`
+}
+
+// What changed in the cluster between two consecutive snapshots.
+type ClusterEventKind string
+
+const (
+	ClusterEventKindNodeAdded          ClusterEventKind = "NodeAdded"
+	ClusterEventKindNodeRemoved        ClusterEventKind = "NodeRemoved"
+	ClusterEventKindNodeStatusChanged  ClusterEventKind = "NodeStatusChanged"
+	ClusterEventKindNodeCordoned       ClusterEventKind = "NodeCordoned"
+	ClusterEventKindNodeUncordoned     ClusterEventKind = "NodeUncordoned"
+	ClusterEventKindPodAdded           ClusterEventKind = "PodAdded"
+	ClusterEventKindPodRemoved         ClusterEventKind = "PodRemoved"
+	ClusterEventKindPodMoved           ClusterEventKind = "PodMoved"
+	ClusterEventKindPodPhaseChanged    ClusterEventKind = "PodPhaseChanged"
+	ClusterEventKindContainerRestarted ClusterEventKind = "ContainerRestarted"
+)
+
+var AllClusterEventKind = []ClusterEventKind{
+	ClusterEventKindNodeAdded,
+	ClusterEventKindNodeRemoved,
+	ClusterEventKindNodeStatusChanged,
+	ClusterEventKindNodeCordoned,
+	ClusterEventKindNodeUncordoned,
+	ClusterEventKindPodAdded,
+	ClusterEventKindPodRemoved,
+	ClusterEventKindPodMoved,
+	ClusterEventKindPodPhaseChanged,
+	ClusterEventKindContainerRestarted,
+}
+
+func (e ClusterEventKind) IsValid() bool {
+	switch e {
+	case ClusterEventKindNodeAdded, ClusterEventKindNodeRemoved, ClusterEventKindNodeStatusChanged, ClusterEventKindNodeCordoned, ClusterEventKindNodeUncordoned, ClusterEventKindPodAdded, ClusterEventKindPodRemoved, ClusterEventKindPodMoved, ClusterEventKindPodPhaseChanged, ClusterEventKindContainerRestarted:
+		return true
+	}
+	return false
+}
+
+func (e ClusterEventKind) String() string {
+	return string(e)
+}
+
+func (e *ClusterEventKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ClusterEventKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ClusterEventKind", str)
+	}
+	return nil
+}
+
+func (e ClusterEventKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ClusterEventKind) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ClusterEventKind) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type NodeComponent string
+
+const (
+	NodeComponentKubelet          NodeComponent = "Kubelet"
+	NodeComponentKubeProxy        NodeComponent = "KubeProxy"
+	NodeComponentKernel           NodeComponent = "Kernel"
+	NodeComponentContainerRuntime NodeComponent = "ContainerRuntime"
+	NodeComponentOsImage          NodeComponent = "OsImage"
+)
+
+var AllNodeComponent = []NodeComponent{
+	NodeComponentKubelet,
+	NodeComponentKubeProxy,
+	NodeComponentKernel,
+	NodeComponentContainerRuntime,
+	NodeComponentOsImage,
+}
+
+func (e NodeComponent) IsValid() bool {
+	switch e {
+	case NodeComponentKubelet, NodeComponentKubeProxy, NodeComponentKernel, NodeComponentContainerRuntime, NodeComponentOsImage:
+		return true
+	}
+	return false
+}
+
+func (e NodeComponent) String() string {
+	return string(e)
+}
+
+func (e *NodeComponent) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = NodeComponent(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid NodeComponent", str)
+	}
+	return nil
+}
+
+func (e NodeComponent) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *NodeComponent) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e NodeComponent) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
 }
 
 type NodeCondition string
@@ -283,6 +969,132 @@ func (e NodeCondition) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// Kinds of node incidents. A Node going NotReady or Unknown again within 30
+// minutes of recovering is the same incident, Flapping once it did 3 times.
+type NodeIncidentKind string
+
+const (
+	NodeIncidentKindNotReady           NodeIncidentKind = "NotReady"
+	NodeIncidentKindFlapping           NodeIncidentKind = "Flapping"
+	NodeIncidentKindCordoned           NodeIncidentKind = "Cordoned"
+	NodeIncidentKindNoExecuteTaint     NodeIncidentKind = "NoExecuteTaint"
+	NodeIncidentKindAllocatableDropped NodeIncidentKind = "AllocatableDropped"
+)
+
+var AllNodeIncidentKind = []NodeIncidentKind{
+	NodeIncidentKindNotReady,
+	NodeIncidentKindFlapping,
+	NodeIncidentKindCordoned,
+	NodeIncidentKindNoExecuteTaint,
+	NodeIncidentKindAllocatableDropped,
+}
+
+func (e NodeIncidentKind) IsValid() bool {
+	switch e {
+	case NodeIncidentKindNotReady, NodeIncidentKindFlapping, NodeIncidentKindCordoned, NodeIncidentKindNoExecuteTaint, NodeIncidentKindAllocatableDropped:
+		return true
+	}
+	return false
+}
+
+func (e NodeIncidentKind) String() string {
+	return string(e)
+}
+
+func (e *NodeIncidentKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = NodeIncidentKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid NodeIncidentKind", str)
+	}
+	return nil
+}
+
+func (e NodeIncidentKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *NodeIncidentKind) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e NodeIncidentKind) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+// Why a Node couldn't take a Pending Pod the scheduler hadn't bound.
+// Schedulable Nodes could have by the replayed state, so something replays
+// don't record held the Pod back, such as its volumes or Pod affinity.
+type PendingCause string
+
+const (
+	// Cordoned, tainted or not matching the node selector or affinity of the Pod.
+	PendingCauseNoEligibleNode          PendingCause = "NoEligibleNode"
+	PendingCauseInsufficientAllocatable PendingCause = "InsufficientAllocatable"
+	PendingCausePodLimitReached         PendingCause = "PodLimitReached"
+	PendingCauseSchedulable             PendingCause = "Schedulable"
+)
+
+var AllPendingCause = []PendingCause{
+	PendingCauseNoEligibleNode,
+	PendingCauseInsufficientAllocatable,
+	PendingCausePodLimitReached,
+	PendingCauseSchedulable,
+}
+
+func (e PendingCause) IsValid() bool {
+	switch e {
+	case PendingCauseNoEligibleNode, PendingCauseInsufficientAllocatable, PendingCausePodLimitReached, PendingCauseSchedulable:
+		return true
+	}
+	return false
+}
+
+func (e PendingCause) String() string {
+	return string(e)
+}
+
+func (e *PendingCause) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PendingCause(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PendingCause", str)
+	}
+	return nil
+}
+
+func (e PendingCause) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *PendingCause) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e PendingCause) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
 type PodPhase string
 
 const (
@@ -402,3 +1214,61 @@ func (e PodQOSClass) MarshalJSON() ([]byte, error) {
 	e.MarshalGQL(&buf)
 	return buf.Bytes(), nil
 }
+
+// Kinds of restart anomalies, from most to least severe.
+type RestartAnomalyKind string
+
+const (
+	RestartAnomalyKindCrashLoop       RestartAnomalyKind = "CrashLoop"
+	RestartAnomalyKindRepeatedOOMKill RestartAnomalyKind = "RepeatedOOMKill"
+	RestartAnomalyKindRestartSpike    RestartAnomalyKind = "RestartSpike"
+)
+
+var AllRestartAnomalyKind = []RestartAnomalyKind{
+	RestartAnomalyKindCrashLoop,
+	RestartAnomalyKindRepeatedOOMKill,
+	RestartAnomalyKindRestartSpike,
+}
+
+func (e RestartAnomalyKind) IsValid() bool {
+	switch e {
+	case RestartAnomalyKindCrashLoop, RestartAnomalyKindRepeatedOOMKill, RestartAnomalyKindRestartSpike:
+		return true
+	}
+	return false
+}
+
+func (e RestartAnomalyKind) String() string {
+	return string(e)
+}
+
+func (e *RestartAnomalyKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RestartAnomalyKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RestartAnomalyKind", str)
+	}
+	return nil
+}
+
+func (e RestartAnomalyKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *RestartAnomalyKind) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e RestartAnomalyKind) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
@@ -1,7 +1,12 @@
 package graph
 
+import "github.com/ccpeng/kube-replay/internal/services"
+
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
-type Resolver struct{}
+type Resolver struct {
+	Replayer    services.Replayer // wrapped by policy.NewReplayer to scope what callers see to their identity
+	ClusterName string            // cluster replayed, for authorizing read scopes
+}
@@ -6,6 +6,7 @@ package graph
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -15,12 +16,11 @@ import (
 
 // RecordNodeAtTimestamp is the resolver for the recordNodeAtTimestamp field.
 func (r *mutationResolver) RecordNodeAtTimestamp(ctx context.Context, input model.NodeSnapshotInput) (string, error) {
-	replayer, err := services.NewReplayer("k8s")
-	if err != nil {
-		return "", fmt.Errorf("unable to create replayer: %v", err)
+	if err := authorizeWrite(ctx); err != nil {
+		return "", err
 	}
 
-	err = replayer.RecordNodeSnapshot(ctx, &input)
+	err := r.Replayer.RecordNodeSnapshot(ctx, &input)
 	if err != nil {
 		return "", fmt.Errorf("unable to record node snapshot: %v", err)
 	}
@@ -28,31 +28,249 @@ func (r *mutationResolver) RecordNodeAtTimestamp(ctx context.Context, input mode
 	return input.ID, nil
 }
 
-// NodeStatesAtTimestamp is the resolver for the nodeStatesAtTimestamp field.
-func (r *queryResolver) NodeStatesAtTimestamp(ctx context.Context, timestamp time.Time) (*model.TimedNodeSnapshots, error) {
-	replayer, err := services.NewReplayer("k8s")
+// RecordPodSnapshots is the resolver for the recordPodSnapshots field.
+func (r *mutationResolver) RecordPodSnapshots(ctx context.Context, input []*model.PodSnapshotInput) (int32, error) {
+	if err := authorizeWrite(ctx); err != nil {
+		return 0, err
+	}
+
+	err := r.Replayer.RecordPodSnapshots(ctx, input)
+	if err != nil {
+		return 0, fmt.Errorf("unable to record pod snapshots: %v", err)
+	}
+
+	return int32(len(input)), nil
+}
+
+// RecordNodeDeletion is the resolver for the recordNodeDeletion field.
+func (r *mutationResolver) RecordNodeDeletion(ctx context.Context, input model.NodeDeletionInput) (string, error) {
+	if err := authorizeWrite(ctx); err != nil {
+		return "", err
+	}
+
+	err := r.Replayer.RecordNodeDeletion(ctx, &input)
+	if err != nil {
+		return "", fmt.Errorf("unable to record node deletion: %v", err)
+	}
+
+	return input.ID, nil
+}
+
+// RecordPodDeletion is the resolver for the recordPodDeletion field.
+func (r *mutationResolver) RecordPodDeletion(ctx context.Context, input model.PodDeletionInput) (string, error) {
+	if err := authorizeWrite(ctx); err != nil {
+		return "", err
+	}
+
+	err := r.Replayer.RecordPodDeletion(ctx, &input)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create replayer: %v", err)
+		return "", fmt.Errorf("unable to record pod deletion: %v", err)
+	}
+
+	return input.ID, nil
+}
+
+// PodsConnection is the resolver for the podsConnection field.
+func (r *nodeSnapshotResolver) PodsConnection(ctx context.Context, obj *model.NodeSnapshot, first *int32, after *string) (*model.PodSnapshotConnection, error) {
+	return services.PaginatePods(obj.Pods, first, after)
+}
+
+// NodeStatesAtTimestamp is the resolver for the nodeStatesAtTimestamp field.
+func (r *queryResolver) NodeStatesAtTimestamp(ctx context.Context, timestamp time.Time, filter *model.SnapshotFilter) (*model.TimedNodeSnapshots, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
 	}
 
-	return replayer.EffectiveAtSnapshot(ctx, timestamp)
+	return r.Replayer.EffectiveAtSnapshot(ctx, timestamp, filter)
 }
 
 // NodeStatesRange is the resolver for the nodeStatesRange field.
-func (r *queryResolver) NodeStatesRange(ctx context.Context, start time.Time, end time.Time, step int64) ([]*model.TimedNodeSnapshots, error) {
-	replayer, err := services.NewReplayer("k8s")
+func (r *queryResolver) NodeStatesRange(ctx context.Context, start time.Time, end time.Time, step int64, filter *model.SnapshotFilter) ([]*model.TimedNodeSnapshots, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.IntervalSnapshots(ctx, start, end, step, filter)
+}
+
+// PodHistory is the resolver for the podHistory field.
+func (r *queryResolver) PodHistory(ctx context.Context, namespace string, name string, start time.Time, end time.Time) (*model.PodHistory, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.PodHistory(ctx, namespace, name, start, end)
+}
+
+// ClusterEvents is the resolver for the clusterEvents field.
+func (r *queryResolver) ClusterEvents(ctx context.Context, start time.Time, end time.Time, filter *model.SnapshotFilter) ([]*model.ClusterEvent, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.ClusterEvents(ctx, start, end, filter)
+}
+
+// ManifestsAtTimestamp is the resolver for the manifestsAtTimestamp field.
+func (r *queryResolver) ManifestsAtTimestamp(ctx context.Context, timestamp time.Time, filter *model.SnapshotFilter) (*model.Manifests, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	m, err := r.Replayer.EffectiveAtManifests(ctx, timestamp, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeList, err := json.Marshal(m.Nodes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode nodes: %v", err)
+	}
+	podList, err := json.Marshal(m.Pods)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create replayer: %v", err)
+		return nil, fmt.Errorf("unable to encode pods: %v", err)
 	}
 
-	return replayer.IntervalSnapshots(ctx, start, end, step)
+	return &model.Manifests{Timestamp: m.Timestamp, NodeList: string(nodeList), PodList: string(podList)}, nil
+}
+
+// SimulatePodFit is the resolver for the simulatePodFit field.
+func (r *queryResolver) SimulatePodFit(ctx context.Context, timestamp time.Time, pod model.HypotheticalPodInput) (*model.PodFitSimulation, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.SimulatePodFit(ctx, timestamp, &pod)
+}
+
+// SimulateNodeDrain is the resolver for the simulateNodeDrain field.
+func (r *queryResolver) SimulateNodeDrain(ctx context.Context, timestamp time.Time, nodeName string) (*model.DrainSimulation, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.SimulateNodeDrain(ctx, timestamp, nodeName)
+}
+
+// CapacityPlan is the resolver for the capacityPlan field.
+func (r *queryResolver) CapacityPlan(ctx context.Context, start time.Time, end time.Time) (*model.CapacityPlan, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.CapacityPlan(ctx, start, end)
+}
+
+// CostReport is the resolver for the costReport field.
+func (r *queryResolver) CostReport(ctx context.Context, start time.Time, end time.Time) (*model.CostReport, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.CostReport(ctx, start, end)
+}
+
+// ImagesAtTimestamp is the resolver for the imagesAtTimestamp field.
+func (r *queryResolver) ImagesAtTimestamp(ctx context.Context, timestamp time.Time, filter *model.SnapshotFilter) ([]*model.ContainerImage, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.ImagesAtTimestamp(ctx, timestamp, filter)
+}
+
+// ImageSightings is the resolver for the imageSightings field.
+func (r *queryResolver) ImageSightings(ctx context.Context, image string, start time.Time, end time.Time) (*model.ImageSightings, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.ImageSightings(ctx, image, start, end)
+}
+
+// RolloutTimeline is the resolver for the rolloutTimeline field.
+func (r *queryResolver) RolloutTimeline(ctx context.Context, namespace string, workload string, start time.Time, end time.Time) ([]*model.RolloutFrame, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.RolloutTimeline(ctx, namespace, workload, start, end)
+}
+
+// VersionReport is the resolver for the versionReport field.
+func (r *queryResolver) VersionReport(ctx context.Context, timestamp time.Time, reference *string, maxMinorSkew int32) (*model.VersionReport, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.VersionReport(ctx, timestamp, reference, int(maxMinorSkew))
+}
+
+// UpgradeProgress is the resolver for the upgradeProgress field.
+func (r *queryResolver) UpgradeProgress(ctx context.Context, start time.Time, end time.Time, component model.NodeComponent, target *string) (*model.UpgradeProgress, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.UpgradeProgress(ctx, start, end, component, target)
+}
+
+// RestartAnomalies is the resolver for the restartAnomalies field.
+func (r *queryResolver) RestartAnomalies(ctx context.Context, start time.Time, end time.Time, namespace *string) ([]*model.RestartAnomaly, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.RestartAnomalies(ctx, start, end, namespace)
+}
+
+// NodeIncidents is the resolver for the nodeIncidents field.
+func (r *queryResolver) NodeIncidents(ctx context.Context, start time.Time, end time.Time, nodeName *string) ([]*model.NodeIncident, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.NodeIncidents(ctx, start, end, nodeName)
+}
+
+// PendingPods is the resolver for the pendingPods field.
+func (r *queryResolver) PendingPods(ctx context.Context, start time.Time, end time.Time, minPendingSeconds int32, namespace *string) ([]*model.PendingPod, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.PendingPods(ctx, start, end, time.Duration(minPendingSeconds)*time.Second, namespace)
+}
+
+// WorkloadsAtTimestamp is the resolver for the workloadsAtTimestamp field.
+func (r *queryResolver) WorkloadsAtTimestamp(ctx context.Context, timestamp time.Time, filter *model.SnapshotFilter) ([]*model.WorkloadSummary, error) {
+	if err := r.authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Replayer.WorkloadsAtTimestamp(ctx, timestamp, filter)
+}
+
+// NodesConnection is the resolver for the nodesConnection field.
+func (r *timedNodeSnapshotsResolver) NodesConnection(ctx context.Context, obj *model.TimedNodeSnapshots, first *int32, after *string) (*model.NodeSnapshotConnection, error) {
+	return services.PaginateNodes(obj.Nodes, first, after)
 }
 
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// NodeSnapshot returns NodeSnapshotResolver implementation.
+func (r *Resolver) NodeSnapshot() NodeSnapshotResolver { return &nodeSnapshotResolver{r} }
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// TimedNodeSnapshots returns TimedNodeSnapshotsResolver implementation.
+func (r *Resolver) TimedNodeSnapshots() TimedNodeSnapshotsResolver {
+	return &timedNodeSnapshotsResolver{r}
+}
+
 type mutationResolver struct{ *Resolver }
+type nodeSnapshotResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type timedNodeSnapshotsResolver struct{ *Resolver }
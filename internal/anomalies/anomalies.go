@@ -0,0 +1,227 @@
+package anomalies
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+// Reasons the kubelet gives for the state of a container
+const (
+	ReasonCrashLoopBackOff = "CrashLoopBackOff"
+	ReasonOOMKilled        = "OOMKilled"
+)
+
+const (
+	// minOOMKills is how many times a container must be OOMKilled in a window for it to be repeated
+	minOOMKills = 2
+	// spikeRestarts is how many restarts within spikeWindow make a spike
+	spikeRestarts = 3
+	spikeWindow   = 10 * time.Minute
+)
+
+// Kind is a kind of anomaly, ordered from most to least severe
+type Kind int
+
+const (
+	CrashLoop Kind = iota
+	RepeatedOOMKill
+	RestartSpike
+)
+
+func (k Kind) String() string {
+	switch k {
+	case CrashLoop:
+		return "CrashLoop"
+	case RepeatedOOMKill:
+		return "RepeatedOOMKill"
+	case RestartSpike:
+		return "RestartSpike"
+	}
+
+	return "Unknown"
+}
+
+// Anomaly is a container of a pod found restarting abnormally over a window
+type Anomaly struct {
+	PodID     string
+	Namespace string
+	PodName   string
+	Workload  data.Workload
+	Container string
+	NodeID    string
+	Kinds     []Kind             // ordered by severity
+	FirstSeen map[Kind]time.Time // first occurrence of each kind
+	Restarts  int64              // within the window
+	OOMKills  int
+}
+
+// Severity returns the most severe kind of the anomaly
+func (a *Anomaly) Severity() Kind {
+	return a.Kinds[0]
+}
+
+// Detect returns the containers of the pods of the nodes that crash looped, were OOMKilled repeatedly or restarted in
+// a spike between beginAt and endAt, the most severe first, then those restarting the most, then the earliest. Pod
+// histories are expected to be trimmed to the window, preceded by the snapshot in effect when it opens.
+func Detect(nodes []*data.NodeMeta, beginAt, endAt time.Time) []*Anomaly {
+	var anomalies []*Anomaly
+	for _, node := range nodes {
+		for _, pod := range node.Pods {
+			for _, name := range containerNames(pod) {
+				if anomaly := detect(pod, name, beginAt, endAt); anomaly != nil {
+					anomaly.NodeID = node.ID
+					anomalies = append(anomalies, anomaly)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		a, b := anomalies[i], anomalies[j]
+		if a.Severity() != b.Severity() {
+			return a.Severity() < b.Severity()
+		}
+		if a.Restarts != b.Restarts {
+			return a.Restarts > b.Restarts
+		}
+		return a.FirstSeen[a.Severity()].Before(b.FirstSeen[b.Severity()])
+	})
+
+	return anomalies
+}
+
+// restart is when a container was seen to have restarted, and how many times since it was last seen
+type restart struct {
+	at    time.Time
+	count int64
+}
+
+func detect(pod *data.PodMeta, name string, beginAt, endAt time.Time) *Anomaly {
+	anomaly := &Anomaly{
+		PodID:     pod.ID,
+		Namespace: pod.Namespace,
+		PodName:   pod.Name,
+		Workload:  pod.Workload(),
+		Container: name,
+		FirstSeen: map[Kind]time.Time{},
+	}
+	seen := func(kind Kind, at time.Time) {
+		if at.Before(beginAt) {
+			at = beginAt
+		}
+		if first, ok := anomaly.FirstSeen[kind]; !ok || at.Before(first) {
+			anomaly.FirstSeen[kind] = at
+		}
+	}
+
+	var restarts []restart
+	oomKills := map[time.Time]bool{}
+	var previous *data.ContainerSnapshot
+	for _, snapshot := range pod.Snapshots.Sorted() {
+		if snapshot.Timestamp.After(endAt) {
+			break
+		}
+		container := containerNamed(snapshot, name)
+		if container == nil {
+			continue
+		}
+
+		// the snapshot in effect when the window opens is only a baseline for restarts, unless the container was
+		// still crash looping
+		inWindow := !snapshot.Timestamp.Before(beginAt)
+		if container.State.Reason == ReasonCrashLoopBackOff {
+			seen(CrashLoop, snapshot.Timestamp)
+		}
+
+		if inWindow {
+			before := int64(0)
+			if previous != nil {
+				before = previous.RestartCount
+			}
+			if container.RestartCount > before && (previous != nil || !pod.StartedAt.Before(beginAt)) {
+				restarts = append(restarts, restart{at: snapshot.Timestamp, count: container.RestartCount - before})
+				anomaly.Restarts += container.RestartCount - before
+			}
+
+			for _, state := range []data.ContainerState{container.State, container.LastState} {
+				if state.Reason != ReasonOOMKilled {
+					continue
+				}
+				at := state.FinishedAt
+				if at.IsZero() {
+					at = snapshot.Timestamp
+				}
+				if !at.Before(beginAt) && !oomKills[at] {
+					oomKills[at] = true
+					if len(oomKills) == minOOMKills {
+						seen(RepeatedOOMKill, snapshot.Timestamp)
+					}
+				}
+			}
+		}
+
+		previous = container
+	}
+	anomaly.OOMKills = len(oomKills)
+
+	if at, ok := spike(restarts); ok {
+		seen(RestartSpike, at)
+	}
+
+	for _, kind := range []Kind{CrashLoop, RepeatedOOMKill, RestartSpike} {
+		if _, ok := anomaly.FirstSeen[kind]; ok {
+			anomaly.Kinds = append(anomaly.Kinds, kind)
+		}
+	}
+	if len(anomaly.Kinds) == 0 {
+		return nil
+	}
+
+	return anomaly
+}
+
+// spike returns when the restarts first added up to spikeRestarts within spikeWindow, if they ever did
+func spike(restarts []restart) (time.Time, bool) {
+	start := 0
+	var count int64
+	for _, r := range restarts {
+		count += r.count
+		for r.at.Sub(restarts[start].at) > spikeWindow {
+			count -= restarts[start].count
+			start++
+		}
+		if count >= spikeRestarts {
+			return r.at, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// containerNames returns the names of the containers of the pod across its snapshots, in the order first seen
+func containerNames(pod *data.PodMeta) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, snapshot := range pod.Snapshots {
+		for _, container := range snapshot.Containers {
+			if !seen[container.Name] {
+				seen[container.Name] = true
+				names = append(names, container.Name)
+			}
+		}
+	}
+
+	return names
+}
+
+func containerNamed(snapshot *data.PodSnapshot, name string) *data.ContainerSnapshot {
+	for _, container := range snapshot.Containers {
+		if container.Name == name {
+			return container
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package anomalies_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/anomalies"
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+func TestDetect(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	at := func(minutes int) time.Time { return t0.Add(time.Duration(minutes) * time.Minute) }
+	snapshot := func(minutes int, restarts int64, state, lastState data.ContainerState) *data.PodSnapshot {
+		return &data.PodSnapshot{Timestamp: at(minutes), Status: data.PodPhaseRunning, Containers: []*data.ContainerSnapshot{
+			{Name: "app", RestartCount: restarts, State: state, LastState: lastState},
+		}}
+	}
+	oomKilled := func(minutes int) data.ContainerState {
+		return data.ContainerState{Reason: anomalies.ReasonOOMKilled, ExitCode: 137, FinishedAt: at(minutes)}
+	}
+	backOff := data.ContainerState{Reason: anomalies.ReasonCrashLoopBackOff}
+
+	node := &data.NodeMeta{ID: "node-a", Pods: []*data.PodMeta{
+		// restarted 5 times before the window, then 3 times within 10 minutes
+		{ID: "api", Namespace: "shop", Name: "api-7d9f8b6c5d-x2vzq", StartedAt: at(-60), Snapshots: data.PodSnapshots{
+			snapshot(-30, 5, data.ContainerState{}, data.ContainerState{}),
+			snapshot(20, 6, data.ContainerState{}, data.ContainerState{}),
+			snapshot(25, 8, data.ContainerState{}, data.ContainerState{}),
+			snapshot(50, 9, data.ContainerState{}, data.ContainerState{}),
+		}},
+		// OOMKilled twice, then backing off
+		{ID: "worker", Namespace: "batch", Name: "worker-0", StartedAt: at(0), Owners: []*data.OwnerReference{
+			{Kind: "Job", Name: "nightly-report", Controller: true},
+		}, Snapshots: data.PodSnapshots{
+			snapshot(0, 0, data.ContainerState{}, data.ContainerState{}),
+			snapshot(10, 1, data.ContainerState{}, oomKilled(9)),
+			snapshot(40, 2, data.ContainerState{}, oomKilled(39)),
+			snapshot(45, 2, backOff, oomKilled(39)),
+		}},
+		// restarted once, an hour in
+		{ID: "web", Namespace: "shop", Name: "web-0", StartedAt: at(0), Snapshots: data.PodSnapshots{
+			snapshot(0, 0, data.ContainerState{}, data.ContainerState{}),
+			snapshot(60, 1, data.ContainerState{}, data.ContainerState{}),
+		}},
+	}}
+
+	detected := anomalies.Detect([]*data.NodeMeta{node}, t0, at(90))
+	g.Expect(detected).To(gomega.HaveLen(2))
+
+	worker := detected[0]
+	g.Expect(worker.PodName).To(gomega.Equal("worker-0"))
+	g.Expect(worker.NodeID).To(gomega.Equal("node-a"))
+	g.Expect(worker.Workload).To(gomega.Equal(data.Workload{Kind: "Job", Name: "nightly-report"}))
+	g.Expect(worker.Kinds).To(gomega.Equal([]anomalies.Kind{anomalies.CrashLoop, anomalies.RepeatedOOMKill}))
+	g.Expect(worker.FirstSeen[anomalies.CrashLoop]).To(gomega.Equal(at(45)))
+	g.Expect(worker.FirstSeen[anomalies.RepeatedOOMKill]).To(gomega.Equal(at(40)))
+	g.Expect(worker.OOMKills).To(gomega.Equal(2))
+	g.Expect(worker.Restarts).To(gomega.BeEquivalentTo(2))
+
+	api := detected[1]
+	g.Expect(api.Severity()).To(gomega.Equal(anomalies.RestartSpike))
+	g.Expect(api.FirstSeen[anomalies.RestartSpike]).To(gomega.Equal(at(25)))
+	g.Expect(api.Restarts).To(gomega.BeEquivalentTo(4))
+	g.Expect(api.Workload).To(gomega.Equal(data.Workload{Name: "api"}))
+
+	// the first OOMKill is out of the window
+	g.Expect(anomalies.Detect([]*data.NodeMeta{node}, at(30), at(44))).To(gomega.BeEmpty())
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// apiKeyAuthenticator authenticates requests by a static API key in the X-API-Key header or an ApiKey authorization.
+// Only SHA-256 digests of the keys are kept.
+type apiKeyAuthenticator struct {
+	identities map[[sha256.Size]byte]*Identity
+}
+
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	key := r.Header.Get("X-API-Key")
+	if scheme, credentials, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "ApiKey") {
+		key = credentials
+	}
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	identity, ok := a.identities[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, errors.New("unknown API key")
+	}
+
+	return identity, nil
+}
+
+// NewAPIKeyAuthenticator returns an Authenticator for the API keys in the file, a JSON object of identities keyed by
+// the hex SHA-256 digest of their key
+func NewAPIKeyAuthenticator(file string) (Authenticator, error) {
+	identities, err := loadIdentities(file)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &apiKeyAuthenticator{identities: map[[sha256.Size]byte]*Identity{}}
+	for digest, identity := range identities {
+		b, err := hex.DecodeString(digest)
+		if err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("invalid SHA-256 digest of API key for %s", identity.Subject)
+		}
+		a.identities[[sha256.Size]byte(b)] = identity
+	}
+
+	return a, nil
+}
@@ -0,0 +1,159 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/auth"
+)
+
+var collector = &auth.Identity{Subject: "collector", Roles: []auth.Role{auth.RoleWriter}}
+
+func writeJSON(g *gomega.WithT, dir, name string, v interface{}) string {
+	b, err := json.Marshal(v)
+	g.Expect(err).To(gomega.BeNil())
+
+	file := filepath.Join(dir, name)
+	g.Expect(os.WriteFile(file, b, 0o600)).To(gomega.Succeed())
+	return file
+}
+
+// authenticate serves a request through the middleware, returning the status and the identity it was passed on with
+func authenticate(authenticator auth.Authenticator, r *http.Request) (int, *auth.Identity) {
+	var identity *auth.Identity
+	rec := httptest.NewRecorder()
+	auth.Middleware(authenticator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity = auth.IdentityFrom(r.Context())
+	})).ServeHTTP(rec, r)
+
+	return rec.Code, identity
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	digest := sha256.Sum256([]byte("s3cret"))
+	file := writeJSON(g, t.TempDir(), "keys.json", map[string]*auth.Identity{hex.EncodeToString(digest[:]): collector})
+	authenticator, err := auth.NewAPIKeyAuthenticator(file)
+	g.Expect(err).To(gomega.BeNil())
+
+	r := httptest.NewRequest("POST", "/query", nil)
+	r.Header.Set("X-API-Key", "s3cret")
+	code, identity := authenticate(authenticator, r)
+	g.Expect(code).To(gomega.Equal(http.StatusOK))
+	g.Expect(identity).To(gomega.Equal(collector))
+
+	r.Header.Del("X-API-Key")
+	r.Header.Set("Authorization", "ApiKey wrong")
+	code, _ = authenticate(authenticator, r)
+	g.Expect(code).To(gomega.Equal(http.StatusUnauthorized))
+
+	code, _ = authenticate(authenticator, httptest.NewRequest("POST", "/query", nil))
+	g.Expect(code).To(gomega.Equal(http.StatusUnauthorized))
+
+	// without an authenticator every request is rejected, unless authentication is disabled
+	code, _ = authenticate(nil, httptest.NewRequest("POST", "/query", nil))
+	g.Expect(code).To(gomega.Equal(http.StatusUnauthorized))
+	code, identity = authenticate(auth.Disabled, httptest.NewRequest("POST", "/query", nil))
+	g.Expect(code).To(gomega.Equal(http.StatusOK))
+	g.Expect(identity).To(gomega.Equal(auth.Unrestricted))
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).To(gomega.BeNil())
+	jwks := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key.Public(), KeyID: "k1", Algorithm: string(jose.RS256), Use: "sig"}}}
+	file := writeJSON(g, t.TempDir(), "jwks.json", jwks)
+
+	authenticator, err := auth.NewJWTAuthenticator(file, "https://issuer", "kube-replay")
+	g.Expect(err).To(gomega.BeNil())
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, (&jose.SignerOptions{}).WithHeader("kid", "k1"))
+	g.Expect(err).To(gomega.BeNil())
+	token := func(issuer string, expiry time.Time) string {
+		s, err := jwt.Signed(signer).Claims(jwt.Claims{
+			Subject:  "team-a",
+			Issuer:   issuer,
+			Audience: jwt.Audience{"kube-replay"},
+			Expiry:   jwt.NewNumericDate(expiry),
+		}).Claims(map[string]interface{}{
+			"roles":      []string{"reader"},
+			"clusters":   []string{"prod"},
+			"namespaces": []string{"team-a"},
+		}).Serialize()
+		g.Expect(err).To(gomega.BeNil())
+		return s
+	}
+
+	r := httptest.NewRequest("POST", "/query", nil)
+	r.Header.Set("Authorization", "Bearer "+token("https://issuer", time.Now().Add(time.Hour)))
+	code, identity := authenticate(authenticator, r)
+	g.Expect(code).To(gomega.Equal(http.StatusOK))
+	g.Expect(identity).To(gomega.Equal(&auth.Identity{
+		Subject:    "team-a",
+		Roles:      []auth.Role{auth.RoleReader},
+		Clusters:   []string{"prod"},
+		Namespaces: []string{"team-a"},
+	}))
+	g.Expect(identity.CanRead("prod")).To(gomega.BeTrue())
+	g.Expect(identity.CanRead("staging")).To(gomega.BeFalse())
+	g.Expect(identity.CanReadNamespace("team-b")).To(gomega.BeFalse())
+
+	r.Header.Set("Authorization", "Bearer "+token("https://issuer", time.Now().Add(-time.Hour)))
+	code, _ = authenticate(authenticator, r)
+	g.Expect(code).To(gomega.Equal(http.StatusUnauthorized))
+
+	r.Header.Set("Authorization", "Bearer "+token("https://elsewhere", time.Now().Add(time.Hour)))
+	code, _ = authenticate(authenticator, r)
+	g.Expect(code).To(gomega.Equal(http.StatusUnauthorized))
+}
+
+func TestCertificateAuthenticator(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	file := writeJSON(g, t.TempDir(), "certs.json", map[string]*auth.Identity{"collector": collector})
+	certificates, err := auth.NewCertificateAuthenticator(file)
+	g.Expect(err).To(gomega.BeNil())
+
+	digest := sha256.Sum256([]byte("s3cret"))
+	apiKeys, err := auth.NewAPIKeyAuthenticator(writeJSON(g, t.TempDir(), "keys.json", map[string]*auth.Identity{
+		hex.EncodeToString(digest[:]): {Subject: "reader", Roles: []auth.Role{auth.RoleReader}},
+	}))
+	g.Expect(err).To(gomega.BeNil())
+	authenticator := auth.Chain(certificates, apiKeys)
+
+	r := httptest.NewRequest("POST", "/query", nil)
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "collector"}}}}}
+	code, identity := authenticate(authenticator, r)
+	g.Expect(code).To(gomega.Equal(http.StatusOK))
+	g.Expect(identity).To(gomega.Equal(collector))
+
+	// requests without a client certificate fall through to the next authenticator
+	r.TLS = &tls.ConnectionState{}
+	r.Header.Set("X-API-Key", "s3cret")
+	code, identity = authenticate(authenticator, r)
+	g.Expect(code).To(gomega.Equal(http.StatusOK))
+	g.Expect(identity.Subject).To(gomega.Equal("reader"))
+
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "intruder"}}}}}
+	code, _ = authenticate(authenticator, r)
+	g.Expect(code).To(gomega.Equal(http.StatusUnauthorized))
+}
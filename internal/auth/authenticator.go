@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+)
+
+// Authenticator authenticates requests by one kind of credentials, returning ErrNoCredentials for requests without
+// them so others can be tried
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// chain authenticates requests with the first authenticator finding credentials on them
+type chain []Authenticator
+
+func (c chain) Authenticate(r *http.Request) (*Identity, error) {
+	for _, authenticator := range c {
+		identity, err := authenticator.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+
+		return identity, err
+	}
+
+	return nil, ErrNoCredentials
+}
+
+// Chain returns an Authenticator trying each of the authenticators in order
+func Chain(authenticators ...Authenticator) Authenticator {
+	return chain(authenticators)
+}
+
+// disabled authenticates every request as Unrestricted
+type disabled struct{}
+
+func (disabled) Authenticate(*http.Request) (*Identity, error) {
+	return Unrestricted, nil
+}
+
+// Disabled is the Authenticator of a server run without authentication, letting every request read and record
+// snapshots
+var Disabled Authenticator = disabled{}
+
+// Middleware authenticates every request before passing it on with its identity in the context, rejecting those
+// without valid credentials. Without an authenticator every request is rejected, so running without authentication
+// takes Disabled.
+func Middleware(authenticator Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var identity *Identity
+			err := ErrNoCredentials
+			if authenticator != nil {
+				identity, err = authenticator.Authenticate(r)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrNoCredentials) {
+					log.Printf("rejected credentials from %s: %v", r.RemoteAddr, err)
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="kube-replay"`)
+				http.Error(w, ErrUnauthenticated.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+		})
+	}
+}
+
+// loadIdentities reads a JSON object of identities keyed by credential from the file
+func loadIdentities(file string) (map[string]*Identity, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read identities: %v", err)
+	}
+
+	var identities map[string]*Identity
+	if err := json.Unmarshal(b, &identities); err != nil {
+		return nil, fmt.Errorf("unable to parse identities in %s: %v", file, err)
+	}
+
+	return identities, nil
+}
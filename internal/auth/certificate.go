@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// certificateAuthenticator authenticates requests by the common name of a client certificate verified during the TLS
+// handshake
+type certificateAuthenticator struct {
+	identities map[string]*Identity
+}
+
+func (a *certificateAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, ErrNoCredentials
+	}
+
+	commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	identity, ok := a.identities[commonName]
+	if !ok {
+		return nil, fmt.Errorf("unknown client certificate %q", commonName)
+	}
+
+	return identity, nil
+}
+
+// NewCertificateAuthenticator returns an Authenticator for the client certificates in the file, a JSON object of
+// identities keyed by certificate common name
+func NewCertificateAuthenticator(file string) (Authenticator, error) {
+	identities, err := loadIdentities(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificateAuthenticator{identities: identities}, nil
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// Role is what an identity may do with the API
+type Role string
+
+const (
+	RoleReader Role = "reader" // replays clusters and namespaces in scope
+	RoleWriter Role = "writer" // records snapshots, e.g. a collector
+)
+
+// Redaction is a kind of data hidden from an identity wherever it's replayed
+type Redaction string
+
+const (
+	RedactImages          Redaction = "images"          // container image names and IDs
+	RedactContainerIDs    Redaction = "containerIDs"    // container runtime IDs
+	RedactNodeIdentifiers Redaction = "nodeIdentifiers" // machine IDs, system UUIDs and provider IDs of nodes
+)
+
+// Wildcard scopes an identity to every cluster or namespace
+const Wildcard = "*"
+
+var (
+	ErrNoCredentials   = errors.New("no credentials")
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+)
+
+// Identity is who a request was authenticated as, with the roles and read scopes granted to them
+type Identity struct {
+	Subject    string      `json:"subject"`
+	Roles      []Role      `json:"roles"`
+	Clusters   []string    `json:"clusters"`   // clusters that may be replayed
+	Namespaces []string    `json:"namespaces"` // namespaces whose pods may be replayed
+	Redactions []Redaction `json:"redact"`
+}
+
+// Unrestricted is the identity of every request when no authenticator is configured
+var Unrestricted = &Identity{
+	Subject:    "anonymous",
+	Roles:      []Role{RoleReader, RoleWriter},
+	Clusters:   []string{Wildcard},
+	Namespaces: []string{Wildcard},
+}
+
+// HasRole returns whether the identity was granted the role
+func (i *Identity) HasRole(role Role) bool {
+	return slices.Contains(i.Roles, role)
+}
+
+// CanRead returns whether the identity may replay the cluster
+func (i *Identity) CanRead(cluster string) bool {
+	return i.HasRole(RoleReader) && inScope(i.Clusters, cluster)
+}
+
+// CanReadNamespace returns whether the identity may replay the pods of the namespace
+func (i *Identity) CanReadNamespace(namespace string) bool {
+	return inScope(i.Namespaces, namespace)
+}
+
+// Redacts returns whether the kind of data is hidden from the identity
+func (i *Identity) Redacts(redaction Redaction) bool {
+	return slices.Contains(i.Redactions, redaction)
+}
+
+func inScope(scopes []string, s string) bool {
+	return slices.Contains(scopes, Wildcard) || slices.Contains(scopes, s)
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFrom returns the identity carried by ctx, or nil if the request wasn't authenticated
+func IdentityFrom(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+var signatureAlgorithms = []jose.SignatureAlgorithm{jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.ES256, jose.ES384, jose.EdDSA}
+
+// claims are the claims of a token granting an identity
+type claims struct {
+	jwt.Claims
+	Roles      []Role      `json:"roles"`
+	Clusters   []string    `json:"clusters"`
+	Namespaces []string    `json:"namespaces"`
+	Redactions []Redaction `json:"redact"`
+}
+
+// jwtAuthenticator authenticates requests by a bearer JWT signed with one of the keys of a JWKS, granting the roles,
+// scopes and redactions in its claims
+type jwtAuthenticator struct {
+	keys     jose.JSONWebKeySet
+	expected jwt.Expected
+}
+
+func (a *jwtAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+
+	parsed, err := jwt.ParseSigned(token, signatureAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse token: %v", err)
+	}
+	if len(parsed.Headers) != 1 {
+		return nil, errors.New("token must have a single signature")
+	}
+
+	keys := a.keys.Key(parsed.Headers[0].KeyID)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("unknown signing key %q", parsed.Headers[0].KeyID)
+	}
+
+	var c claims
+	if err := parsed.Claims(keys[0].Public(), &c); err != nil {
+		return nil, fmt.Errorf("unable to verify token: %v", err)
+	}
+
+	expected := a.expected
+	expected.Time = time.Now()
+	if err := c.ValidateWithLeeway(expected, jwt.DefaultLeeway); err != nil {
+		return nil, fmt.Errorf("invalid token: %v", err)
+	}
+	if c.Expiry == nil {
+		return nil, errors.New("invalid token: no expiry")
+	}
+
+	return &Identity{
+		Subject:    c.Subject,
+		Roles:      c.Roles,
+		Clusters:   c.Clusters,
+		Namespaces: c.Namespaces,
+		Redactions: c.Redactions,
+	}, nil
+}
+
+// NewJWTAuthenticator returns an Authenticator for JWTs signed with the keys of the JWKS file, issued by issuer for
+// audience, either of which is not checked when empty
+func NewJWTAuthenticator(jwksFile, issuer, audience string) (Authenticator, error) {
+	b, err := os.ReadFile(jwksFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read JWKS: %v", err)
+	}
+
+	a := &jwtAuthenticator{expected: jwt.Expected{Issuer: issuer}}
+	if err := json.Unmarshal(b, &a.keys); err != nil {
+		return nil, fmt.Errorf("unable to parse JWKS in %s: %v", jwksFile, err)
+	}
+	if audience != "" {
+		a.expected.AnyAudience = jwt.Audience{audience}
+	}
+
+	return a, nil
+}
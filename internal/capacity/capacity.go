@@ -0,0 +1,285 @@
+package capacity
+
+import (
+	"iter"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/simulator"
+)
+
+// Labels the instance type of a node is read from, the deprecated beta one last
+var instanceTypeLabels = []string{"node.kubernetes.io/instance-type", "beta.kubernetes.io/instance-type"}
+
+// planned are the resources recommendations are made for
+var planned = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+
+// Group is a set of nodes taken to be interchangeable when planning capacity
+type Group struct {
+	Roles        string // comma-separated, ordered
+	Architecture string
+	Provider     string // scheme of the provider ID, e.g. aws
+	InstanceType string
+}
+
+// GroupOf returns the group of the node. Provider IDs don't carry the instance type (e.g. aws:///us-west-2a/i-0123),
+// so it's read from the well-known instance type labels.
+func GroupOf(node *data.NodeMeta) Group {
+	roles := append([]string{}, node.Roles...)
+	sort.Strings(roles)
+
+	group := Group{
+		Roles:        strings.Join(roles, ","),
+		Architecture: node.Architecture,
+	}
+	if provider, _, ok := strings.Cut(node.ProviderID, "://"); ok {
+		group.Provider = provider
+	}
+	for _, label := range instanceTypeLabels {
+		if instanceType, ok := node.Labels[label]; ok {
+			group.InstanceType = instanceType
+			break
+		}
+	}
+
+	return group
+}
+
+// Less orders groups by roles, architecture, provider and instance type
+func (g Group) Less(other Group) bool {
+	if g.Roles != other.Roles {
+		return g.Roles < other.Roles
+	}
+	if g.Architecture != other.Architecture {
+		return g.Architecture < other.Architecture
+	}
+	if g.Provider != other.Provider {
+		return g.Provider < other.Provider
+	}
+	return g.InstanceType < other.InstanceType
+}
+
+// Recommendation is the capacity a group of nodes needed over a window, and how many of them would have sufficed
+type Recommendation struct {
+	Group
+	NodeCount            int                 // most nodes of the group at once
+	RecommendedNodeCount int                 // fewest nodes that would have fit what was requested at every instant
+	NodeAllocatable      corev1.ResourceList // smallest allocatable of a node of the group, which counts are based on
+	PeakRequested        corev1.ResourceList
+	P95Requested         corev1.ResourceList // requested for at least 95% of the window
+	PeakLimits           corev1.ResourceList
+	Stranded             corev1.ResourceList // allocatable never requested at any instant of the window
+}
+
+// usage is what's requested of a group of nodes at an instant, in milli-units
+type usage struct {
+	nodes       int
+	requested   map[corev1.ResourceName]int64
+	limits      map[corev1.ResourceName]int64
+	allocatable map[corev1.ResourceName]int64
+	pods        int64
+}
+
+// Plan returns a recommendation for each group of nodes from the cluster states in ascending order, each holding until
+// the next or until endAt for the last. Groups are ordered by roles, architecture, provider and instance type.
+func Plan(frames iter.Seq[*data.ClusterState], endAt time.Time) []*Recommendation {
+	recommendations := map[Group]*Recommendation{}
+	usages := map[Group][]*usage{}
+	var weights []time.Duration
+
+	for frame, until := range data.Spans(frames, endAt) {
+		i := len(weights)
+		weights = append(weights, until.Sub(frame.Timestamp))
+
+		frameUsages := map[Group]*usage{}
+		for _, node := range frame.Nodes {
+			group := GroupOf(node.Meta)
+			r, ok := recommendations[group]
+			if !ok {
+				r = &Recommendation{Group: group, NodeAllocatable: corev1.ResourceList{}}
+				recommendations[group] = r
+			}
+
+			u, ok := frameUsages[group]
+			if !ok {
+				u = &usage{
+					requested:   map[corev1.ResourceName]int64{},
+					limits:      map[corev1.ResourceName]int64{},
+					allocatable: map[corev1.ResourceName]int64{},
+				}
+				frameUsages[group] = u
+			}
+			u.nodes++
+
+			allocatable := simulator.Allocatable(node.Snapshot.State.Allocatable)
+			for _, name := range append(planned, corev1.ResourcePods) {
+				quantity, ok := allocatable[name]
+				if !ok {
+					continue
+				}
+				u.allocatable[name] += quantity.MilliValue()
+				if smallest, ok := r.NodeAllocatable[name]; !ok || quantity.Cmp(smallest) < 0 {
+					r.NodeAllocatable[name] = quantity
+				}
+			}
+
+			for _, podAt := range node.Pods {
+				if simulator.Terminated(podAt) {
+					continue
+				}
+				pod := simulator.NewPod(podAt)
+				for _, name := range planned {
+					if quantity, ok := pod.Requests[name]; ok {
+						u.requested[name] += quantity.MilliValue()
+					}
+					if quantity, ok := pod.Limits[name]; ok {
+						u.limits[name] += quantity.MilliValue()
+					}
+				}
+				u.pods++
+			}
+		}
+
+		// groups without nodes in the frame have nothing requested of them
+		for group := range recommendations {
+			u, ok := frameUsages[group]
+			if !ok {
+				u = &usage{requested: map[corev1.ResourceName]int64{}, limits: map[corev1.ResourceName]int64{}, allocatable: map[corev1.ResourceName]int64{}}
+			}
+			for len(usages[group]) < i {
+				usages[group] = append(usages[group], nil)
+			}
+			usages[group] = append(usages[group], u)
+		}
+	}
+
+	var plan []*Recommendation
+	for group, r := range recommendations {
+		r.recommend(usages[group], weights)
+		plan = append(plan, r)
+	}
+	sort.Slice(plan, func(i, j int) bool {
+		return plan[i].Group.Less(plan[j].Group)
+	})
+
+	return plan
+}
+
+// recommend sets the statistics of the recommendation from the usage of its group at each frame, weighted by how long
+// each held. Frames before the group's first node was seen are nil.
+func (r *Recommendation) recommend(usages []*usage, weights []time.Duration) {
+	peakRequested := map[corev1.ResourceName]int64{}
+	peakLimits := map[corev1.ResourceName]int64{}
+	stranded := map[corev1.ResourceName]int64{}
+	samples := map[corev1.ResourceName][]sample{}
+
+	for i, u := range usages {
+		if u == nil {
+			continue
+		}
+		if u.nodes > r.NodeCount {
+			r.NodeCount = u.nodes
+		}
+		if nodes := r.nodesFor(u); nodes > r.RecommendedNodeCount {
+			r.RecommendedNodeCount = nodes
+		}
+
+		for _, name := range planned {
+			peakRequested[name] = max(peakRequested[name], u.requested[name])
+			peakLimits[name] = max(peakLimits[name], u.limits[name])
+			if u.nodes > 0 {
+				free := max(u.allocatable[name]-u.requested[name], 0)
+				if current, ok := stranded[name]; !ok || free < current {
+					stranded[name] = free
+				}
+			}
+			samples[name] = append(samples[name], sample{value: u.requested[name], weight: weights[i]})
+		}
+	}
+
+	r.PeakRequested, r.P95Requested = corev1.ResourceList{}, corev1.ResourceList{}
+	r.PeakLimits, r.Stranded = corev1.ResourceList{}, corev1.ResourceList{}
+	for _, name := range planned {
+		r.PeakRequested[name] = quantity(name, peakRequested[name])
+		r.P95Requested[name] = quantity(name, percentile(samples[name], 0.95))
+		r.PeakLimits[name] = quantity(name, peakLimits[name])
+		r.Stranded[name] = quantity(name, stranded[name])
+	}
+}
+
+// nodesFor returns the fewest nodes of the group's smallest allocatable that fit what was requested at an instant
+func (r *Recommendation) nodesFor(u *usage) int {
+	nodes := 0
+	needed := map[corev1.ResourceName]int64{corev1.ResourcePods: u.pods * 1000}
+	for _, name := range planned {
+		needed[name] = u.requested[name]
+	}
+
+	for name, requested := range needed {
+		allocatable, ok := r.NodeAllocatable[name]
+		if !ok || allocatable.MilliValue() <= 0 {
+			continue
+		}
+		nodes = max(nodes, int(math.Ceil(float64(requested)/float64(allocatable.MilliValue()))))
+	}
+	if u.pods > 0 {
+		nodes = max(nodes, 1)
+	}
+
+	return nodes
+}
+
+// sample is a value held for a duration
+type sample struct {
+	value  int64
+	weight time.Duration
+}
+
+// percentile returns the smallest value held for at least the share p of the total duration of the samples, or of
+// their count if they add up to no time
+func percentile(samples []sample, p float64) int64 {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	sorted := append([]sample{}, samples...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].value < sorted[j].value
+	})
+
+	var total time.Duration
+	for _, s := range sorted {
+		total += s.weight
+	}
+	if total <= 0 {
+		for i := range sorted {
+			sorted[i].weight = 1
+		}
+		total = time.Duration(len(sorted))
+	}
+
+	var cumulative time.Duration
+	for _, s := range sorted {
+		cumulative += s.weight
+		if float64(cumulative) >= p*float64(total) {
+			return s.value
+		}
+	}
+
+	return sorted[len(sorted)-1].value
+}
+
+// quantity returns the milli-units of the resource as a quantity formatted like Kubernetes does
+func quantity(name corev1.ResourceName, milli int64) resource.Quantity {
+	if name == corev1.ResourceCPU {
+		return *resource.NewMilliQuantity(milli, resource.DecimalSI)
+	}
+
+	return *resource.NewQuantity(milli/1000, resource.BinarySI)
+}
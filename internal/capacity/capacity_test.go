@@ -0,0 +1,76 @@
+package capacity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/ccpeng/kube-replay/internal/capacity"
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+func TestPlan(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	requesting := func(cpu, memory string) []*data.ContainerSnapshot {
+		return []*data.ContainerSnapshot{{Resources: data.ContainerResources{
+			Requests: data.ContainerResource{Cpu: cpu, Memory: memory},
+			Limits:   data.ContainerResource{Cpu: "4", Memory: memory},
+		}}}
+	}
+	worker := func(id string, pods ...*data.PodMeta) *data.NodeMeta {
+		return &data.NodeMeta{
+			ID: id, Name: id, Roles: []string{"worker"}, Architecture: "amd64", ProviderID: "aws:///us-west-2a/i-" + id,
+			Labels:    map[string]string{"node.kubernetes.io/instance-type": "m5.large"},
+			Snapshots: data.NodeSnapshots{{Timestamp: t0, State: data.NodeState{Allocatable: data.NodeCapacity{Cpu: "2", Memory: "8Gi", Pods: 110}}}},
+			Pods:      pods,
+		}
+	}
+
+	// web requests a CPU throughout, and a batch job two more for a minute an hour in
+	nodes := []*data.NodeMeta{
+		worker("a", &data.PodMeta{ID: "web", Snapshots: data.PodSnapshots{
+			{Timestamp: t0, Status: data.PodPhaseRunning, Containers: requesting("1", "2Gi")},
+		}}),
+		worker("b", &data.PodMeta{ID: "batch", Snapshots: data.PodSnapshots{
+			{Timestamp: t0.Add(time.Hour), Status: data.PodPhaseRunning, Containers: requesting("2", "1Gi")},
+			{Timestamp: t0.Add(61 * time.Minute), Status: data.PodPhaseSucceeded, Containers: requesting("2", "1Gi")},
+		}}),
+		{
+			ID: "cp", Name: "cp", Roles: []string{"control-plane"}, Architecture: "arm64",
+			Snapshots: data.NodeSnapshots{{Timestamp: t0, State: data.NodeState{Allocatable: data.NodeCapacity{Cpu: "4", Memory: "16Gi"}}}},
+		},
+	}
+	for _, node := range nodes {
+		for _, pod := range node.Pods {
+			pod.SetDynamoAttributes(node.ID)
+		}
+	}
+
+	times := []time.Time{t0, t0.Add(time.Hour), t0.Add(61 * time.Minute)}
+	plan := capacity.Plan(data.SweepSeq(nodes, times), t0.Add(2*time.Hour))
+	g.Expect(plan).To(gomega.HaveLen(2))
+
+	g.Expect(plan[0].Group).To(gomega.Equal(capacity.Group{Roles: "control-plane", Architecture: "arm64"}))
+	g.Expect(plan[0].NodeCount).To(gomega.Equal(1))
+	g.Expect(plan[0].RecommendedNodeCount).To(gomega.Equal(0))
+	g.Expect(plan[0].Stranded.Cpu().String()).To(gomega.Equal("4"))
+
+	workers := plan[1]
+	g.Expect(workers.Group).To(gomega.Equal(capacity.Group{Roles: "worker", Architecture: "amd64", Provider: "aws", InstanceType: "m5.large"}))
+	g.Expect(workers.NodeCount).To(gomega.Equal(2))
+	g.Expect(workers.RecommendedNodeCount).To(gomega.Equal(2))
+	g.Expect(workers.NodeAllocatable.Cpu().String()).To(gomega.Equal("2"))
+	g.Expect(workers.PeakRequested.Cpu().String()).To(gomega.Equal("3"))
+	g.Expect(workers.PeakRequested.Memory().String()).To(gomega.Equal("3Gi"))
+	// the spike held for a minute of the two hours
+	g.Expect(workers.P95Requested.Cpu().String()).To(gomega.Equal("1"))
+	g.Expect(workers.PeakLimits.Cpu().String()).To(gomega.Equal("8"))
+	g.Expect(workers.Stranded.Cpu().String()).To(gomega.Equal("1"))
+	g.Expect(workers.Stranded.Memory().Equal(resource.MustParse("13Gi"))).To(gomega.BeTrue())
+	g.Expect(workers.NodeAllocatable).To(gomega.HaveKey(corev1.ResourcePods))
+}
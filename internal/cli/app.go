@@ -0,0 +1,554 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/cost"
+	"github.com/ccpeng/kube-replay/internal/manifests"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+var outputFlag = &cli.StringFlag{
+	Name:    "output",
+	Aliases: []string{"o"},
+	Usage:   "output format: table, json or yaml",
+	Value:   OutputTable,
+}
+
+var manifestOutputFlag = &cli.StringFlag{
+	Name:    "output",
+	Aliases: []string{"o"},
+	Usage:   "output format: json or yaml",
+	Value:   OutputYAML,
+}
+
+// NewApp returns the kube-replay CLI, writing what it replays to w
+func NewApp(w io.Writer) *cli.App {
+	return &cli.App{
+		Name:      "kube-replay",
+		Usage:     "replay the recorded state of a Kubernetes cluster",
+		Writer:    w,
+		ErrWriter: w,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "server",
+				Usage:   "GraphQL endpoint of the kube-replay server",
+				EnvVars: []string{"KUBE_REPLAY_SERVER"},
+				Value:   "http://localhost:8080/query",
+			},
+			&cli.StringFlag{
+				Name:    "token",
+				Usage:   "bearer JWT to authenticate to the server with",
+				EnvVars: []string{"KUBE_REPLAY_TOKEN"},
+			},
+			&cli.StringFlag{
+				Name:    "api-key",
+				Usage:   "API key to authenticate to the server with",
+				EnvVars: []string{"KUBE_REPLAY_API_KEY"},
+			},
+			&cli.BoolFlag{
+				Name:  "local",
+				Usage: "read the DynamoDB table of the cluster directly instead of querying the server",
+			},
+			&cli.StringFlag{
+				Name:    "cluster",
+				Usage:   "cluster (i.e. DynamoDB table) to read with --local",
+				EnvVars: []string{"CLUSTER_NAME"},
+				Value:   "k8s",
+			},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:  "get",
+				Usage: "list nodes or pods as they were at a time",
+				Subcommands: []*cli.Command{
+					{
+						Name:    "nodes",
+						Aliases: []string{"node", "no"},
+						Usage:   "list nodes",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "at", Usage: "time to replay", Value: "now"},
+							&cli.StringFlag{Name: "selector", Aliases: []string{"l"}, Usage: "label selector nodes must match"},
+							outputFlag,
+						},
+						Action: getNodes,
+					},
+					{
+						Name:    "pods",
+						Aliases: []string{"pod", "po"},
+						Usage:   "list pods",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "at", Usage: "time to replay", Value: "now"},
+							&cli.StringFlag{Name: "namespace", Aliases: []string{"n"}, Usage: "namespace of the pods", Value: "default"},
+							&cli.BoolFlag{Name: "all-namespaces", Aliases: []string{"A"}, Usage: "list pods of every namespace"},
+							&cli.StringFlag{Name: "node", Usage: "name of the node the pods are bound to"},
+							outputFlag,
+						},
+						Action: getPods,
+					},
+				},
+			},
+			{
+				Name:  "diff",
+				Usage: "list what changed from one time to another",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "from", Usage: "time to compare from", Required: true},
+					&cli.StringFlag{Name: "to", Usage: "time to compare to", Value: "now"},
+					&cli.StringFlag{Name: "namespace", Aliases: []string{"n"}, Usage: "namespace of the pods, all if not set"},
+					outputFlag,
+				},
+				Action: diff,
+			},
+			{
+				Name:  "history",
+				Usage: "list every snapshot of an object",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "pod",
+						Usage:     "list the nodes a pod was bound to and its snapshots",
+						ArgsUsage: "<name>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "namespace", Aliases: []string{"n"}, Usage: "namespace of the pod", Value: "default"},
+							&cli.StringFlag{Name: "since", Usage: "start of the history", Value: "24h"},
+							&cli.StringFlag{Name: "until", Usage: "end of the history", Value: "now"},
+							outputFlag,
+						},
+						Action: podHistory,
+					},
+				},
+			},
+			{
+				Name:  "export",
+				Usage: "write nodes or pods as they were at a time as Kubernetes manifests, with their status",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "nodes",
+						Usage: "write a v1.NodeList",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "at", Usage: "time to replay", Value: "now"},
+							&cli.StringFlag{Name: "selector", Aliases: []string{"l"}, Usage: "label selector nodes must match"},
+							manifestOutputFlag,
+						},
+						Action: exportNodes,
+					},
+					{
+						Name:  "pods",
+						Usage: "write a v1.PodList",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "at", Usage: "time to replay", Value: "now"},
+							&cli.StringFlag{Name: "namespace", Aliases: []string{"n"}, Usage: "namespace of the pods", Value: "default"},
+							&cli.BoolFlag{Name: "all-namespaces", Aliases: []string{"A"}, Usage: "write pods of every namespace"},
+							&cli.StringFlag{Name: "node", Usage: "name of the node the pods are bound to"},
+							manifestOutputFlag,
+						},
+						Action: exportPods,
+					},
+				},
+			},
+			{
+				Name:  "events",
+				Usage: "list every change to the cluster in a range",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "since", Usage: "start of the range", Value: "1h"},
+					&cli.StringFlag{Name: "until", Usage: "end of the range", Value: "now"},
+					&cli.StringFlag{Name: "namespace", Aliases: []string{"n"}, Usage: "namespace of the pods, all if not set"},
+					outputFlag,
+				},
+				Action: events,
+			},
+			{
+				Name:  "anomalies",
+				Usage: "list containers that crash looped, were OOMKilled repeatedly or restarted in a spike in a range",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "since", Usage: "start of the range", Value: "1h"},
+					&cli.StringFlag{Name: "until", Usage: "end of the range", Value: "now"},
+					&cli.StringFlag{Name: "namespace", Aliases: []string{"n"}, Usage: "namespace of the pods, all if not set"},
+					outputFlag,
+				},
+				Action: restartAnomalies,
+			},
+			{
+				Name:  "cost",
+				Usage: "attribute the cost of the nodes in a range to the namespaces and pods requesting them",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "since", Usage: "start of the range", Value: "24h"},
+					&cli.StringFlag{Name: "until", Usage: "end of the range", Value: "now"},
+					&cli.StringFlag{
+						Name:    "price-table",
+						Usage:   "JSON file of hourly prices of nodes by instance type, to read with --local",
+						EnvVars: []string{"PRICE_TABLE_FILE"},
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "output format: table, json, yaml or csv",
+						Value:   OutputTable,
+					},
+				},
+				Action: costReport,
+			},
+		},
+	}
+}
+
+func getNodes(c *cli.Context) error {
+	source, output, err := setup(c)
+	if err != nil {
+		return err
+	}
+	at, err := parseTime(c.String("at"), time.Now())
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := source.EffectiveAtSnapshot(c.Context, at, nodeFilter(c))
+	if err != nil {
+		return err
+	}
+
+	if output != OutputTable {
+		return printObject(c.App.Writer, output, snapshot)
+	}
+	return printNodes(c.App.Writer, snapshot)
+}
+
+func getPods(c *cli.Context) error {
+	source, output, err := setup(c)
+	if err != nil {
+		return err
+	}
+	at, err := parseTime(c.String("at"), time.Now())
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := source.EffectiveAtSnapshot(c.Context, at, podFilter(c))
+	if err != nil {
+		return err
+	}
+
+	if output != OutputTable {
+		pods := []*model.PodSnapshot{}
+		for _, pod := range sortedPods(snapshot.Nodes) {
+			pods = append(pods, pod.pod)
+		}
+		return printObject(c.App.Writer, output, pods)
+	}
+	return printPods(c.App.Writer, snapshot, c.Bool("all-namespaces"))
+}
+
+func exportNodes(c *cli.Context) error {
+	m, output, err := exportManifests(c, nodeFilter(c))
+	if err != nil {
+		return err
+	}
+
+	return printObject(c.App.Writer, output, m.Nodes)
+}
+
+func exportPods(c *cli.Context) error {
+	m, output, err := exportManifests(c, podFilter(c))
+	if err != nil {
+		return err
+	}
+
+	return printObject(c.App.Writer, output, m.Pods)
+}
+
+// exportManifests returns the manifests at the time given, and the output format which must not be a table
+func exportManifests(c *cli.Context, filter *model.SnapshotFilter) (*manifests.Manifests, string, error) {
+	source, output, err := setup(c)
+	if err != nil {
+		return nil, "", err
+	}
+	if output == OutputTable {
+		return nil, "", fmt.Errorf("manifests can only be written as json or yaml")
+	}
+	at, err := parseTime(c.String("at"), time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+
+	m, err := source.EffectiveAtManifests(c.Context, at, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return m, output, nil
+}
+
+func diff(c *cli.Context) error {
+	source, output, err := setup(c)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	from, err := parseTime(c.String("from"), now)
+	if err != nil {
+		return err
+	}
+	to, err := parseTime(c.String("to"), now)
+	if err != nil {
+		return err
+	}
+
+	filter := namespaceFilter(c.String("namespace"))
+	fromSnapshot, err := source.EffectiveAtSnapshot(c.Context, from, filter)
+	if err != nil {
+		return err
+	}
+	toSnapshot, err := source.EffectiveAtSnapshot(c.Context, to, filter)
+	if err != nil {
+		return err
+	}
+
+	return writeEvents(c, output, services.Diff(fromSnapshot, toSnapshot))
+}
+
+func podHistory(c *cli.Context) error {
+	args, err := interspersed(c)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("expected the name of the pod, got %d arguments", len(args))
+	}
+	source, output, err := setup(c)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	since, err := parseTime(c.String("since"), now)
+	if err != nil {
+		return err
+	}
+	until, err := parseTime(c.String("until"), now)
+	if err != nil {
+		return err
+	}
+
+	history, err := source.PodHistory(c.Context, c.String("namespace"), args[0], since, until)
+	if err != nil {
+		return err
+	}
+
+	if output != OutputTable {
+		return printObject(c.App.Writer, output, history)
+	}
+	return printHistory(c.App.Writer, history)
+}
+
+func events(c *cli.Context) error {
+	source, output, err := setup(c)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	since, err := parseTime(c.String("since"), now)
+	if err != nil {
+		return err
+	}
+	until, err := parseTime(c.String("until"), now)
+	if err != nil {
+		return err
+	}
+
+	events, err := source.ClusterEvents(c.Context, since, until, namespaceFilter(c.String("namespace")))
+	if err != nil {
+		return err
+	}
+
+	return writeEvents(c, output, events)
+}
+
+func writeEvents(c *cli.Context, output string, events []*model.ClusterEvent) error {
+	if output != OutputTable {
+		return printObject(c.App.Writer, output, events)
+	}
+	return printEvents(c.App.Writer, events)
+}
+
+func restartAnomalies(c *cli.Context) error {
+	source, output, err := setup(c)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	since, err := parseTime(c.String("since"), now)
+	if err != nil {
+		return err
+	}
+	until, err := parseTime(c.String("until"), now)
+	if err != nil {
+		return err
+	}
+
+	var namespace *string
+	if n := c.String("namespace"); n != "" {
+		namespace = &n
+	}
+	anomalies, err := source.RestartAnomalies(c.Context, since, until, namespace)
+	if err != nil {
+		return err
+	}
+
+	if output != OutputTable {
+		return printObject(c.App.Writer, output, anomalies)
+	}
+	return printAnomalies(c.App.Writer, anomalies)
+}
+
+func costReport(c *cli.Context) error {
+	// only the cost report can be written as CSV
+	output := c.String("output")
+	var source Source
+	var err error
+	if output == OutputCSV {
+		source, err = newSource(c)
+	} else {
+		source, output, err = setup(c)
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	since, err := parseTime(c.String("since"), now)
+	if err != nil {
+		return err
+	}
+	until, err := parseTime(c.String("until"), now)
+	if err != nil {
+		return err
+	}
+
+	report, err := source.CostReport(c.Context, since, until)
+	if err != nil {
+		return err
+	}
+
+	switch output {
+	case OutputCSV:
+		return printCostCSV(c.App.Writer, report)
+	case OutputTable:
+		return printCost(c.App.Writer, report)
+	default:
+		return printObject(c.App.Writer, output, report)
+	}
+}
+
+// setup returns the source to replay from given the global flags, and the output format of the command
+func setup(c *cli.Context) (Source, string, error) {
+	output := c.String("output")
+	switch output {
+	case OutputTable, OutputJSON, OutputYAML:
+	default:
+		return nil, "", fmt.Errorf("unknown output format %q, expected table, json or yaml", output)
+	}
+
+	source, err := newSource(c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return source, output, nil
+}
+
+// newSource returns the server to query given the global flags, or the store of the cluster with --local
+func newSource(c *cli.Context) (Source, error) {
+	if !c.Bool("local") {
+		return NewGraphQLSource(c.String("server"), c.String("token"), c.String("api-key")), nil
+	}
+
+	var opts []services.Option
+	if file := c.String("price-table"); file != "" {
+		prices, err := cost.LoadPrices(file)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, services.WithPrices(prices))
+	}
+
+	return services.NewReplayer(c.String("cluster"), opts...)
+}
+
+// interspersed parses the flags given after the arguments of the command, as kubectl allows, returning the arguments
+func interspersed(c *cli.Context) ([]string, error) {
+	var args []string
+	rest := c.Args().Slice()
+	for len(rest) > 0 {
+		set := flag.NewFlagSet(c.Command.Name, flag.ContinueOnError)
+		set.SetOutput(io.Discard)
+		for _, f := range c.Command.Flags {
+			if err := f.Apply(set); err != nil {
+				return nil, err
+			}
+		}
+		if err := set.Parse(rest); err != nil {
+			return nil, err
+		}
+
+		// aliases are separate flags until urfave/cli normalizes them, so set every name of the flag
+		var err error
+		set.Visit(func(f *flag.Flag) {
+			for _, cliFlag := range c.Command.Flags {
+				if !slices.Contains(cliFlag.Names(), f.Name) {
+					continue
+				}
+				for _, name := range cliFlag.Names() {
+					if err == nil {
+						err = c.Set(name, f.Value.String())
+					}
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if set.NArg() == 0 {
+			break
+		}
+		args = append(args, set.Arg(0))
+		rest = set.Args()[1:]
+	}
+
+	return args, nil
+}
+
+// nodeFilter returns the filter for the nodes matching the selector flag, if any
+func nodeFilter(c *cli.Context) *model.SnapshotFilter {
+	selector := c.String("selector")
+	if selector == "" {
+		return nil
+	}
+
+	return &model.SnapshotFilter{Nodes: &model.NodeFilter{LabelSelector: &selector}}
+}
+
+// podFilter returns the filter for the pods in the namespace and on the node of the flags
+func podFilter(c *cli.Context) *model.SnapshotFilter {
+	filter := &model.SnapshotFilter{}
+	if !c.Bool("all-namespaces") {
+		filter.Pods = &model.PodFilter{Namespaces: []string{c.String("namespace")}}
+	}
+	if node := c.String("node"); node != "" {
+		filter.Nodes = &model.NodeFilter{Names: []string{node}}
+	}
+
+	return filter
+}
+
+func namespaceFilter(namespace string) *model.SnapshotFilter {
+	if namespace == "" {
+		return nil
+	}
+
+	return &model.SnapshotFilter{Pods: &model.PodFilter{Namespaces: []string{namespace}}}
+}
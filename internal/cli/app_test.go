@@ -0,0 +1,123 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/ccpeng/kube-replay/graph"
+	"github.com/ccpeng/kube-replay/internal/auth"
+	"github.com/ccpeng/kube-replay/internal/cli"
+	"github.com/ccpeng/kube-replay/internal/cost"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+func TestApp(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	ctx := context.Background()
+	store := repositories.NewMemoryStore()
+
+	err := store.Upsert(ctx, &data.NodeMeta{
+		ID:        "node-a",
+		Name:      "a",
+		Labels:    map[string]string{"node.kubernetes.io/instance-type": "m5.large"},
+		Snapshots: data.NodeSnapshots{{Timestamp: t0, State: data.NodeState{Condition: data.NodeStateReady}}},
+		Pods: []*data.PodMeta{
+			{
+				ID: "uid-1", Name: "web-0", Namespace: "shop", StartedAt: t0,
+				Snapshots: data.PodSnapshots{
+					{Timestamp: t0, Status: data.PodPhasePending},
+					{Timestamp: t0.Add(time.Minute), Status: data.PodPhaseRunning, Containers: []*data.ContainerSnapshot{{
+						Name: "web", Ready: true, RestartCount: 2, StartedAt: t0.Add(time.Minute),
+						State: data.ContainerState{StartedAt: t0.Add(time.Minute)},
+					}}},
+				},
+			},
+			{ID: "uid-2", Name: "db-0", Namespace: "data", StartedAt: t0, Snapshots: data.PodSnapshots{{Timestamp: t0, Status: data.PodPhaseRunning}}},
+		},
+	})
+	g.Expect(err).To(gomega.BeNil())
+
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers: &graph.Resolver{Replayer: services.NewReplayerWithStore(store, services.WithPrices(cost.Prices{"m5.large": 0.1}))},
+	}))
+	srv.AddTransport(transport.POST{})
+	server := httptest.NewServer(auth.Middleware(auth.Disabled)(srv))
+	defer server.Close()
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := cli.NewApp(&out).RunContext(ctx, append([]string{"kube-replay", "--server", server.URL}, args...))
+		return out.String(), err
+	}
+
+	out, err := run("get", "nodes", "--at", "2025-04-27T00:05Z")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(out).To(gomega.MatchRegexp(`NAME\s+STATUS\s+ROLES\s+PODS\s+VERSION\s+LAST SEEN\n`))
+	g.Expect(out).To(gomega.MatchRegexp(`a\s+Ready\s+<none>\s+2\s+\s+5m\n`))
+
+	out, err = run("get", "pods", "-n", "shop", "--at", "2025-04-27T00:05Z")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(out).To(gomega.MatchRegexp(`web-0\s+1/1\s+Running\s+2\s+5m\s+a\n`))
+	g.Expect(out).NotTo(gomega.ContainSubstring("db-0"))
+
+	out, err = run("get", "pods", "-A", "--at", "2025-04-27T00:05Z", "-o", "json")
+	g.Expect(err).To(gomega.BeNil())
+	var pods []map[string]interface{}
+	g.Expect(json.Unmarshal([]byte(out), &pods)).To(gomega.Succeed())
+	g.Expect(pods).To(gomega.HaveLen(2))
+	g.Expect(pods[0]["name"]).To(gomega.Equal("db-0"))
+
+	out, err = run("events", "--since", "2025-04-27T00:00Z", "--until", "2025-04-27T01:00Z")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(out).To(gomega.ContainSubstring("Pod shop/web-0 went from Pending to Running"))
+
+	out, err = run("diff", "--from", "2025-04-27T00:00Z", "--to", "2025-04-27T00:05Z", "-o", "yaml")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(out).To(gomega.ContainSubstring("kind: PodPhaseChanged"))
+
+	out, err = run("history", "pod", "web-0", "-n", "shop", "--since", "2025-04-27", "--until", "2025-04-28")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(out).To(gomega.MatchRegexp(`uid-1\s+node-a\s+2025-04-27T00:00:00Z`))
+
+	out, err = run("export", "pods", "-n", "shop", "--at", "2025-04-27T00:05Z")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(out).To(gomega.ContainSubstring("kind: PodList"))
+	g.Expect(out).To(gomega.ContainSubstring("nodeName: a"))
+	g.Expect(out).NotTo(gomega.ContainSubstring("db-0"))
+
+	out, err = run("export", "nodes", "--at", "2025-04-27T00:05Z", "-o", "json")
+	g.Expect(err).To(gomega.BeNil())
+	var nodes corev1.NodeList
+	g.Expect(json.Unmarshal([]byte(out), &nodes)).To(gomega.Succeed())
+	g.Expect(nodes.Items).To(gomega.HaveLen(1))
+	g.Expect(nodes.Items[0].Name).To(gomega.Equal("a"))
+
+	// web-0 restarted twice, too few to be a spike
+	out, err = run("anomalies", "--since", "2025-04-27T00:00Z", "--until", "2025-04-27T01:00Z", "-n", "shop")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(out).To(gomega.MatchRegexp(`^NAMESPACE\s+WORKLOAD\s+POD\s+CONTAINER\s+SEVERITY\s+RESTARTS\s+OOMKILLS\s+FIRST SEEN\n$`))
+
+	// nothing is requested of the node, so its hour is idle
+	out, err = run("cost", "--since", "2025-04-27T00:00Z", "--until", "2025-04-27T01:00Z", "-o", "csv")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(out).To(gomega.Equal("namespace,pod,cost\n,<idle>,0.100000\n"))
+
+	_, err = run("get", "nodes", "--at", "yesterday")
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(`unable to parse time "yesterday"`)))
+
+	_, err = run("get", "nodes", "-o", "wide")
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("unknown output format")))
+}
@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+)
+
+// Output formats of the CLI
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputCSV   = "csv"
+)
+
+// printObject writes v as indented JSON or YAML
+func printObject(w io.Writer, output string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode output: %v", err)
+	}
+
+	if output == OutputYAML {
+		if b, err = yaml.JSONToYAML(b); err != nil {
+			return fmt.Errorf("unable to encode output: %v", err)
+		}
+	} else {
+		b = append(b, '\n')
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// printNodes writes a table of the nodes of the snapshot, like kubectl get nodes
+func printNodes(w io.Writer, snapshot *model.TimedNodeSnapshots) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATUS\tROLES\tPODS\tVERSION\tLAST SEEN")
+	for _, node := range sortedNodes(snapshot.Nodes) {
+		roles := "<none>"
+		if len(node.Roles) > 0 {
+			roles = strings.Join(node.Roles, ",")
+		}
+		version := "<unknown>"
+		if node.Info != nil {
+			version = node.Info.KubeletVersion
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			node.Name, nodeStatus(node), roles, len(node.Pods), version, age(snapshot.Timestamp.Sub(node.Timestamp)))
+	}
+
+	return tw.Flush()
+}
+
+// printPods writes a table of the pods of the snapshot, like kubectl get pods, with their namespaces if allNamespaces
+func printPods(w io.Writer, snapshot *model.TimedNodeSnapshots, allNamespaces bool) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	if allNamespaces {
+		fmt.Fprint(tw, "NAMESPACE\t")
+	}
+	fmt.Fprintln(tw, "NAME\tREADY\tSTATUS\tRESTARTS\tAGE\tNODE")
+	for _, pod := range sortedPods(snapshot.Nodes) {
+		if allNamespaces {
+			fmt.Fprintf(tw, "%s\t", valueOf(pod.pod.Namespace))
+		}
+		ready, restarts := containerCounts(pod.pod)
+		fmt.Fprintf(tw, "%s\t%d/%d\t%s\t%d\t%s\t%s\n",
+			pod.pod.Name, ready, len(pod.pod.Containers), pod.pod.Status, restarts,
+			age(snapshot.Timestamp.Sub(pod.pod.StartedAt)), pod.node)
+	}
+
+	return tw.Flush()
+}
+
+// printEvents writes a table of the events, oldest first
+func printEvents(w io.Writer, events []*model.ClusterEvent) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tKIND\tOBJECT\tMESSAGE")
+	for _, event := range events {
+		object := "node/" + event.NodeName
+		if event.PodName != nil {
+			object = "pod/" + valueOf(event.Namespace) + "/" + *event.PodName
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", event.Timestamp.UTC().Format(time.RFC3339), event.Kind, object, event.Message)
+	}
+
+	return tw.Flush()
+}
+
+// printHistory writes the nodes the pod was bound to, then a table of its snapshots
+func printHistory(w io.Writer, history *model.PodHistory) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "UID\tNODE\tFROM\tTO")
+	for _, binding := range history.Bindings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			binding.PodID, binding.NodeID, binding.From.UTC().Format(time.RFC3339), binding.To.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "TIME\tUID\tNODE\tREADY\tSTATUS\tRESTARTS")
+	for _, pod := range history.Snapshots {
+		ready, restarts := containerCounts(pod)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d/%d\t%s\t%d\n",
+			pod.Timestamp.UTC().Format(time.RFC3339), pod.ID, pod.NodeID, ready, len(pod.Containers), pod.Status, restarts)
+	}
+
+	return tw.Flush()
+}
+
+// printAnomalies writes a table of the restart anomalies, with when the most severe kind of each was first seen
+func printAnomalies(w io.Writer, anomalies []*model.RestartAnomaly) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tWORKLOAD\tPOD\tCONTAINER\tSEVERITY\tRESTARTS\tOOMKILLS\tFIRST SEEN")
+	for _, anomaly := range anomalies {
+		firstSeen := ""
+		for _, occurrence := range anomaly.Occurrences {
+			if occurrence.Kind == anomaly.Severity {
+				firstSeen = occurrence.FirstSeen.UTC().Format(time.RFC3339)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n", anomaly.Namespace, anomaly.Workload, anomaly.Name,
+			anomaly.Container, anomaly.Severity, anomaly.Restarts, anomaly.OomKills, firstSeen)
+	}
+
+	return tw.Flush()
+}
+
+// printCost writes a table of the cost of each namespace, then what was idle or left unpriced
+func printCost(w io.Writer, report *model.CostReport) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tPODS\tCOST")
+	for _, namespace := range report.Namespaces {
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\n", namespace.Namespace, len(namespace.Pods), namespace.Cost)
+	}
+	fmt.Fprintf(tw, "<idle>\t\t%.2f\n", report.IdleCost)
+	fmt.Fprintf(tw, "<total>\t\t%.2f\n", report.TotalCost)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(report.UnpricedInstanceTypes) > 0 {
+		_, err := fmt.Fprintf(w, "\nnodes of unpriced instance types aren't counted: %s\n", strings.Join(report.UnpricedInstanceTypes, ", "))
+		return err
+	}
+	return nil
+}
+
+// printCostCSV writes the cost of each pod as CSV, with the idle cost as a row of its own
+func printCostCSV(w io.Writer, report *model.CostReport) error {
+	cw := csv.NewWriter(w)
+	records := [][]string{{"namespace", "pod", "cost"}}
+	for _, namespace := range report.Namespaces {
+		for _, pod := range namespace.Pods {
+			records = append(records, []string{pod.Namespace, pod.Name, strconv.FormatFloat(pod.Cost, 'f', 6, 64)})
+		}
+	}
+	records = append(records, []string{"", "<idle>", strconv.FormatFloat(report.IdleCost, 'f', 6, 64)})
+
+	return cw.WriteAll(records)
+}
+
+// nodeStatus formats the status of the node as kubectl does, noting when it's cordoned
+func nodeStatus(node *model.NodeSnapshot) string {
+	if node.State == nil {
+		return string(model.NodeConditionUnknown)
+	}
+
+	status := string(node.State.Status)
+	if node.State.Unschedulable != nil && *node.State.Unschedulable {
+		status += ",SchedulingDisabled"
+	}
+
+	return status
+}
+
+// containerCounts returns how many containers of the pod are ready and how many times they restarted in all
+func containerCounts(pod *model.PodSnapshot) (ready int, restarts int64) {
+	for _, container := range pod.Containers {
+		if container.Ready {
+			ready++
+		}
+		if container.RestartCount != nil {
+			restarts += *container.RestartCount
+		}
+	}
+
+	return ready, restarts
+}
+
+func sortedNodes(nodes []*model.NodeSnapshot) []*model.NodeSnapshot {
+	sorted := append([]*model.NodeSnapshot{}, nodes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	return sorted
+}
+
+// boundPod is a pod along with the name of the node it's bound to
+type boundPod struct {
+	pod  *model.PodSnapshot
+	node string
+}
+
+// sortedPods returns the pods of every node, ordered by namespace and name
+func sortedPods(nodes []*model.NodeSnapshot) []boundPod {
+	var pods []boundPod
+	for _, node := range nodes {
+		for _, pod := range node.Pods {
+			pods = append(pods, boundPod{pod: pod, node: node.Name})
+		}
+	}
+	sort.SliceStable(pods, func(i, j int) bool {
+		if a, b := valueOf(pods[i].pod.Namespace), valueOf(pods[j].pod.Namespace); a != b {
+			return a < b
+		}
+		return pods[i].pod.Name < pods[j].pod.Name
+	})
+
+	return pods
+}
+
+func valueOf(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
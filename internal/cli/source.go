@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/manifests"
+)
+
+// Source replays the cluster for the CLI, either a GraphQL server or a store read directly
+type Source interface {
+	EffectiveAtSnapshot(ctx context.Context, t time.Time, filter *model.SnapshotFilter) (*model.TimedNodeSnapshots, error)
+	PodHistory(ctx context.Context, namespace, name string, beginAt, endAt time.Time) (*model.PodHistory, error)
+	ClusterEvents(ctx context.Context, beginAt, endAt time.Time, filter *model.SnapshotFilter) ([]*model.ClusterEvent, error)
+	EffectiveAtManifests(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) (*manifests.Manifests, error)
+	CostReport(ctx context.Context, beginAt, endAt time.Time) (*model.CostReport, error)
+	RestartAnomalies(ctx context.Context, beginAt, endAt time.Time, namespace *string) ([]*model.RestartAnomaly, error)
+}
+
+const podFields = `
+fragment PodFields on PodSnapshot {
+  id nodeID timestamp name namespace status startedAt deletedAt finishedAt deletedBy qosClass
+  containers { containerID name image imageID ready restartCount startedAt running state { exitCode startedAt finishedAt reason } }
+}`
+
+const nodeStatesAtTimestampQuery = `
+query NodeStatesAtTimestamp($timestamp: Time!, $filter: SnapshotFilter) {
+  nodeStatesAtTimestamp(timestamp: $timestamp, filter: $filter) {
+    timestamp
+    nodes {
+      id timestamp name roles labels { key value } providerID deletedAt
+      info { architecture containerRuntimeVersion kernelVersion kubeletVersion kubeProxyVersion osImage operatingSystem machineId systemUUID bootID }
+      state {
+        status unschedulable
+        capacity { cpu memory ephemeralStorage pods }
+        allocatable { cpu memory ephemeralStorage pods }
+        taints { key value effect }
+      }
+      pods { ...PodFields }
+    }
+  }
+}` + podFields
+
+const podHistoryQuery = `
+query PodHistory($namespace: String!, $name: String!, $start: Time!, $end: Time!) {
+  podHistory(namespace: $namespace, name: $name, start: $start, end: $end) {
+    namespace name
+    bindings { podID nodeID from to }
+    snapshots { ...PodFields }
+  }
+}` + podFields
+
+const clusterEventsQuery = `
+query ClusterEvents($start: Time!, $end: Time!, $filter: SnapshotFilter) {
+  clusterEvents(start: $start, end: $end, filter: $filter) {
+    timestamp kind nodeID nodeName podID namespace podName message
+  }
+}`
+
+const manifestsAtTimestampQuery = `
+query ManifestsAtTimestamp($timestamp: Time!, $filter: SnapshotFilter) {
+  manifestsAtTimestamp(timestamp: $timestamp, filter: $filter) { timestamp nodeList podList }
+}`
+
+const costReportQuery = `
+query CostReport($start: Time!, $end: Time!) {
+  costReport(start: $start, end: $end) {
+    start end totalCost idleCost unpricedInstanceTypes
+    namespaces { namespace cost pods { namespace name cost } }
+  }
+}`
+
+const restartAnomaliesQuery = `
+query RestartAnomalies($start: Time!, $end: Time!, $namespace: String) {
+  restartAnomalies(start: $start, end: $end, namespace: $namespace) {
+    podID namespace name workload container nodeID severity restarts oomKills
+    occurrences { kind firstSeen }
+  }
+}`
+
+// graphQLSource replays the cluster by querying a kube-replay server
+type graphQLSource struct {
+	endpoint string
+	client   *http.Client
+	header   http.Header
+}
+
+func (s *graphQLSource) EffectiveAtSnapshot(ctx context.Context, t time.Time, filter *model.SnapshotFilter) (*model.TimedNodeSnapshots, error) {
+	var resp struct {
+		NodeStatesAtTimestamp *model.TimedNodeSnapshots `json:"nodeStatesAtTimestamp"`
+	}
+	err := s.query(ctx, nodeStatesAtTimestampQuery, map[string]interface{}{"timestamp": t, "filter": filter}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.NodeStatesAtTimestamp, nil
+}
+
+func (s *graphQLSource) PodHistory(ctx context.Context, namespace, name string, beginAt, endAt time.Time) (*model.PodHistory, error) {
+	var resp struct {
+		PodHistory *model.PodHistory `json:"podHistory"`
+	}
+	variables := map[string]interface{}{"namespace": namespace, "name": name, "start": beginAt, "end": endAt}
+	if err := s.query(ctx, podHistoryQuery, variables, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.PodHistory, nil
+}
+
+func (s *graphQLSource) ClusterEvents(ctx context.Context, beginAt, endAt time.Time, filter *model.SnapshotFilter) ([]*model.ClusterEvent, error) {
+	var resp struct {
+		ClusterEvents []*model.ClusterEvent `json:"clusterEvents"`
+	}
+	variables := map[string]interface{}{"start": beginAt, "end": endAt, "filter": filter}
+	if err := s.query(ctx, clusterEventsQuery, variables, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.ClusterEvents, nil
+}
+
+func (s *graphQLSource) EffectiveAtManifests(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) (*manifests.Manifests, error) {
+	var resp struct {
+		ManifestsAtTimestamp *model.Manifests `json:"manifestsAtTimestamp"`
+	}
+	err := s.query(ctx, manifestsAtTimestampQuery, map[string]interface{}{"timestamp": effectiveAt, "filter": filter}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &manifests.Manifests{Timestamp: resp.ManifestsAtTimestamp.Timestamp}
+	if err := json.Unmarshal([]byte(resp.ManifestsAtTimestamp.NodeList), &m.Nodes); err != nil {
+		return nil, fmt.Errorf("unable to decode nodes: %v", err)
+	}
+	if err := json.Unmarshal([]byte(resp.ManifestsAtTimestamp.PodList), &m.Pods); err != nil {
+		return nil, fmt.Errorf("unable to decode pods: %v", err)
+	}
+
+	return m, nil
+}
+
+func (s *graphQLSource) CostReport(ctx context.Context, beginAt, endAt time.Time) (*model.CostReport, error) {
+	var resp struct {
+		CostReport *model.CostReport `json:"costReport"`
+	}
+	if err := s.query(ctx, costReportQuery, map[string]interface{}{"start": beginAt, "end": endAt}, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.CostReport, nil
+}
+
+func (s *graphQLSource) RestartAnomalies(ctx context.Context, beginAt, endAt time.Time, namespace *string) ([]*model.RestartAnomaly, error) {
+	var resp struct {
+		RestartAnomalies []*model.RestartAnomaly `json:"restartAnomalies"`
+	}
+	variables := map[string]interface{}{"start": beginAt, "end": endAt, "namespace": namespace}
+	if err := s.query(ctx, restartAnomaliesQuery, variables, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.RestartAnomalies, nil
+}
+
+// query posts the query to the server and decodes the data of its response into out
+func (s *graphQLSource) query(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("unable to encode query: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create request: %v", err)
+	}
+	req.Header = s.header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to query %s: %v", s.endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read response: %v", err)
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(b, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unable to query %s: %s: %s", s.endpoint, resp.Status, strings.TrimSpace(string(b)))
+		}
+		return fmt.Errorf("unable to decode response: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		messages := make([]string, 0, len(result.Errors))
+		for _, e := range result.Errors {
+			messages = append(messages, e.Message)
+		}
+		return errors.New(strings.Join(messages, "; "))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to query %s: %s", s.endpoint, resp.Status)
+	}
+
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("unable to decode response: %v", err)
+	}
+
+	return nil
+}
+
+// NewGraphQLSource returns a Source querying the server at endpoint, authenticated by a bearer token or an API key if given
+func NewGraphQLSource(endpoint, token, apiKey string) Source {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	if apiKey != "" {
+		header.Set("X-API-Key", apiKey)
+	}
+
+	return &graphQLSource{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 2 * time.Minute},
+		header:   header,
+	}
+}
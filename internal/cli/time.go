@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the layouts times may be given in, taken as UTC when they have no zone
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseTime parses s as "now", a duration before now such as "90m", or a time in one of timeLayouts
+func parseTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "now" {
+		return now, nil
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unable to parse time %q, expected now, a duration ago such as 1h or a time such as 2025-04-27T00:00Z", s)
+}
+
+// age formats d the way kubectl does, to the two most significant units
+func age(d time.Duration) string {
+	if d < 0 {
+		return "<invalid>"
+	}
+
+	seconds := int(d.Seconds())
+	minutes, hours, days := seconds/60, seconds/3600, seconds/86400
+	switch {
+	case seconds < 120:
+		return fmt.Sprintf("%ds", seconds)
+	case minutes < 10:
+		if s := seconds % 60; s != 0 {
+			return fmt.Sprintf("%dm%ds", minutes, s)
+		}
+		return fmt.Sprintf("%dm", minutes)
+	case hours < 3:
+		return fmt.Sprintf("%dm", minutes)
+	case hours < 8:
+		if m := minutes % 60; m != 0 {
+			return fmt.Sprintf("%dh%dm", hours, m)
+		}
+		return fmt.Sprintf("%dh", hours)
+	case hours < 48:
+		return fmt.Sprintf("%dh", hours)
+	case hours < 24*8:
+		if h := hours % 24; h != 0 {
+			return fmt.Sprintf("%dd%dh", days, h)
+		}
+		return fmt.Sprintf("%dd", days)
+	default:
+		return fmt.Sprintf("%dd", days)
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is how the server is run, read from environment variables
+type Config struct {
+	Port                 string
+	ClusterName          string // DynamoDB table to record to and replay from
+	MaxRangeFrames       int64
+	MaxEventFrames       int64 // recorded events a query over events, such as a cost report, may replay
+	QueryComplexityLimit int
+	CacheSize            int
+	TracesExporter       string
+	EnablePlayground     bool     // serves the playground and allows introspection
+	PriceTableFile       string   // hourly prices of nodes by instance type, for cost reports
+	PodAnnotations       []string // keys of the pod annotations recorded, or key prefixes ending with *
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration // to drain requests in flight on SIGTERM
+	MaxBodyBytes      int64
+
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+
+	AuthAPIKeysFile     string
+	AuthJWKSFile        string
+	AuthJWTIssuer       string
+	AuthJWTAudience     string
+	AuthClientCertsFile string
+	AuthDisabled        bool // lets every request read and record snapshots, when no authenticator is configured
+}
+
+// env reads typed environment variables, keeping the first error
+type env struct {
+	err error
+}
+
+func (e *env) string(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+
+	return fallback
+}
+
+func (e *env) list(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+func (e *env) int(key string, fallback int64) int64 {
+	return parse(e, key, fallback, func(v string) (int64, error) { return strconv.ParseInt(v, 10, 64) })
+}
+
+func (e *env) bool(key string, fallback bool) bool {
+	return parse(e, key, fallback, strconv.ParseBool)
+}
+
+func (e *env) duration(key string, fallback time.Duration) time.Duration {
+	return parse(e, key, fallback, time.ParseDuration)
+}
+
+func parse[T any](e *env, key string, fallback T, parse func(string) (T, error)) T {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	parsed, err := parse(v)
+	if err != nil {
+		if e.err == nil {
+			e.err = fmt.Errorf("invalid %s %q: %v", key, v, err)
+		}
+		return fallback
+	}
+
+	return parsed
+}
+
+// Load reads the config from the environment, defaulting whatever is unset
+func Load() (*Config, error) {
+	e := &env{}
+	cfg := &Config{
+		Port:                 e.string("PORT", "8080"),
+		ClusterName:          e.string("CLUSTER_NAME", "k8s"),
+		MaxRangeFrames:       e.int("MAX_RANGE_FRAMES", 1000),
+		MaxEventFrames:       e.int("MAX_EVENT_FRAMES", 100000),
+		QueryComplexityLimit: int(e.int("QUERY_COMPLEXITY_LIMIT", 100000)),
+		CacheSize:            int(e.int("CACHE_SIZE", 1000)),
+		TracesExporter:       e.string("TRACES_EXPORTER", "none"),
+		EnablePlayground:     e.bool("ENABLE_PLAYGROUND", true),
+		PriceTableFile:       e.string("PRICE_TABLE_FILE", ""),
+		PodAnnotations:       e.list("RECORDED_POD_ANNOTATIONS"),
+
+		ReadHeaderTimeout: e.duration("READ_HEADER_TIMEOUT", 10*time.Second),
+		ReadTimeout:       e.duration("READ_TIMEOUT", 30*time.Second),
+		WriteTimeout:      e.duration("WRITE_TIMEOUT", 60*time.Second),
+		IdleTimeout:       e.duration("IDLE_TIMEOUT", 120*time.Second),
+		ShutdownTimeout:   e.duration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		MaxBodyBytes:      e.int("MAX_BODY_BYTES", 10<<20),
+
+		TLSCertFile:     e.string("TLS_CERT_FILE", ""),
+		TLSKeyFile:      e.string("TLS_KEY_FILE", ""),
+		TLSClientCAFile: e.string("TLS_CLIENT_CA_FILE", ""),
+
+		AuthAPIKeysFile:     e.string("AUTH_API_KEYS_FILE", ""),
+		AuthJWKSFile:        e.string("AUTH_JWKS_FILE", ""),
+		AuthJWTIssuer:       e.string("AUTH_JWT_ISSUER", ""),
+		AuthJWTAudience:     e.string("AUTH_JWT_AUDIENCE", ""),
+		AuthClientCertsFile: e.string("AUTH_CLIENT_CERTS_FILE", ""),
+		AuthDisabled:        e.bool("AUTH_DISABLED", false),
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if cfg.TLSClientCAFile != "" && cfg.TLSCertFile == "" {
+		return nil, errors.New("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE")
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		return nil, errors.New("MAX_BODY_BYTES must be positive")
+	}
+
+	authenticated := cfg.AuthAPIKeysFile != "" || cfg.AuthJWKSFile != "" || cfg.AuthClientCertsFile != ""
+	if !authenticated && !cfg.AuthDisabled {
+		return nil, errors.New("no authenticator configured, set AUTH_API_KEYS_FILE, AUTH_JWKS_FILE or AUTH_CLIENT_CERTS_FILE, or AUTH_DISABLED=true to run without authentication")
+	}
+	if authenticated && cfg.AuthDisabled {
+		return nil, errors.New("AUTH_DISABLED can't be set with an authenticator configured")
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,53 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/config"
+)
+
+func TestLoad(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// authentication has to be configured or explicitly disabled
+	_, err := config.Load()
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("no authenticator configured")))
+
+	t.Setenv("AUTH_DISABLED", "true")
+	cfg, err := config.Load()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(cfg.AuthDisabled).To(gomega.BeTrue())
+	g.Expect(cfg.Port).To(gomega.Equal("8080"))
+	g.Expect(cfg.EnablePlayground).To(gomega.BeTrue())
+	g.Expect(cfg.WriteTimeout).To(gomega.Equal(time.Minute))
+
+	t.Setenv("CLUSTER_NAME", "prod")
+	t.Setenv("ENABLE_PLAYGROUND", "false")
+	t.Setenv("WRITE_TIMEOUT", "2m30s")
+	t.Setenv("MAX_BODY_BYTES", "1048576")
+	t.Setenv("RECORDED_POD_ANNOTATIONS", "kubectl.kubernetes.io/restartedAt, example.com/*")
+	cfg, err = config.Load()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(cfg.ClusterName).To(gomega.Equal("prod"))
+	g.Expect(cfg.EnablePlayground).To(gomega.BeFalse())
+	g.Expect(cfg.WriteTimeout).To(gomega.Equal(150 * time.Second))
+	g.Expect(cfg.MaxBodyBytes).To(gomega.BeEquivalentTo(1 << 20))
+	g.Expect(cfg.PodAnnotations).To(gomega.Equal([]string{"kubectl.kubernetes.io/restartedAt", "example.com/*"}))
+
+	t.Setenv("READ_TIMEOUT", "30")
+	_, err = config.Load()
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(`invalid READ_TIMEOUT "30"`)))
+
+	t.Setenv("READ_TIMEOUT", "")
+	t.Setenv("AUTH_API_KEYS_FILE", "keys.json")
+	_, err = config.Load()
+	g.Expect(err).To(gomega.MatchError("AUTH_DISABLED can't be set with an authenticator configured"))
+
+	t.Setenv("AUTH_DISABLED", "")
+	t.Setenv("TLS_CERT_FILE", "server.crt")
+	_, err = config.Load()
+	g.Expect(err).To(gomega.MatchError("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+}
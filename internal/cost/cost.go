@@ -0,0 +1,168 @@
+package cost
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"os"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/ccpeng/kube-replay/internal/capacity"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/simulator"
+)
+
+// Prices are the hourly prices of nodes by instance type
+type Prices map[string]float64
+
+// UnknownInstanceType is what nodes without an instance type label are listed as unpriced under
+const UnknownInstanceType = "unknown"
+
+// LoadPrices reads prices from a JSON object of hourly prices keyed by instance type, e.g. {"m5.large": 0.096}
+func LoadPrices(file string) (Prices, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read price table: %v", err)
+	}
+
+	var prices Prices
+	if err := json.Unmarshal(b, &prices); err != nil {
+		return nil, fmt.Errorf("unable to parse price table: %v", err)
+	}
+
+	return prices, nil
+}
+
+// PodCost is the cost attributed to the pods named namespace/name, across the UIDs they were recreated with
+type PodCost struct {
+	Namespace string
+	Name      string
+	Cost      float64
+}
+
+// Report is the cost of the nodes of the cluster over a window, attributed to the pods requesting them
+type Report struct {
+	Total    float64
+	Idle     float64    // cost of nodes while nothing was requested of them
+	Unpriced []string   // instance types of nodes missing from the prices, whose cost isn't counted
+	Pods     []*PodCost // ordered by namespace and name
+}
+
+// Attribute returns the cost of the nodes of the cluster states in ascending order, each holding until the next or
+// until endAt for the last. The cost of a node is split between the pods bound to it by their share of what's
+// requested of it, CPU and memory weighing the same.
+func Attribute(frames iter.Seq[*data.ClusterState], endAt time.Time, prices Prices) *Report {
+	report := &Report{}
+	pods := map[string]*PodCost{}
+	unpriced := map[string]bool{}
+
+	for frame, until := range data.Spans(frames, endAt) {
+		hours := until.Sub(frame.Timestamp).Hours()
+		if hours <= 0 {
+			continue
+		}
+
+		for _, node := range frame.Nodes {
+			instanceType := capacity.GroupOf(node.Meta).InstanceType
+			if instanceType == "" {
+				instanceType = UnknownInstanceType
+			}
+			price, ok := prices[instanceType]
+			if !ok || instanceType == UnknownInstanceType {
+				unpriced[instanceType] = true
+				continue
+			}
+
+			cost := price * hours
+			report.Total += cost
+
+			shares := shares(node)
+			if len(shares) == 0 {
+				report.Idle += cost
+				continue
+			}
+			for _, share := range shares {
+				key := data.PodKey(share.pod.Meta.Namespace, share.pod.Meta.Name)
+				podCost, ok := pods[key]
+				if !ok {
+					podCost = &PodCost{Namespace: share.pod.Meta.Namespace, Name: share.pod.Meta.Name}
+					pods[key] = podCost
+				}
+				podCost.Cost += cost * share.share
+			}
+		}
+	}
+
+	for instanceType := range unpriced {
+		report.Unpriced = append(report.Unpriced, instanceType)
+	}
+	sort.Strings(report.Unpriced)
+
+	for _, podCost := range pods {
+		report.Pods = append(report.Pods, podCost)
+	}
+	sort.Slice(report.Pods, func(i, j int) bool {
+		if report.Pods[i].Namespace != report.Pods[j].Namespace {
+			return report.Pods[i].Namespace < report.Pods[j].Namespace
+		}
+		return report.Pods[i].Name < report.Pods[j].Name
+	})
+
+	return report
+}
+
+// podShare is the share of a node's cost attributed to a pod bound to it
+type podShare struct {
+	pod   *data.PodAt
+	share float64
+}
+
+// shares returns the share of each pod of the node in what's requested of it, or none if nothing is
+func shares(node *data.NodeAt) []podShare {
+	resources := []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+	totals := map[corev1.ResourceName]int64{}
+	requests := map[*data.PodAt]corev1.ResourceList{}
+	for _, podAt := range node.Pods {
+		if simulator.Terminated(podAt) {
+			continue
+		}
+
+		requests[podAt] = simulator.NewPod(podAt).Requests
+		for _, name := range resources {
+			quantity := requests[podAt][name]
+			totals[name] += quantity.MilliValue()
+		}
+	}
+
+	var requested []corev1.ResourceName
+	for _, name := range resources {
+		if totals[name] > 0 {
+			requested = append(requested, name)
+		}
+	}
+	if len(requested) == 0 {
+		return nil
+	}
+
+	var shares []podShare
+	for _, podAt := range node.Pods {
+		podRequests, ok := requests[podAt]
+		if !ok {
+			continue
+		}
+
+		share := 0.0
+		for _, name := range requested {
+			quantity := podRequests[name]
+			share += float64(quantity.MilliValue()) / float64(totals[name]) / float64(len(requested))
+		}
+		if share > 0 {
+			shares = append(shares, podShare{pod: podAt, share: share})
+		}
+	}
+
+	return shares
+}
@@ -0,0 +1,87 @@
+package cost_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/cost"
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+func TestAttribute(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	requesting := func(cpu, memory string) []*data.ContainerSnapshot {
+		return []*data.ContainerSnapshot{{Resources: data.ContainerResources{
+			Requests: data.ContainerResource{Cpu: cpu, Memory: memory},
+		}}}
+	}
+	node := func(id, instanceType string, pods ...*data.PodMeta) *data.NodeMeta {
+		return &data.NodeMeta{
+			ID: id, Name: id, ProviderID: "aws:///us-west-2a/i-" + id,
+			Labels:    map[string]string{"node.kubernetes.io/instance-type": instanceType},
+			Snapshots: data.NodeSnapshots{{Timestamp: t0, State: data.NodeState{Allocatable: data.NodeCapacity{Cpu: "2", Memory: "8Gi"}}}},
+			Pods:      pods,
+		}
+	}
+	// d and e have no instance type label, so they can't be priced, even by the provider ID of d
+	unlabeled := func(id, providerID string) *data.NodeMeta {
+		return &data.NodeMeta{ID: id, Name: id, ProviderID: providerID, Snapshots: data.NodeSnapshots{{Timestamp: t0}}}
+	}
+
+	// web requests three times the CPU and memory of api for the first hour, then api has the node to itself
+	nodes := []*data.NodeMeta{
+		node("a", "m5.large",
+			&data.PodMeta{ID: "web", Namespace: "shop", Name: "web-0", Snapshots: data.PodSnapshots{
+				{Timestamp: t0, Status: data.PodPhaseRunning, Containers: requesting("750m", "3Gi")},
+				{Timestamp: t0.Add(time.Hour), Status: data.PodPhaseSucceeded, Containers: requesting("750m", "3Gi")},
+			}},
+			&data.PodMeta{ID: "api", Namespace: "shop", Name: "api-0", Snapshots: data.PodSnapshots{
+				{Timestamp: t0, Status: data.PodPhaseRunning, Containers: requesting("250m", "1Gi")},
+			}},
+		),
+		node("b", "m5.large"),
+		node("c", "x1.metal", &data.PodMeta{ID: "db", Namespace: "data", Name: "db-0", Snapshots: data.PodSnapshots{
+			{Timestamp: t0, Status: data.PodPhaseRunning, Containers: requesting("1", "1Gi")},
+		}}),
+		unlabeled("d", "metal://rack-1/d"),
+		unlabeled("e", ""),
+	}
+	for _, n := range nodes {
+		for _, pod := range n.Pods {
+			pod.SetDynamoAttributes(n.ID)
+		}
+	}
+
+	times := []time.Time{t0, t0.Add(time.Hour)}
+	report := cost.Attribute(data.SweepSeq(nodes, times), t0.Add(2*time.Hour), cost.Prices{"m5.large": 0.1, "metal://rack-1/d": 0.05})
+
+	g.Expect(report.Total).To(gomega.BeNumerically("~", 0.4))
+	// b never had anything requested of it
+	g.Expect(report.Idle).To(gomega.BeNumerically("~", 0.2))
+	g.Expect(report.Unpriced).To(gomega.Equal([]string{cost.UnknownInstanceType, "x1.metal"}))
+	g.Expect(report.Pods).To(gomega.HaveLen(2))
+	g.Expect(report.Pods[0].Name).To(gomega.Equal("api-0"))
+	g.Expect(report.Pods[0].Cost).To(gomega.BeNumerically("~", 0.125))
+	g.Expect(report.Pods[1].Name).To(gomega.Equal("web-0"))
+	g.Expect(report.Pods[1].Cost).To(gomega.BeNumerically("~", 0.075))
+}
+
+func TestLoadPrices(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	file := filepath.Join(t.TempDir(), "prices.json")
+	g.Expect(os.WriteFile(file, []byte(`{"m5.large": 0.096}`), 0o600)).To(gomega.Succeed())
+	prices, err := cost.LoadPrices(file)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(prices).To(gomega.HaveKeyWithValue("m5.large", 0.096))
+
+	g.Expect(os.WriteFile(file, []byte(`["m5.large"]`), 0o600)).To(gomega.Succeed())
+	_, err = cost.LoadPrices(file)
+	g.Expect(err).NotTo(gomega.BeNil())
+}
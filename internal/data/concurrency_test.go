@@ -0,0 +1,84 @@
+package data_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+// sharedTrees returns node trees with histories of a snapshot every minute for an hour
+func sharedTrees(t0 time.Time, nodeCount, podCount int) []*data.NodeMeta {
+	var nodes []*data.NodeMeta
+	for n := 0; n < nodeCount; n++ {
+		node := &data.NodeMeta{ID: fmt.Sprintf("node-%d", n)}
+		for m := 0; m < 60; m++ {
+			node.Snapshots = append(node.Snapshots, &data.NodeSnapshot{Timestamp: t0.Add(time.Duration(m) * time.Minute)})
+		}
+
+		for p := 0; p < podCount; p++ {
+			pod := &data.PodMeta{ID: fmt.Sprintf("pod-%d-%d", n, p), TreeID: node.ID}
+			for m := p; m < 60; m += 5 {
+				pod.Snapshots = append(pod.Snapshots, &data.PodSnapshot{Timestamp: t0.Add(time.Duration(m) * time.Minute)})
+			}
+
+			node.Pods = append(node.Pods, pod)
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+func TestConcurrentReplaysOfSharedTrees(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	nodes := sharedTrees(t0, 5, 10)
+
+	var timestamps []time.Time
+	for s := 0; s < 3600; s += 30 {
+		timestamps = append(timestamps, t0.Add(time.Duration(s)*time.Second))
+	}
+	expected := data.Sweep(nodes, timestamps)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			if i%2 == 0 {
+				for j, state := range data.Sweep(nodes, timestamps) {
+					if len(state.Nodes) != len(expected[j].Nodes) {
+						errs <- fmt.Errorf("sweep at %s has %d nodes, expected %d", timestamps[j], len(state.Nodes), len(expected[j].Nodes))
+						return
+					}
+				}
+				return
+			}
+
+			for j := len(timestamps) - 1; j >= 0; j-- {
+				state := data.StateAt(nodes, timestamps[j])
+				for k, node := range state.Nodes {
+					if node.Snapshot != expected[j].Nodes[k].Snapshot || len(node.Pods) != len(expected[j].Nodes[k].Pods) {
+						errs <- fmt.Errorf("state at %s differs from the sweep for %s", timestamps[j], node.Meta.ID)
+						return
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		g.Expect(err).To(gomega.BeNil())
+	}
+}
@@ -2,10 +2,15 @@ package data
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"time"
 )
 
+// UnscheduledID is the ID of the tree the pods not bound to a node yet are recorded under. It has no snapshots of its
+// own, so it's never replayed as a node.
+const UnscheduledID = "unscheduled"
+
 type NodeMeta struct {
 	ID       string    `dynamo:",hash"`                          // metadata.uuid
 	TreeID   string    `index:"TreeIndex,hash"`                  // same as ID
@@ -26,40 +31,53 @@ type NodeMeta struct {
 	SystemUUID              string
 	BootID                  string
 	Roles                   []string
+	Labels                  map[string]string
+	DeletedAt               time.Time     `dynamo:",omitempty"`
 	Snapshots               NodeSnapshots `dynamo:"-"`
 	Pods                    []*PodMeta    `dynamo:"-"`
 }
 
+// DeletedAsOf reports whether the node had been removed from the cluster at or before the timestamp
+func (n *NodeMeta) DeletedAsOf(timestamp time.Time) bool {
+	return !n.DeletedAt.IsZero() && !n.DeletedAt.After(timestamp)
+}
+
+// Unscheduled reports whether the tree holds the pods not bound to a node yet rather than a node
+func (n *NodeMeta) Unscheduled() bool {
+	return n.ID == UnscheduledID
+}
+
 func (n *NodeMeta) SetDynamoAttributes() {
 	n.TreeID = n.ID
 	n.TreePath = "root"
 	n.Type = "node_meta"
 }
 
+// NodeSnapshots is the history of a node, ordered by timestamp. It's never reordered in place, so a history can be
+// shared by concurrent replays.
 type NodeSnapshots []*NodeSnapshot
 
-func (n *NodeSnapshots) Len() int {
-	return len(*n)
-}
-
-func (n *NodeSnapshots) Less(i, j int) bool {
-	return (*n)[i].Timestamp.Before((*n)[j].Timestamp)
-}
+// Sorted returns a copy of the snapshots ordered by timestamp
+func (n NodeSnapshots) Sorted() NodeSnapshots {
+	sorted := slices.Clone(n)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
 
-func (n *NodeSnapshots) Swap(i, j int) {
-	(*n)[i], (*n)[j] = (*n)[j], (*n)[i]
+	return sorted
 }
 
-// EffectiveAt sorts all NodeSnapshots in reverse and returns the first occurring snapshot that's <= the timestamp
-func (n *NodeSnapshots) EffectiveAt(timestamp time.Time) *NodeSnapshot {
-	sort.Sort(sort.Reverse(n))
-	for _, snapshot := range *n {
-		if snapshot.Timestamp.Before(timestamp) || snapshot.Timestamp.Equal(timestamp) {
-			return snapshot
-		}
+// EffectiveAt binary searches the snapshots, which must be ordered by timestamp, for the last one that's <= the
+// timestamp
+func (n NodeSnapshots) EffectiveAt(timestamp time.Time) *NodeSnapshot {
+	i := sort.Search(len(n), func(i int) bool {
+		return n[i].Timestamp.After(timestamp)
+	})
+	if i == 0 {
+		return nil
 	}
 
-	return nil
+	return n[i-1]
 }
 
 type NodeSnapshot struct {
@@ -86,6 +104,17 @@ type NodeState struct {
 	Allocatable   NodeCapacity
 	Taints        []*Taint
 	Unschedulable bool
+	Versions      NodeVersions
+}
+
+// NodeVersions are the versions of what runs on a node, recorded with each snapshot since upgrades change them in
+// place while the node's metadata only keeps the latest
+type NodeVersions struct {
+	KubeletVersion          string
+	KubeProxyVersion        string
+	KernelVersion           string
+	ContainerRuntimeVersion string
+	OsImage                 string
 }
 
 type NodeCapacity struct {
@@ -2,7 +2,10 @@ package data
 
 import (
 	"fmt"
+	"regexp"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -11,48 +14,178 @@ type PodMeta struct {
 	TreeID   string    `index:"TreeIndex,hash"`                  // rootID
 	TreePath string    `dynamo:",range" index:"TreeIndex,range"` // nodeMeta's ID
 	ExpireAt time.Time `json:"-" dynamo:",unixtime"`
+	PodKey   string    `index:"PodIndex,hash"` // namespace/name, stable across pod recreations
+
+	Type        string //pod_meta
+	Name        string
+	Namespace   string
+	StartedAt   time.Time `dynamo:",omitempty"`
+	DeletedAt   time.Time `dynamo:",omitempty"`
+	FinishedAt  time.Time `dynamo:",omitempty"`
+	DeletedBy   string
+	QOSClass    PodQOSClass
+	Labels      map[string]string
+	Annotations map[string]string // those selected to be recorded
+	Owners      []*OwnerReference
+
+	Tolerations       []*Toleration
+	NodeSelector      map[string]string
+	Affinity          *Affinity `dynamo:",omitempty"`
+	Priority          *int32    `dynamo:",omitempty"`
+	PriorityClassName string
+	PreemptionPolicy  string
+
+	Snapshots PodSnapshots `dynamo:"-"`
+}
+
+// Toleration is a taint a pod tolerates
+type Toleration struct {
+	Key               string
+	Operator          string
+	Value             string
+	Effect            string
+	TolerationSeconds *int64 `dynamo:",omitempty"`
+}
+
+// Affinity summarizes the affinity of a pod, with terms written as label selectors
+type Affinity struct {
+	RequiredNodeTerms  []string // any of which a node must match
+	PreferredNodeTerms []string
+	PodAffinity        []*AffinityTerm
+	PodAntiAffinity    []*AffinityTerm
+}
 
-	Type       string //pod_meta
+// AffinityTerm is the pods a pod is (anti-)affine to, within the same value of a node label
+type AffinityTerm struct {
+	LabelSelector string
+	TopologyKey   string
+	Required      bool
+}
+
+// OwnerReference is an object owning a pod, e.g. the ReplicaSet or Job that created it
+type OwnerReference struct {
+	Kind       string
 	Name       string
-	Namespace  string
-	StartedAt  time.Time `dynamo:",omitempty"`
-	DeletedAt  time.Time `dynamo:",omitempty"`
-	FinishedAt time.Time `dynamo:",omitempty"`
-	DeletedBy  string
-	QOSClass   PodQOSClass
-	Snapshots  PodSnapshots `dynamo:"-"`
+	UID        string
+	Controller bool
+}
+
+// Workload is the object managing a pod, e.g. a Deployment. Kind is empty when it's only known by the pod name.
+type Workload struct {
+	Kind string
+	Name string
 }
 
 func (p *PodMeta) SetDynamoAttributes(nodeID string) {
 	p.TreeID = nodeID
 	p.TreePath = nodeID
+	p.PodKey = PodKey(p.Namespace, p.Name)
 	p.Type = "pod_meta"
 }
 
-type PodSnapshots []*PodSnapshot
+// DeletedAsOf reports whether the pod had been deleted at or before the timestamp
+func (p *PodMeta) DeletedAsOf(timestamp time.Time) bool {
+	return !p.DeletedAt.IsZero() && !p.DeletedAt.After(timestamp)
+}
 
-func (p *PodSnapshots) Len() int {
-	return len(*p)
+// NodeID returns the ID of the node the pod meta is stored under
+func (p *PodMeta) NodeID() string {
+	return p.TreeID
 }
 
-func (p *PodSnapshots) Less(i, j int) bool {
-	return (*p)[i].Timestamp.Before((*p)[j].Timestamp)
+// PodKey returns the key identifying a pod by namespace and name, regardless of its UID
+func PodKey(namespace, name string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
+
+// Suffixes Kubernetes generates for the pods of workloads, from the alphabet it generates names with
+var (
+	replicaSetSuffix  = regexp.MustCompile(`^(.+)-[bcdfghjklmnpqrstvwxz2456789]{1,10}-[bcdfghjklmnpqrstvwxz2456789]{5}$`)
+	statefulSetSuffix = regexp.MustCompile(`^(.+)-[0-9]+$`)
+	generatedSuffix   = regexp.MustCompile(`^(.+)-[bcdfghjklmnpqrstvwxz2456789]{5}$`)
+)
+
+// WorkloadOf returns the name of the workload that created the pod named podName, by stripping the suffix a
+// Deployment, StatefulSet, DaemonSet or Job generates, or podName itself if it has none. Names can be mistaken for
+// generated ones, so it's only the fallback of Workload for pods recorded without owners.
+func WorkloadOf(podName string) string {
+	for _, suffix := range []*regexp.Regexp{replicaSetSuffix, statefulSetSuffix, generatedSuffix} {
+		if match := suffix.FindStringSubmatch(podName); match != nil {
+			return match[1]
+		}
+	}
+
+	return podName
 }
 
-func (p *PodSnapshots) Swap(i, j int) {
-	(*p)[i], (*p)[j] = (*p)[j], (*p)[i]
+// Workload returns the workload managing the pod, following its controller from a ReplicaSet to the Deployment that
+// created it by its pod-template-hash label, or by the name of the pod if it has no owners recorded
+func (p *PodMeta) Workload() Workload {
+	var owner *OwnerReference
+	for _, o := range p.Owners {
+		if o.Controller {
+			owner = o
+			break
+		}
+	}
+	if owner == nil && len(p.Owners) > 0 {
+		owner = p.Owners[0]
+	}
+	if owner == nil {
+		return Workload{Name: WorkloadOf(p.Name)}
+	}
+
+	if hash := p.Labels["pod-template-hash"]; owner.Kind == "ReplicaSet" && hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+		return Workload{Kind: "Deployment", Name: strings.TrimSuffix(owner.Name, "-"+hash)}
+	}
+
+	return Workload{Kind: owner.Kind, Name: owner.Name}
 }
 
-// EffectiveAt sorts all PodSnapshots in reverse and returns the first occurring snapshot that's <= the timestamp
-func (p *PodSnapshots) EffectiveAt(timestamp time.Time) *PodSnapshot {
-	sort.Sort(sort.Reverse(p))
-	for _, snapshot := range *p {
-		if snapshot.Timestamp.Before(timestamp) || snapshot.Timestamp.Equal(timestamp) {
-			return snapshot
+// mirrorAnnotation is set by the kubelet on the mirror pods of its static pods
+const mirrorAnnotation = "kubernetes.io/config.mirror"
+
+// NodeBound reports whether the pod belongs to its node, being managed by a DaemonSet or the mirror pod of a static
+// pod, so draining the node leaves it behind rather than evicting it. Mirror pods are told by their owner, the node,
+// since their annotation is only recorded if selected.
+func (p *PodMeta) NodeBound() bool {
+	if _, ok := p.Annotations[mirrorAnnotation]; ok {
+		return true
+	}
+	for _, owner := range p.Owners {
+		if owner.Kind == "Node" || owner.Controller && owner.Kind == "DaemonSet" {
+			return true
 		}
 	}
 
-	return nil
+	return false
+}
+
+// PodSnapshots is the history of a pod on a node, ordered by timestamp. It's never reordered in place, so a history
+// can be shared by concurrent replays.
+type PodSnapshots []*PodSnapshot
+
+// Sorted returns a copy of the snapshots ordered by timestamp
+func (p PodSnapshots) Sorted() PodSnapshots {
+	sorted := slices.Clone(p)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	return sorted
+}
+
+// EffectiveAt binary searches the snapshots, which must be ordered by timestamp, for the last one that's <= the
+// timestamp
+func (p PodSnapshots) EffectiveAt(timestamp time.Time) *PodSnapshot {
+	i := sort.Search(len(p), func(i int) bool {
+		return p[i].Timestamp.After(timestamp)
+	})
+	if i == 0 {
+		return nil
+	}
+
+	return p[i-1]
 }
 
 type PodSnapshot struct {
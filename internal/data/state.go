@@ -0,0 +1,174 @@
+package data
+
+import (
+	"sort"
+	"time"
+)
+
+// PodBinding is the span of time a pod was observed on a node
+type PodBinding struct {
+	PodID  string
+	NodeID string
+	From   time.Time
+	To     time.Time
+}
+
+// Binding returns the span between the first and last snapshot of the pod meta on its node, or nil if there are none
+func (p *PodMeta) Binding() *PodBinding {
+	if len(p.Snapshots) == 0 {
+		return nil
+	}
+
+	binding := &PodBinding{
+		PodID:  p.ID,
+		NodeID: p.NodeID(),
+		From:   p.Snapshots[0].Timestamp,
+		To:     p.Snapshots[0].Timestamp,
+	}
+	for _, snapshot := range p.Snapshots {
+		if snapshot.Timestamp.Before(binding.From) {
+			binding.From = snapshot.Timestamp
+		}
+		if snapshot.Timestamp.After(binding.To) {
+			binding.To = snapshot.Timestamp
+		}
+	}
+
+	return binding
+}
+
+// ClusterState is the reconstructed view of every node and pod at a single instant
+type ClusterState struct {
+	Timestamp time.Time
+	Nodes     []*NodeAt
+}
+
+// NodeAt is a node with the snapshot and pods effective at an instant
+type NodeAt struct {
+	Meta     *NodeMeta
+	Snapshot *NodeSnapshot
+	Pods     []*PodAt
+}
+
+// Versions returns the versions of the node as of its snapshot, or the latest of its metadata for snapshots recorded
+// before versions were
+func (n *NodeAt) Versions() NodeVersions {
+	if n.Snapshot.State.Versions != (NodeVersions{}) {
+		return n.Snapshot.State.Versions
+	}
+
+	return NodeVersions{
+		KubeletVersion:          n.Meta.KubeletVersion,
+		KubeProxyVersion:        n.Meta.KubeProxyVersion,
+		KernelVersion:           n.Meta.KernelVersion,
+		ContainerRuntimeVersion: n.Meta.ContainerRuntimeVersion,
+		OsImage:                 n.Meta.OsImage,
+	}
+}
+
+// PodAt is a pod with the snapshot effective at an instant
+type PodAt struct {
+	Meta     *PodMeta
+	Snapshot *PodSnapshot
+}
+
+// StateAt reconstructs the cluster at the timestamp, with nodes ordered by name and pods by namespace and name. Nodes
+// and pods without a snapshot at or before the timestamp, or deleted at or before it, are left out. A pod stored under
+// several nodes (i.e. it migrated) is bound to the node holding its most recent snapshot at or before the timestamp.
+func StateAt(nodes []*NodeMeta, timestamp time.Time) *ClusterState {
+	return stateAt(nodes, timestamp, func(node *NodeMeta) *NodeSnapshot {
+		return node.Snapshots.EffectiveAt(timestamp)
+	}, func(pod *PodMeta) *PodSnapshot {
+		return pod.Snapshots.EffectiveAt(timestamp)
+	})
+}
+
+// stateAt reconstructs the cluster at the timestamp from the node and pod snapshots effective at it
+func stateAt(nodes []*NodeMeta, timestamp time.Time, nodeInTime func(*NodeMeta) *NodeSnapshot, podInTime func(*PodMeta) *PodSnapshot) *ClusterState {
+	state := &ClusterState{
+		Timestamp: timestamp,
+	}
+
+	bindings := map[string]*PodAt{}
+	for _, node := range nodes {
+		for _, pod := range node.Pods {
+			if pod.DeletedAsOf(timestamp) {
+				continue
+			}
+
+			podSnapshot := podInTime(pod)
+			if podSnapshot == nil {
+				continue
+			}
+
+			bound, ok := bindings[pod.ID]
+			if !ok || bound.Snapshot.Timestamp.Before(podSnapshot.Timestamp) {
+				bindings[pod.ID] = &PodAt{Meta: pod, Snapshot: podSnapshot}
+			}
+		}
+	}
+
+	for _, node := range nodes {
+		if node.DeletedAsOf(timestamp) {
+			continue
+		}
+
+		nodeSnapshot := nodeInTime(node)
+		if nodeSnapshot == nil {
+			continue
+		}
+
+		nodeAt := &NodeAt{
+			Meta:     node,
+			Snapshot: nodeSnapshot,
+			Pods:     []*PodAt{},
+		}
+		for _, pod := range node.Pods {
+			if bound, ok := bindings[pod.ID]; ok && bound.Meta == pod {
+				nodeAt.Pods = append(nodeAt.Pods, bound)
+			}
+		}
+
+		sort.SliceStable(nodeAt.Pods, func(i, j int) bool {
+			a, b := nodeAt.Pods[i].Meta, nodeAt.Pods[j].Meta
+			if a.Namespace != b.Namespace {
+				return a.Namespace < b.Namespace
+			}
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			return a.ID < b.ID
+		})
+
+		state.Nodes = append(state.Nodes, nodeAt)
+	}
+
+	sort.SliceStable(state.Nodes, func(i, j int) bool {
+		a, b := state.Nodes[i].Meta, state.Nodes[j].Meta
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+
+	return state
+}
+
+// PodBindings returns the bindings of all the pod metas recorded on a node, ordered by when they began
+func PodBindings(podMetas []*PodMeta) []*PodBinding {
+	bindings := []*PodBinding{}
+	for _, podMeta := range podMetas {
+		if podMeta.NodeID() == UnscheduledID {
+			continue
+		}
+		if binding := podMeta.Binding(); binding != nil {
+			bindings = append(bindings, binding)
+		}
+	}
+
+	sort.SliceStable(bindings, func(i, j int) bool {
+		return bindings[i].From.Before(bindings[j].From)
+	})
+
+	return bindings
+}
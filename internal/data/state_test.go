@@ -0,0 +1,134 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+func TestStateAt_MigratedPod(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	t1, t2 := t0.Add(time.Minute), t0.Add(2*time.Minute)
+
+	podOnA := &data.PodMeta{
+		ID: "pod-1", TreeID: "node-a", Name: "web-0", Namespace: "shop",
+		Snapshots: data.PodSnapshots{{Timestamp: t0, Status: data.PodPhaseRunning}},
+	}
+	podOnB := &data.PodMeta{
+		ID: "pod-1", TreeID: "node-b", Name: "web-0", Namespace: "shop",
+		Snapshots: data.PodSnapshots{{Timestamp: t2, Status: data.PodPhaseRunning}},
+	}
+	nodes := []*data.NodeMeta{
+		{ID: "node-a", Snapshots: data.NodeSnapshots{{Timestamp: t0}}, Pods: []*data.PodMeta{podOnA}},
+		{ID: "node-b", Snapshots: data.NodeSnapshots{{Timestamp: t0}}, Pods: []*data.PodMeta{podOnB}},
+	}
+
+	state := data.StateAt(nodes, t1)
+	g.Expect(state.Nodes).To(gomega.HaveLen(2))
+	g.Expect(state.Nodes[0].Pods).To(gomega.HaveLen(1))
+	g.Expect(state.Nodes[0].Pods[0].Meta).To(gomega.BeIdenticalTo(podOnA))
+	g.Expect(state.Nodes[1].Pods).To(gomega.BeEmpty())
+
+	state = data.StateAt(nodes, t2)
+	g.Expect(state.Nodes[0].Pods).To(gomega.BeEmpty())
+	g.Expect(state.Nodes[1].Pods).To(gomega.HaveLen(1))
+	g.Expect(state.Nodes[1].Pods[0].Meta).To(gomega.BeIdenticalTo(podOnB))
+}
+
+func TestStateAt_BeforeFirstSnapshot(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+
+	nodes := []*data.NodeMeta{
+		{
+			ID:        "node-a",
+			Snapshots: data.NodeSnapshots{{Timestamp: t0}},
+			Pods: []*data.PodMeta{
+				{ID: "pod-1", TreeID: "node-a", Snapshots: data.PodSnapshots{{Timestamp: t0.Add(time.Minute)}}},
+			},
+		},
+	}
+
+	g.Expect(data.StateAt(nodes, t0.Add(-time.Second)).Nodes).To(gomega.BeEmpty())
+	g.Expect(data.StateAt(nodes, t0).Nodes[0].Pods).To(gomega.BeEmpty())
+}
+
+func TestPodBindings(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+
+	bindings := data.PodBindings([]*data.PodMeta{
+		{ID: "pod-2", TreeID: "node-b", Snapshots: data.PodSnapshots{{Timestamp: t0.Add(3 * time.Minute)}}},
+		{ID: "pod-1", TreeID: "node-a", Snapshots: data.PodSnapshots{{Timestamp: t0.Add(time.Minute)}, {Timestamp: t0}}},
+		{ID: "pod-3", TreeID: "node-c"},
+	})
+
+	g.Expect(bindings).To(gomega.HaveLen(2))
+	g.Expect(*bindings[0]).To(gomega.Equal(data.PodBinding{PodID: "pod-1", NodeID: "node-a", From: t0, To: t0.Add(time.Minute)}))
+	g.Expect(bindings[1].NodeID).To(gomega.Equal("node-b"))
+}
+
+func TestStateAt_Deletions(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	t1, t2 := t0.Add(time.Minute), t0.Add(2*time.Minute)
+
+	nodes := []*data.NodeMeta{
+		{
+			ID:        "node-a",
+			Snapshots: data.NodeSnapshots{{Timestamp: t0}},
+			Pods: []*data.PodMeta{
+				{ID: "pod-1", TreeID: "node-a", DeletedAt: t1, Snapshots: data.PodSnapshots{{Timestamp: t0}}},
+				{ID: "pod-2", TreeID: "node-a", Snapshots: data.PodSnapshots{{Timestamp: t0}}},
+			},
+		},
+		{ID: "node-b", DeletedAt: t2, Snapshots: data.NodeSnapshots{{Timestamp: t0}}},
+	}
+
+	state := data.StateAt(nodes, t0)
+	g.Expect(state.Nodes).To(gomega.HaveLen(2))
+	g.Expect(state.Nodes[0].Pods).To(gomega.HaveLen(2))
+
+	state = data.StateAt(nodes, t1)
+	g.Expect(state.Nodes).To(gomega.HaveLen(2))
+	g.Expect(state.Nodes[0].Pods).To(gomega.HaveLen(1))
+	g.Expect(state.Nodes[0].Pods[0].Meta.ID).To(gomega.Equal("pod-2"))
+
+	state = data.StateAt(nodes, t2)
+	g.Expect(state.Nodes).To(gomega.HaveLen(1))
+	g.Expect(state.Nodes[0].Meta.ID).To(gomega.Equal("node-a"))
+}
+
+func TestWorkloadOf(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	g.Expect(data.WorkloadOf("web-7d9f8b6c5d-x2vzq")).To(gomega.Equal("web"))
+	g.Expect(data.WorkloadOf("db-0")).To(gomega.Equal("db"))
+	g.Expect(data.WorkloadOf("fluent-bit-kx7wn")).To(gomega.Equal("fluent-bit"))
+	g.Expect(data.WorkloadOf("standalone")).To(gomega.Equal("standalone"))
+}
+
+func TestPodMeta_Workload(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	deployed := &data.PodMeta{
+		Name:   "web-7d9f8b6c5d-x2vzq",
+		Labels: map[string]string{"app": "web", "pod-template-hash": "7d9f8b6c5d"},
+		Owners: []*data.OwnerReference{{Kind: "ReplicaSet", Name: "web-7d9f8b6c5d", UID: "rs-1", Controller: true}},
+	}
+	g.Expect(deployed.Workload()).To(gomega.Equal(data.Workload{Kind: "Deployment", Name: "web"}))
+
+	job := &data.PodMeta{Name: "backup-28731540-abcde", Owners: []*data.OwnerReference{{Kind: "Job", Name: "backup-28731540"}}}
+	g.Expect(job.Workload()).To(gomega.Equal(data.Workload{Kind: "Job", Name: "backup-28731540"}))
+
+	// recorded before owners were
+	g.Expect((&data.PodMeta{Name: "db-0"}).Workload()).To(gomega.Equal(data.Workload{Name: "db"}))
+}
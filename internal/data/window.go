@@ -0,0 +1,152 @@
+package data
+
+import (
+	"iter"
+	"slices"
+	"sort"
+	"time"
+)
+
+// TrimTo drops the history of the node that isn't needed to replay it between beginAt and endAt: snapshots after
+// endAt, snapshots before beginAt other than the latest one (the state in effect when the window opens), and pods
+// deleted before the window or without snapshots left. It returns false when nothing of the node is left to replay,
+// or for the unscheduled tree, when none of its pods are.
+func (n *NodeMeta) TrimTo(beginAt, endAt time.Time) bool {
+	if n.DeletedAsOf(beginAt) {
+		return false
+	}
+
+	n.Snapshots = trim(n.Snapshots, beginAt, endAt, func(snapshot *NodeSnapshot) time.Time {
+		return snapshot.Timestamp
+	})
+	if len(n.Snapshots) == 0 && !n.Unscheduled() {
+		return false
+	}
+
+	pods := make([]*PodMeta, 0, len(n.Pods))
+	for _, pod := range n.Pods {
+		if pod.DeletedAsOf(beginAt) {
+			continue
+		}
+
+		pod.Snapshots = trim(pod.Snapshots, beginAt, endAt, func(snapshot *PodSnapshot) time.Time {
+			return snapshot.Timestamp
+		})
+		if len(pod.Snapshots) > 0 {
+			pods = append(pods, pod)
+		}
+	}
+	n.Pods = pods
+
+	return !n.Unscheduled() || len(n.Pods) > 0
+}
+
+// trim returns the snapshots between beginAt and endAt, preceded by the latest one before beginAt, in ascending order
+func trim[S ~[]*T, T any](snapshots S, beginAt, endAt time.Time, timestampOf func(*T) time.Time) S {
+	var latestBefore *T
+	trimmed := S{}
+	for _, snapshot := range snapshots {
+		timestamp := timestampOf(snapshot)
+		switch {
+		case timestamp.After(endAt):
+		case timestamp.Before(beginAt):
+			if latestBefore == nil || timestampOf(latestBefore).Before(timestamp) {
+				latestBefore = snapshot
+			}
+		default:
+			trimmed = append(trimmed, snapshot)
+		}
+	}
+
+	if latestBefore != nil {
+		trimmed = append(trimmed, latestBefore)
+	}
+
+	sort.SliceStable(trimmed, func(i, j int) bool {
+		return timestampOf(trimmed[i]).Before(timestampOf(trimmed[j]))
+	})
+
+	return trimmed
+}
+
+// Sweep reconstructs the cluster at each of the ascending timestamps in a single forward pass over the histories of
+// the nodes, rather than searching every history again for every timestamp. Each cursor is local to the sweep, so
+// histories can be shared by concurrent sweeps.
+func Sweep(nodes []*NodeMeta, timestamps []time.Time) []*ClusterState {
+	return slices.Collect(SweepSeq(nodes, timestamps))
+}
+
+// SweepSeq is Sweep yielding each state as it's reconstructed, so callers aggregating over many timestamps don't hold
+// every state at once. Each iteration sweeps again from the first timestamp.
+func SweepSeq(nodes []*NodeMeta, timestamps []time.Time) iter.Seq[*ClusterState] {
+	return func(yield func(*ClusterState) bool) {
+		nodeCursors := map[*NodeMeta]*cursor[NodeSnapshot]{}
+		podCursors := map[*PodMeta]*cursor[PodSnapshot]{}
+		for _, node := range nodes {
+			nodeCursors[node] = newCursor(node.Snapshots, func(snapshot *NodeSnapshot) time.Time {
+				return snapshot.Timestamp
+			})
+			for _, pod := range node.Pods {
+				podCursors[pod] = newCursor(pod.Snapshots, func(snapshot *PodSnapshot) time.Time {
+					return snapshot.Timestamp
+				})
+			}
+		}
+
+		for _, timestamp := range timestamps {
+			state := stateAt(nodes, timestamp, func(node *NodeMeta) *NodeSnapshot {
+				return nodeCursors[node].advanceTo(timestamp)
+			}, func(pod *PodMeta) *PodSnapshot {
+				return podCursors[pod].advanceTo(timestamp)
+			})
+			if !yield(state) {
+				return
+			}
+		}
+	}
+}
+
+// Spans pairs each of the states in ascending order with when it stops holding: the timestamp of the next, or endAt
+// for the last
+func Spans(states iter.Seq[*ClusterState], endAt time.Time) iter.Seq2[*ClusterState, time.Time] {
+	return func(yield func(*ClusterState, time.Time) bool) {
+		var previous *ClusterState
+		for state := range states {
+			if previous != nil && !yield(previous, state.Timestamp) {
+				return
+			}
+			previous = state
+		}
+		if previous != nil {
+			yield(previous, endAt)
+		}
+	}
+}
+
+// cursor walks forward through a history of snapshots ordered by timestamp
+type cursor[T any] struct {
+	snapshots   []*T
+	timestampOf func(*T) time.Time
+	next        int
+}
+
+func newCursor[S ~[]*T, T any](snapshots S, timestampOf func(*T) time.Time) *cursor[T] {
+	return &cursor[T]{
+		snapshots:   snapshots,
+		timestampOf: timestampOf,
+	}
+}
+
+// advanceTo moves past every snapshot at or before the timestamp and returns the last of them, i.e. the one
+// effective at the timestamp. Timestamps must not go backwards between calls.
+func (c *cursor[T]) advanceTo(timestamp time.Time) *T {
+	for c.next < len(c.snapshots) && !c.timestampOf(c.snapshots[c.next]).After(timestamp) {
+		c.next++
+	}
+
+	if c.next == 0 {
+		return nil
+	}
+
+	return c.snapshots[c.next-1]
+}
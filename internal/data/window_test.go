@@ -0,0 +1,103 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+func TestNodeMeta_TrimTo(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	at := func(minutes int) time.Time {
+		return t0.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	node := &data.NodeMeta{
+		ID:        "node-a",
+		Snapshots: data.NodeSnapshots{{Timestamp: at(3)}, {Timestamp: at(0)}, {Timestamp: at(1)}, {Timestamp: at(10)}},
+		Pods: []*data.PodMeta{
+			{ID: "before", Snapshots: data.PodSnapshots{{Timestamp: at(0)}}},
+			{ID: "after", Snapshots: data.PodSnapshots{{Timestamp: at(10)}}},
+			{ID: "deleted", DeletedAt: at(1), Snapshots: data.PodSnapshots{{Timestamp: at(0)}}},
+		},
+	}
+
+	g.Expect(node.TrimTo(at(2), at(5))).To(gomega.BeTrue())
+	g.Expect(node.Snapshots).To(gomega.HaveLen(2))
+	g.Expect(node.Snapshots[0].Timestamp).To(gomega.Equal(at(1)))
+	g.Expect(node.Snapshots[1].Timestamp).To(gomega.Equal(at(3)))
+	g.Expect(node.Pods).To(gomega.HaveLen(1))
+	g.Expect(node.Pods[0].ID).To(gomega.Equal("before"))
+
+	g.Expect((&data.NodeMeta{Snapshots: data.NodeSnapshots{{Timestamp: at(10)}}}).TrimTo(at(2), at(5))).To(gomega.BeFalse())
+	g.Expect((&data.NodeMeta{DeletedAt: at(1), Snapshots: data.NodeSnapshots{{Timestamp: at(0)}}}).TrimTo(at(2), at(5))).To(gomega.BeFalse())
+
+	// the unscheduled tree has no snapshots of its own, so it's kept as long as it has pods to replay
+	unscheduled := &data.NodeMeta{ID: data.UnscheduledID, Pods: []*data.PodMeta{{ID: "pending", Snapshots: data.PodSnapshots{{Timestamp: at(3)}}}}}
+	g.Expect(unscheduled.TrimTo(at(2), at(5))).To(gomega.BeTrue())
+	g.Expect(unscheduled.TrimTo(at(0), at(1))).To(gomega.BeFalse())
+}
+
+func TestSweep(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	at := func(minutes int) time.Time {
+		return t0.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	nodes := []*data.NodeMeta{
+		{
+			ID:        "node-a",
+			Snapshots: data.NodeSnapshots{{Timestamp: at(0)}, {Timestamp: at(4), State: data.NodeState{Condition: data.NodeStateNotReady}}},
+			Pods: []*data.PodMeta{
+				{ID: "pod-1", TreeID: "node-a", Snapshots: data.PodSnapshots{{Timestamp: at(0)}}},
+				{ID: "pod-2", TreeID: "node-a", DeletedAt: at(3), Snapshots: data.PodSnapshots{{Timestamp: at(1)}, {Timestamp: at(2)}}},
+			},
+		},
+		{
+			ID:        "node-b",
+			Snapshots: data.NodeSnapshots{{Timestamp: at(2)}},
+			Pods: []*data.PodMeta{
+				{ID: "pod-1", TreeID: "node-b", Snapshots: data.PodSnapshots{{Timestamp: at(3)}}},
+			},
+		},
+	}
+
+	timestamps := []time.Time{at(-1), at(0), at(1), at(2), at(3), at(4), at(5)}
+	states := data.Sweep(nodes, timestamps)
+	g.Expect(states).To(gomega.HaveLen(len(timestamps)))
+
+	for i, timestamp := range timestamps {
+		g.Expect(states[i]).To(gomega.Equal(data.StateAt(nodes, timestamp)), "at %s", timestamp)
+	}
+}
+
+func TestSpans(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	at := func(minutes int) time.Time {
+		return t0.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	node := &data.NodeMeta{ID: "node-a", Snapshots: data.NodeSnapshots{{Timestamp: at(0)}}}
+	states := data.SweepSeq([]*data.NodeMeta{node}, []time.Time{at(0), at(2), at(3)})
+
+	var timestamps, untils []time.Time
+	for state, until := range data.Spans(states, at(5)) {
+		timestamps = append(timestamps, state.Timestamp)
+		untils = append(untils, until)
+	}
+	g.Expect(timestamps).To(gomega.Equal([]time.Time{at(0), at(2), at(3)}))
+	g.Expect(untils).To(gomega.Equal([]time.Time{at(2), at(3), at(5)}))
+
+	for range data.Spans(data.SweepSeq(nil, nil), at(5)) {
+		t.Fatal("expected no spans without states")
+	}
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// pingTimeout bounds how long a readiness probe waits on the store
+const pingTimeout = 2 * time.Second
+
+// Pinger checks a dependency of the server can be reached
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Probes serves the liveness and readiness of the server. It's ready while the store can be reached, until it starts
+// draining to shut down.
+type Probes struct {
+	store    Pinger
+	draining atomic.Bool
+}
+
+// Healthz reports the server is alive whenever it can serve at all
+func (p *Probes) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// Readyz reports whether the server should be sent requests
+func (p *Probes) Readyz(w http.ResponseWriter, r *http.Request) {
+	if p.draining.Load() {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), pingTimeout)
+	defer cancel()
+	if err := p.store.Ping(ctx); err != nil {
+		log.Printf("readiness probe failed: %v", err)
+		http.Error(w, "store unreachable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// Drain marks the server as no longer ready, so it's taken out of rotation while requests in flight finish
+func (p *Probes) Drain() {
+	p.draining.Store(true)
+}
+
+// NewProbes returns probes checking the store for readiness
+func NewProbes(store Pinger) *Probes {
+	return &Probes{store: store}
+}
@@ -0,0 +1,45 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/health"
+)
+
+type pinger struct {
+	err error
+}
+
+func (p *pinger) Ping(ctx context.Context) error {
+	return p.err
+}
+
+func TestProbes(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	store := &pinger{}
+	probes := health.NewProbes(store)
+	probe := func(handler http.HandlerFunc) int {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest("GET", "/", nil))
+		return rec.Code
+	}
+
+	g.Expect(probe(probes.Healthz)).To(gomega.Equal(http.StatusOK))
+	g.Expect(probe(probes.Readyz)).To(gomega.Equal(http.StatusOK))
+
+	store.err = errors.New("ResourceNotFoundException")
+	g.Expect(probe(probes.Healthz)).To(gomega.Equal(http.StatusOK))
+	g.Expect(probe(probes.Readyz)).To(gomega.Equal(http.StatusServiceUnavailable))
+
+	store.err = nil
+	probes.Drain()
+	g.Expect(probe(probes.Healthz)).To(gomega.Equal(http.StatusOK))
+	g.Expect(probe(probes.Readyz)).To(gomega.Equal(http.StatusServiceUnavailable))
+}
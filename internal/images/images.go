@@ -0,0 +1,144 @@
+package images
+
+import (
+	"iter"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/simulator"
+)
+
+// Use is a pod running containers of an image
+type Use struct {
+	Image     string
+	ImageID   string // digest the image was resolved to, empty until the container started
+	PodID     string
+	Namespace string
+	PodName   string
+	Workload  data.Workload
+	NodeID    string
+	NodeName  string
+}
+
+// Uses returns the images run by the containers of pods that haven't terminated, once per pod and image, ordered by
+// image, image ID, namespace and pod name
+func Uses(state *data.ClusterState) []*Use {
+	var uses []*Use
+	for _, node := range state.Nodes {
+		for _, podAt := range node.Pods {
+			if simulator.Terminated(podAt) {
+				continue
+			}
+
+			seen := map[[2]string]bool{}
+			for _, container := range podAt.Snapshot.Containers {
+				key := [2]string{container.Image, container.ImageID}
+				if container.Image == "" || seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				uses = append(uses, &Use{
+					Image:     container.Image,
+					ImageID:   container.ImageID,
+					PodID:     podAt.Meta.ID,
+					Namespace: podAt.Meta.Namespace,
+					PodName:   podAt.Meta.Name,
+					Workload:  podAt.Meta.Workload(),
+					NodeID:    node.Meta.ID,
+					NodeName:  node.Meta.Name,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(uses, func(i, j int) bool {
+		a, b := uses[i], uses[j]
+		if a.Image != b.Image {
+			return a.Image < b.Image
+		}
+		if a.ImageID != b.ImageID {
+			return a.ImageID < b.ImageID
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.PodName < b.PodName
+	})
+
+	return uses
+}
+
+// Matches reports whether the use is of the image given by reference (e.g. nginx:1.27), image ID or bare digest
+func (u *Use) Matches(image string) bool {
+	return u.Image == image || u.ImageID == image || strings.HasSuffix(u.ImageID, "@"+image)
+}
+
+// Sighting is when a pod was seen running an image
+type Sighting struct {
+	Use
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Sightings returns when each pod was seen running the image from the cluster states in ascending order, each holding
+// until the next or until endAt for the last, ordered by when they were first seen
+func Sightings(frames iter.Seq[*data.ClusterState], endAt time.Time, image string) []*Sighting {
+	var sightings []*Sighting
+	byUse := map[Use]*Sighting{}
+	for frame, until := range data.Spans(frames, endAt) {
+		for _, use := range Uses(frame) {
+			if !use.Matches(image) {
+				continue
+			}
+
+			sighting, ok := byUse[*use]
+			if !ok {
+				sighting = &Sighting{Use: *use, FirstSeen: frame.Timestamp}
+				byUse[*use] = sighting
+				sightings = append(sightings, sighting)
+			}
+			sighting.LastSeen = until
+		}
+	}
+
+	sort.SliceStable(sightings, func(i, j int) bool {
+		return sightings[i].FirstSeen.Before(sightings[j].FirstSeen)
+	})
+
+	return sightings
+}
+
+// Frame is how many pods of a workload ran each image from an instant on
+type Frame struct {
+	Timestamp time.Time
+	Pods      map[string]int // by image
+}
+
+// Rollout returns how many pods of the workload named workload in the namespace ran each image at the cluster states,
+// leaving out those where the counts didn't change
+func Rollout(frames iter.Seq[*data.ClusterState], namespace, workload string) []*Frame {
+	var rollout []*Frame
+	for state := range frames {
+		frame := &Frame{Timestamp: state.Timestamp, Pods: map[string]int{}}
+		counted := map[[2]string]bool{}
+		for _, use := range Uses(state) {
+			key := [2]string{use.PodID, use.Image}
+			if use.Namespace != namespace || use.Workload.Name != workload || counted[key] {
+				continue
+			}
+			counted[key] = true
+			frame.Pods[use.Image]++
+		}
+
+		if len(rollout) > 0 && maps.Equal(rollout[len(rollout)-1].Pods, frame.Pods) {
+			continue
+		}
+		rollout = append(rollout, frame)
+	}
+
+	return rollout
+}
@@ -0,0 +1,72 @@
+package images_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/images"
+)
+
+func TestRollout(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	running := func(at time.Time, image, imageID string) *data.PodSnapshot {
+		return &data.PodSnapshot{Timestamp: at, Status: data.PodPhaseRunning, Containers: []*data.ContainerSnapshot{
+			{Name: "web", Image: image, ImageID: imageID},
+			{Name: "sidecar", Image: "envoy:1.30", ImageID: "envoy@sha256:e"},
+		}}
+	}
+
+	// web-old-1 is replaced by web-new-1 at t0+2m, while web-old-2 keeps running the old version
+	node := &data.NodeMeta{
+		ID: "node-a", Name: "a",
+		Snapshots: data.NodeSnapshots{{Timestamp: t0}},
+		Pods: []*data.PodMeta{
+			{ID: "old-1", Namespace: "shop", Name: "web-6b4f5c7d8b-q2wxz", Snapshots: data.PodSnapshots{
+				running(t0, "web:1.0", "web@sha256:1"),
+				{Timestamp: t0.Add(2 * time.Minute), Status: data.PodPhaseSucceeded},
+			}},
+			{ID: "old-2", Namespace: "shop", Name: "web-6b4f5c7d8b-zk9tm", Snapshots: data.PodSnapshots{
+				running(t0, "web:1.0", "web@sha256:1"),
+			}},
+			{ID: "new-1", Namespace: "shop", Name: "web-7c9d6f8b5d-p4rlx", Snapshots: data.PodSnapshots{
+				{Timestamp: t0.Add(time.Minute), Status: data.PodPhasePending, Containers: []*data.ContainerSnapshot{{Name: "web", Image: "web:1.1"}}},
+				running(t0.Add(2*time.Minute), "web:1.1", "web@sha256:2"),
+			}},
+			{ID: "api", Namespace: "shop", Name: "api-0", Snapshots: data.PodSnapshots{running(t0, "web:1.0", "web@sha256:1")}},
+			// named like a pod of web, but owned by a ReplicaSet of its own
+			{ID: "rs-1", Namespace: "shop", Name: "web-5d8c7b6f9d-t7xkq", Owners: []*data.OwnerReference{
+				{Kind: "ReplicaSet", Name: "web-5d8c7b6f9d", Controller: true},
+			}, Snapshots: data.PodSnapshots{running(t0, "web:1.0", "web@sha256:0")}},
+		},
+	}
+	for _, pod := range node.Pods {
+		pod.SetDynamoAttributes(node.ID)
+	}
+
+	times := []time.Time{t0, t0.Add(time.Minute), t0.Add(2 * time.Minute), t0.Add(3 * time.Minute)}
+	frames := data.SweepSeq([]*data.NodeMeta{node}, times)
+
+	rollout := images.Rollout(frames, "shop", "web")
+	g.Expect(rollout).To(gomega.HaveLen(3))
+	g.Expect(rollout[0].Pods).To(gomega.Equal(map[string]int{"web:1.0": 2, "envoy:1.30": 2}))
+	g.Expect(rollout[1].Pods).To(gomega.Equal(map[string]int{"web:1.0": 2, "web:1.1": 1, "envoy:1.30": 2}))
+	g.Expect(rollout[2].Timestamp).To(gomega.Equal(t0.Add(2 * time.Minute)))
+	g.Expect(rollout[2].Pods).To(gomega.Equal(map[string]int{"web:1.0": 1, "web:1.1": 1, "envoy:1.30": 2}))
+
+	sightings := images.Sightings(frames, t0.Add(5*time.Minute), "sha256:1")
+	g.Expect(sightings).To(gomega.HaveLen(3))
+	g.Expect(sightings[0].PodName).To(gomega.Equal("api-0"))
+	g.Expect(sightings[0].LastSeen).To(gomega.Equal(t0.Add(5 * time.Minute)))
+	g.Expect(sightings[1].PodID).To(gomega.Equal("old-1"))
+	g.Expect(sightings[1].LastSeen).To(gomega.Equal(t0.Add(2 * time.Minute)))
+
+	uses := images.Uses(data.StateAt([]*data.NodeMeta{node}, times[1]))
+	g.Expect(uses[0].Image).To(gomega.Equal("envoy:1.30"))
+	g.Expect(uses[len(uses)-1].Image).To(gomega.Equal("web:1.1"))
+	g.Expect(uses[len(uses)-1].ImageID).To(gomega.BeEmpty())
+}
@@ -0,0 +1,279 @@
+package incidents
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/simulator"
+)
+
+const (
+	// flapWindow is how soon a node must go NotReady again after recovering for both to be one incident
+	flapWindow = 30 * time.Minute
+	// flapTransitions is how many times a node must go NotReady within one incident for it to be flapping
+	flapTransitions = 3
+)
+
+// Kind is a kind of node incident
+type Kind int
+
+const (
+	NotReady Kind = iota
+	Flapping
+	Cordoned
+	NoExecuteTaint
+	AllocatableDropped
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NotReady:
+		return "NotReady"
+	case Flapping:
+		return "Flapping"
+	case Cordoned:
+		return "Cordoned"
+	case NoExecuteTaint:
+		return "NoExecuteTaint"
+	case AllocatableDropped:
+		return "AllocatableDropped"
+	}
+
+	return "Unknown"
+}
+
+// PhaseChange is a pod going from a phase to another
+type PhaseChange struct {
+	At   time.Time
+	From data.PodPhase
+	To   data.PodPhase
+}
+
+// AffectedPod is a pod running on a node when an incident started, with what became of it
+type AffectedPod struct {
+	Meta      *data.PodMeta
+	Phase     data.PodPhase // when the incident started
+	Changes   []PhaseChange // until the end of the window
+	DeletedAt time.Time     // zero unless deleted before the end of the window
+}
+
+// Incident is something going wrong with a node, and the pods running on it when it did
+type Incident struct {
+	NodeID      string
+	NodeName    string
+	Kind        Kind
+	StartedAt   time.Time
+	EndedAt     time.Time // zero if still ongoing at the end of the window
+	Detail      string
+	Transitions int // times the node went NotReady, for NotReady and Flapping incidents
+	Pods        []*AffectedPod
+}
+
+// Detect returns the incidents of the nodes that started between beginAt and endAt, ordered by when they started, then
+// by node name. Node histories are expected to be trimmed to the window, preceded by the snapshot in effect when it
+// opens, which incidents are detected against.
+func Detect(nodes []*data.NodeMeta, beginAt, endAt time.Time) []*Incident {
+	var incidents []*Incident
+	for _, node := range nodes {
+		for _, incident := range detect(node, beginAt, endAt) {
+			incident.Pods = affectedPods(node, incident.StartedAt, endAt)
+			incidents = append(incidents, incident)
+		}
+	}
+
+	sort.SliceStable(incidents, func(i, j int) bool {
+		a, b := incidents[i], incidents[j]
+		if !a.StartedAt.Equal(b.StartedAt) {
+			return a.StartedAt.Before(b.StartedAt)
+		}
+		if a.NodeName != b.NodeName {
+			return a.NodeName < b.NodeName
+		}
+		return a.Kind < b.Kind
+	})
+
+	return incidents
+}
+
+// detect returns the incidents of the node, opening each as its snapshots show it starting and closing it as they
+// show it over
+func detect(node *data.NodeMeta, beginAt, endAt time.Time) []*Incident {
+	var incidents, notReady []*Incident
+	open := map[string]*Incident{}
+	start := func(key string, kind Kind, at time.Time, format string, args ...interface{}) *Incident {
+		if _, ok := open[key]; ok {
+			return nil
+		}
+		incident := &Incident{NodeID: node.ID, NodeName: node.Name, Kind: kind, StartedAt: at, Detail: fmt.Sprintf(format, args...)}
+		open[key] = incident
+		return incident
+	}
+	stop := func(key string, at time.Time) {
+		if incident, ok := open[key]; ok {
+			incident.EndedAt = at
+			delete(open, key)
+		}
+	}
+
+	baselines := map[corev1.ResourceName]resource.Quantity{}
+	var previous *data.NodeSnapshot
+	for _, snapshot := range node.Snapshots.Sorted() {
+		if snapshot.Timestamp.After(endAt) {
+			break
+		}
+		if previous == nil || snapshot.Timestamp.Before(beginAt) {
+			previous = snapshot
+			continue
+		}
+		was, is, at := previous.State, snapshot.State, snapshot.Timestamp
+
+		if is.Condition != data.NodeStateReady && was.Condition == data.NodeStateReady {
+			if incident := start("condition", NotReady, at, "node went %s", is.Condition.String()); incident != nil {
+				incident.Transitions = 1
+				notReady = append(notReady, incident)
+			}
+		} else if is.Condition == data.NodeStateReady {
+			stop("condition", at)
+		}
+
+		if is.Unschedulable && !was.Unschedulable {
+			if incident := start("cordon", Cordoned, at, "node was cordoned"); incident != nil {
+				incidents = append(incidents, incident)
+			}
+		} else if !is.Unschedulable {
+			stop("cordon", at)
+		}
+
+		wasTainted, isTainted := noExecuteTaints(was), noExecuteTaints(is)
+		for _, taint := range sortedKeys(isTainted) {
+			if !wasTainted[taint] {
+				if incident := start(taint, NoExecuteTaint, at, "taint %s was added", taint); incident != nil {
+					incidents = append(incidents, incident)
+				}
+			}
+		}
+		for _, taint := range sortedKeys(wasTainted) {
+			if !isTainted[taint] {
+				stop(taint, at)
+			}
+		}
+
+		wasAllocatable, isAllocatable := simulator.Allocatable(was.Allocatable), simulator.Allocatable(is.Allocatable)
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			key := "allocatable/" + string(name)
+			before, okBefore := wasAllocatable[name]
+			after, okAfter := isAllocatable[name]
+			if !okBefore || !okAfter {
+				continue
+			}
+
+			if baseline, ok := baselines[name]; ok {
+				if after.Cmp(baseline) >= 0 {
+					stop(key, at)
+					delete(baselines, name)
+				}
+			} else if after.Cmp(before) < 0 {
+				baselines[name] = before
+				if incident := start(key, AllocatableDropped, at, "%s allocatable dropped from %s to %s", name, before.String(), after.String()); incident != nil {
+					incidents = append(incidents, incident)
+				}
+			}
+		}
+
+		previous = snapshot
+	}
+
+	return append(incidents, flaps(notReady)...)
+}
+
+// flaps merges the NotReady incidents of a node that recovered within flapWindow of going NotReady again, those where
+// it went NotReady flapTransitions times or more becoming flapping
+func flaps(notReady []*Incident) []*Incident {
+	var merged []*Incident
+	for _, incident := range notReady {
+		if len(merged) > 0 {
+			last := merged[len(merged)-1]
+			if !last.EndedAt.IsZero() && incident.StartedAt.Sub(last.EndedAt) <= flapWindow {
+				last.EndedAt = incident.EndedAt
+				last.Transitions++
+				if last.Transitions >= flapTransitions {
+					last.Kind = Flapping
+					last.Detail = fmt.Sprintf("node went NotReady %d times", last.Transitions)
+				}
+				continue
+			}
+		}
+		merged = append(merged, incident)
+	}
+
+	return merged
+}
+
+// noExecuteTaints returns the taints of the state evicting pods that don't tolerate them, as key=value:NoExecute
+func noExecuteTaints(state data.NodeState) map[string]bool {
+	taints := map[string]bool{}
+	for _, taint := range state.Taints {
+		if corev1.TaintEffect(taint.Effect) == corev1.TaintEffectNoExecute {
+			t := corev1.Taint{Key: taint.Key, Value: taint.Value, Effect: corev1.TaintEffectNoExecute}
+			taints[t.ToString()] = true
+		}
+	}
+
+	return taints
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// affectedPods returns the pods of the node that hadn't terminated at startedAt, with their phase changes until endAt,
+// ordered by namespace and name
+func affectedPods(node *data.NodeMeta, startedAt, endAt time.Time) []*AffectedPod {
+	var pods []*AffectedPod
+	for _, pod := range node.Pods {
+		if pod.DeletedAsOf(startedAt) {
+			continue
+		}
+		snapshot := pod.Snapshots.EffectiveAt(startedAt)
+		if snapshot == nil || simulator.Terminated(&data.PodAt{Meta: pod, Snapshot: snapshot}) {
+			continue
+		}
+
+		affected := &AffectedPod{Meta: pod, Phase: snapshot.Status}
+		phase := snapshot.Status
+		for _, later := range pod.Snapshots.Sorted() {
+			if !later.Timestamp.After(startedAt) || later.Timestamp.After(endAt) {
+				continue
+			}
+			if later.Status != phase {
+				affected.Changes = append(affected.Changes, PhaseChange{At: later.Timestamp, From: phase, To: later.Status})
+				phase = later.Status
+			}
+		}
+		if pod.DeletedAsOf(endAt) {
+			affected.DeletedAt = pod.DeletedAt
+		}
+		pods = append(pods, affected)
+	}
+
+	sort.SliceStable(pods, func(i, j int) bool {
+		a, b := pods[i].Meta, pods[j].Meta
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	return pods
+}
@@ -0,0 +1,99 @@
+package incidents_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/incidents"
+)
+
+func TestDetect(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	at := func(minutes int) time.Time { return t0.Add(time.Duration(minutes) * time.Minute) }
+	healthy := data.NodeState{Condition: data.NodeStateReady, Allocatable: data.NodeCapacity{Cpu: "4", Memory: "16Gi", Pods: 110}}
+	snapshot := func(minutes int, change func(*data.NodeState)) *data.NodeSnapshot {
+		state := healthy
+		change(&state)
+		return &data.NodeSnapshot{Timestamp: at(minutes), State: state}
+	}
+	unchanged := func(*data.NodeState) {}
+	condition := func(c data.NodeCondition) func(*data.NodeState) {
+		return func(state *data.NodeState) { state.Condition = c }
+	}
+	podSnapshot := func(minutes int, phase data.PodPhase) *data.PodSnapshot {
+		return &data.PodSnapshot{Timestamp: at(minutes), Status: phase}
+	}
+
+	// flapped three times, then was drained
+	flapping := &data.NodeMeta{ID: "node-a", Name: "node-a", Snapshots: data.NodeSnapshots{
+		snapshot(-10, unchanged),
+		snapshot(5, condition(data.NodeStateNotReady)),
+		snapshot(10, unchanged),
+		snapshot(20, condition(data.NodeStateUnknown)),
+		snapshot(25, unchanged),
+		snapshot(40, condition(data.NodeStateNotReady)),
+		snapshot(45, unchanged),
+		snapshot(50, func(state *data.NodeState) {
+			state.Unschedulable = true
+			state.Taints = []*data.Taint{{Key: "node.kubernetes.io/unreachable", Effect: "NoExecute"}}
+		}),
+	}, Pods: []*data.PodMeta{
+		{ID: "api", Namespace: "shop", Name: "api-0", Snapshots: data.PodSnapshots{
+			podSnapshot(-20, data.PodPhaseRunning),
+			podSnapshot(55, data.PodPhaseFailed),
+		}, DeletedAt: at(56)},
+		{ID: "done", Namespace: "batch", Name: "job-0", Snapshots: data.PodSnapshots{
+			podSnapshot(-20, data.PodPhaseSucceeded),
+		}},
+	}}
+	// lost memory once, and went NotReady once before the window
+	shrinking := &data.NodeMeta{ID: "node-b", Name: "node-b", Snapshots: data.NodeSnapshots{
+		snapshot(-20, unchanged),
+		snapshot(-5, condition(data.NodeStateNotReady)),
+		snapshot(30, func(state *data.NodeState) { state.Allocatable.Memory = "8Gi" }),
+		snapshot(60, unchanged),
+	}}
+
+	detected := incidents.Detect([]*data.NodeMeta{flapping, shrinking}, t0, at(90))
+	g.Expect(detected).To(gomega.HaveLen(4))
+
+	flap := detected[0]
+	g.Expect(flap.Kind).To(gomega.Equal(incidents.Flapping))
+	g.Expect(flap.NodeName).To(gomega.Equal("node-a"))
+	g.Expect(flap.StartedAt).To(gomega.Equal(at(5)))
+	g.Expect(flap.EndedAt).To(gomega.Equal(at(45)))
+	g.Expect(flap.Transitions).To(gomega.Equal(3))
+	g.Expect(flap.Pods).To(gomega.HaveLen(1))
+	g.Expect(flap.Pods[0].Meta.Name).To(gomega.Equal("api-0"))
+	g.Expect(flap.Pods[0].Phase).To(gomega.Equal(data.PodPhaseRunning))
+	g.Expect(flap.Pods[0].Changes).To(gomega.Equal([]incidents.PhaseChange{
+		{At: at(55), From: data.PodPhaseRunning, To: data.PodPhaseFailed},
+	}))
+	g.Expect(flap.Pods[0].DeletedAt).To(gomega.Equal(at(56)))
+
+	// node-b was NotReady, then recovered while losing memory
+	dropped := detected[1]
+	g.Expect(dropped.Kind).To(gomega.Equal(incidents.AllocatableDropped))
+	g.Expect(dropped.NodeName).To(gomega.Equal("node-b"))
+	g.Expect(dropped.Detail).To(gomega.Equal("memory allocatable dropped from 16Gi to 8Gi"))
+	g.Expect(dropped.EndedAt).To(gomega.Equal(at(60)))
+	g.Expect(dropped.Pods).To(gomega.BeEmpty())
+
+	g.Expect(detected[2].Kind).To(gomega.Equal(incidents.Cordoned))
+	g.Expect(detected[2].EndedAt.IsZero()).To(gomega.BeTrue())
+	g.Expect(detected[3].Kind).To(gomega.Equal(incidents.NoExecuteTaint))
+	g.Expect(detected[3].Detail).To(gomega.Equal("taint node.kubernetes.io/unreachable:NoExecute was added"))
+	g.Expect(detected[3].Pods).To(gomega.HaveLen(1))
+
+	// the window closes before the node goes NotReady again
+	single := incidents.Detect([]*data.NodeMeta{flapping}, t0, at(15))
+	g.Expect(single).To(gomega.HaveLen(1))
+	g.Expect(single[0].Kind).To(gomega.Equal(incidents.NotReady))
+	g.Expect(single[0].Detail).To(gomega.Equal("node went NotReady"))
+	g.Expect(single[0].Transitions).To(gomega.Equal(1))
+}
@@ -0,0 +1,396 @@
+package manifests
+
+import (
+	"maps"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+// roleLabelPrefix is the prefix of the labels kubectl reads the roles of nodes from
+const roleLabelPrefix = "node-role.kubernetes.io/"
+
+// Manifests are the nodes and pods of the cluster at an instant, as Kubernetes API objects
+type Manifests struct {
+	Timestamp time.Time
+	Nodes     *corev1.NodeList
+	Pods      *corev1.PodList
+}
+
+// FromState returns the manifests of every node and pod of the cluster state, in the same order
+func FromState(state *data.ClusterState) *Manifests {
+	m := &Manifests{
+		Timestamp: state.Timestamp,
+		Nodes:     &corev1.NodeList{TypeMeta: metav1.TypeMeta{Kind: "NodeList", APIVersion: "v1"}, Items: []corev1.Node{}},
+		Pods:      &corev1.PodList{TypeMeta: metav1.TypeMeta{Kind: "PodList", APIVersion: "v1"}, Items: []corev1.Pod{}},
+	}
+	for _, node := range state.Nodes {
+		m.Nodes.Items = append(m.Nodes.Items, *Node(node))
+		for _, pod := range node.Pods {
+			m.Pods.Items = append(m.Pods.Items, *Pod(pod, node.Meta.Name))
+		}
+	}
+
+	return m
+}
+
+// Node returns the node as a v1.Node with its status as of its snapshot. The roles of the node are set as
+// node-role.kubernetes.io labels when they're missing, since that's where they're read from.
+func Node(node *data.NodeAt) *corev1.Node {
+	labels := map[string]string{}
+	for k, v := range node.Meta.Labels {
+		labels[k] = v
+	}
+	for _, role := range node.Meta.Roles {
+		if _, ok := labels[roleLabelPrefix+role]; !ok {
+			labels[roleLabelPrefix+role] = ""
+		}
+	}
+
+	state, versions := node.Snapshot.State, node.Versions()
+	var taints []corev1.Taint
+	for _, taint := range state.Taints {
+		taints = append(taints, corev1.Taint{
+			Key:       taint.Key,
+			Value:     taint.Value,
+			Effect:    corev1.TaintEffect(taint.Effect),
+			TimeAdded: metaTime(taint.TimeAdded),
+		})
+	}
+
+	return &corev1.Node{
+		TypeMeta: metav1.TypeMeta{Kind: "Node", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   node.Meta.Name,
+			UID:    types.UID(node.Meta.ID),
+			Labels: labels,
+		},
+		Spec: corev1.NodeSpec{
+			ProviderID:    node.Meta.ProviderID,
+			Unschedulable: state.Unschedulable,
+			Taints:        taints,
+		},
+		Status: corev1.NodeStatus{
+			Capacity:    nodeResources(state.Capacity),
+			Allocatable: nodeResources(state.Allocatable),
+			Conditions: []corev1.NodeCondition{{
+				Type:              corev1.NodeReady,
+				Status:            readyStatus(state.Condition),
+				LastHeartbeatTime: metav1.NewTime(node.Snapshot.Timestamp),
+			}},
+			NodeInfo: corev1.NodeSystemInfo{
+				MachineID:               node.Meta.MachineID,
+				SystemUUID:              node.Meta.SystemUUID,
+				BootID:                  node.Meta.BootID,
+				KernelVersion:           versions.KernelVersion,
+				OSImage:                 versions.OsImage,
+				ContainerRuntimeVersion: versions.ContainerRuntimeVersion,
+				KubeletVersion:          versions.KubeletVersion,
+				KubeProxyVersion:        versions.KubeProxyVersion,
+				OperatingSystem:         node.Meta.OperatingSystem,
+				Architecture:            node.Meta.Architecture,
+			},
+		},
+	}
+}
+
+// Pod returns the pod bound to the node named nodeName as a v1.Pod with its status as of its snapshot. Pods aren't
+// recorded with their creation time, so it's taken to be when they started.
+func Pod(pod *data.PodAt, nodeName string) *corev1.Pod {
+	phase, qosClass := pod.Snapshot.Status, pod.Meta.QOSClass
+	started := metaTime(pod.Meta.StartedAt)
+
+	p := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pod.Meta.Name,
+			Namespace:   pod.Meta.Namespace,
+			UID:         types.UID(pod.Meta.ID),
+			Labels:      maps.Clone(pod.Meta.Labels),
+			Annotations: maps.Clone(pod.Meta.Annotations),
+		},
+		Spec: corev1.PodSpec{
+			NodeName:          nodeName,
+			InitContainers:    containers(pod.Snapshot.InitContainers),
+			Containers:        containers(pod.Snapshot.Containers),
+			NodeSelector:      maps.Clone(pod.Meta.NodeSelector),
+			Affinity:          affinity(pod.Meta.Affinity),
+			Priority:          pod.Meta.Priority,
+			PriorityClassName: pod.Meta.PriorityClassName,
+		},
+		Status: corev1.PodStatus{
+			Phase:                      corev1.PodPhase(phase.String()),
+			QOSClass:                   corev1.PodQOSClass(qosClass.String()),
+			StartTime:                  started,
+			InitContainerStatuses:      containerStatuses(pod.Snapshot.InitContainers),
+			ContainerStatuses:          containerStatuses(pod.Snapshot.Containers),
+			EphemeralContainerStatuses: containerStatuses(pod.Snapshot.EphemeralContainers),
+		},
+	}
+	if started != nil {
+		p.CreationTimestamp = *started
+	}
+	if pod.Meta.PreemptionPolicy != "" {
+		policy := corev1.PreemptionPolicy(pod.Meta.PreemptionPolicy)
+		p.Spec.PreemptionPolicy = &policy
+	}
+	for _, toleration := range pod.Meta.Tolerations {
+		p.Spec.Tolerations = append(p.Spec.Tolerations, corev1.Toleration{
+			Key:               toleration.Key,
+			Operator:          corev1.TolerationOperator(toleration.Operator),
+			Value:             toleration.Value,
+			Effect:            corev1.TaintEffect(toleration.Effect),
+			TolerationSeconds: toleration.TolerationSeconds,
+		})
+	}
+	for _, owner := range pod.Meta.Owners {
+		controller := owner.Controller
+		p.OwnerReferences = append(p.OwnerReferences, metav1.OwnerReference{
+			Kind:       owner.Kind,
+			Name:       owner.Name,
+			UID:        types.UID(owner.UID),
+			Controller: &controller,
+		})
+	}
+
+	for _, container := range pod.Snapshot.EphemeralContainers {
+		p.Spec.EphemeralContainers = append(p.Spec.EphemeralContainers, corev1.EphemeralContainer{
+			EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+				Name:      container.Name,
+				Image:     container.Image,
+				Resources: containerResources(container.Resources),
+			},
+		})
+	}
+
+	ready := corev1.ConditionTrue
+	for _, container := range pod.Snapshot.Containers {
+		if !container.Ready {
+			ready = corev1.ConditionFalse
+		}
+	}
+	p.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}}
+
+	return p
+}
+
+// affinity returns the affinity the summary was written from, leaving out terms that can't be parsed. Weights of
+// preferred terms aren't recorded, so they're all 1.
+func affinity(summary *data.Affinity) *corev1.Affinity {
+	if summary == nil {
+		return nil
+	}
+
+	a := &corev1.Affinity{}
+	var required []corev1.NodeSelectorTerm
+	var preferred []corev1.PreferredSchedulingTerm
+	for _, term := range summary.RequiredNodeTerms {
+		if t, err := nodeSelectorTerm(term); err == nil {
+			required = append(required, t)
+		}
+	}
+	for _, term := range summary.PreferredNodeTerms {
+		if t, err := nodeSelectorTerm(term); err == nil {
+			preferred = append(preferred, corev1.PreferredSchedulingTerm{Weight: 1, Preference: t})
+		}
+	}
+	if len(required) > 0 || len(preferred) > 0 {
+		a.NodeAffinity = &corev1.NodeAffinity{PreferredDuringSchedulingIgnoredDuringExecution: preferred}
+		if len(required) > 0 {
+			a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{NodeSelectorTerms: required}
+		}
+	}
+
+	if required, preferred := podAffinityTerms(summary.PodAffinity); len(required) > 0 || len(preferred) > 0 {
+		a.PodAffinity = &corev1.PodAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution:  required,
+			PreferredDuringSchedulingIgnoredDuringExecution: preferred,
+		}
+	}
+	if required, preferred := podAffinityTerms(summary.PodAntiAffinity); len(required) > 0 || len(preferred) > 0 {
+		a.PodAntiAffinity = &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution:  required,
+			PreferredDuringSchedulingIgnoredDuringExecution: preferred,
+		}
+	}
+
+	return a
+}
+
+// nodeSelectorOperators are the operators of node selector requirements by those of label selectors
+var nodeSelectorOperators = map[selection.Operator]corev1.NodeSelectorOperator{
+	selection.In:           corev1.NodeSelectorOpIn,
+	selection.Equals:       corev1.NodeSelectorOpIn,
+	selection.DoubleEquals: corev1.NodeSelectorOpIn,
+	selection.NotIn:        corev1.NodeSelectorOpNotIn,
+	selection.NotEquals:    corev1.NodeSelectorOpNotIn,
+	selection.Exists:       corev1.NodeSelectorOpExists,
+	selection.DoesNotExist: corev1.NodeSelectorOpDoesNotExist,
+	selection.GreaterThan:  corev1.NodeSelectorOpGt,
+	selection.LessThan:     corev1.NodeSelectorOpLt,
+}
+
+func nodeSelectorTerm(term string) (corev1.NodeSelectorTerm, error) {
+	selector, err := labels.Parse(term)
+	if err != nil {
+		return corev1.NodeSelectorTerm{}, err
+	}
+
+	var t corev1.NodeSelectorTerm
+	requirements, _ := selector.Requirements()
+	for _, requirement := range requirements {
+		t.MatchExpressions = append(t.MatchExpressions, corev1.NodeSelectorRequirement{
+			Key:      requirement.Key(),
+			Operator: nodeSelectorOperators[requirement.Operator()],
+			Values:   requirement.ValuesUnsorted(),
+		})
+	}
+
+	return t, nil
+}
+
+func podAffinityTerms(terms []*data.AffinityTerm) ([]corev1.PodAffinityTerm, []corev1.WeightedPodAffinityTerm) {
+	var required []corev1.PodAffinityTerm
+	var preferred []corev1.WeightedPodAffinityTerm
+	for _, term := range terms {
+		selector, err := metav1.ParseToLabelSelector(term.LabelSelector)
+		if err != nil {
+			continue
+		}
+
+		t := corev1.PodAffinityTerm{LabelSelector: selector, TopologyKey: term.TopologyKey}
+		if term.Required {
+			required = append(required, t)
+		} else {
+			preferred = append(preferred, corev1.WeightedPodAffinityTerm{Weight: 1, PodAffinityTerm: t})
+		}
+	}
+
+	return required, preferred
+}
+
+func containers(snapshots []*data.ContainerSnapshot) []corev1.Container {
+	var containers []corev1.Container
+	for _, container := range snapshots {
+		containers = append(containers, corev1.Container{
+			Name:      container.Name,
+			Image:     container.Image,
+			Resources: containerResources(container.Resources),
+		})
+	}
+
+	return containers
+}
+
+func containerStatuses(snapshots []*data.ContainerSnapshot) []corev1.ContainerStatus {
+	var statuses []corev1.ContainerStatus
+	for _, container := range snapshots {
+		started := container.Running
+		status := corev1.ContainerStatus{
+			Name:         container.Name,
+			Image:        container.Image,
+			ImageID:      container.ImageID,
+			ContainerID:  container.ContainerID,
+			Ready:        container.Ready,
+			RestartCount: int32(container.RestartCount),
+			Started:      &started,
+		}
+
+		switch {
+		case container.Running:
+			startedAt := container.State.StartedAt
+			if startedAt.IsZero() {
+				startedAt = container.StartedAt
+			}
+			status.State.Running = &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(startedAt)}
+		case !container.State.FinishedAt.IsZero():
+			status.State.Terminated = terminated(container.State)
+		default:
+			status.State.Waiting = &corev1.ContainerStateWaiting{Reason: container.State.Reason}
+		}
+		if !container.LastState.FinishedAt.IsZero() {
+			status.LastTerminationState.Terminated = terminated(container.LastState)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+func terminated(state data.ContainerState) *corev1.ContainerStateTerminated {
+	return &corev1.ContainerStateTerminated{
+		ExitCode:   int32(state.ExitCode),
+		Reason:     state.Reason,
+		StartedAt:  metav1.NewTime(state.StartedAt),
+		FinishedAt: metav1.NewTime(state.FinishedAt),
+	}
+}
+
+func containerResources(resources data.ContainerResources) corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: resourceList(resources.Requests.Cpu, resources.Requests.Memory, resources.Requests.EphemeralStorage),
+		Limits:   resourceList(resources.Limits.Cpu, resources.Limits.Memory, resources.Limits.EphemeralStorage),
+	}
+}
+
+func nodeResources(capacity data.NodeCapacity) corev1.ResourceList {
+	resources := resourceList(capacity.Cpu, capacity.Memory, capacity.EphemeralStorage)
+	if capacity.Pods > 0 {
+		if resources == nil {
+			resources = corev1.ResourceList{}
+		}
+		resources[corev1.ResourcePods] = *resource.NewQuantity(capacity.Pods, resource.DecimalSI)
+	}
+
+	return resources
+}
+
+// resourceList returns the quantities given, leaving out those that are empty or can't be parsed
+func resourceList(cpu, memory, ephemeralStorage string) corev1.ResourceList {
+	var resources corev1.ResourceList
+	for name, value := range map[corev1.ResourceName]string{
+		corev1.ResourceCPU:              cpu,
+		corev1.ResourceMemory:           memory,
+		corev1.ResourceEphemeralStorage: ephemeralStorage,
+	} {
+		quantity, err := resource.ParseQuantity(value)
+		if value == "" || err != nil {
+			continue
+		}
+		if resources == nil {
+			resources = corev1.ResourceList{}
+		}
+		resources[name] = quantity
+	}
+
+	return resources
+}
+
+func readyStatus(condition data.NodeCondition) corev1.ConditionStatus {
+	switch condition {
+	case data.NodeStateReady:
+		return corev1.ConditionTrue
+	case data.NodeStateNotReady:
+		return corev1.ConditionFalse
+	default:
+		return corev1.ConditionUnknown
+	}
+}
+
+// metaTime returns nil for a zero time, i.e. one that wasn't recorded
+func metaTime(t time.Time) *metav1.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	mt := metav1.NewTime(t)
+	return &mt
+}
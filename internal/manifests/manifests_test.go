@@ -0,0 +1,112 @@
+package manifests_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/manifests"
+)
+
+func TestFromState(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	nodes := []*data.NodeMeta{{
+		ID:             "node-a",
+		Name:           "a",
+		ProviderID:     "aws:///us-west-2a/i-0123",
+		KubeletVersion: "v1.32.1",
+		Roles:          []string{"worker"},
+		Labels:         map[string]string{"topology.kubernetes.io/zone": "us-west-2a"},
+		Snapshots: data.NodeSnapshots{{
+			Timestamp: t0,
+			State: data.NodeState{
+				Condition:     data.NodeStateNotReady,
+				Capacity:      data.NodeCapacity{Cpu: "4", Memory: "16Gi", Pods: 110},
+				Allocatable:   data.NodeCapacity{Cpu: "3920m", Memory: "15Gi", Pods: 110},
+				Taints:        []*data.Taint{{Key: "node.kubernetes.io/unschedulable", Effect: "NoSchedule", TimeAdded: t0}},
+				Unschedulable: true,
+			},
+		}},
+		Pods: []*data.PodMeta{{
+			ID: "uid-1", Name: "web-0", Namespace: "shop", StartedAt: t0, QOSClass: data.PodQOSClassBurstable,
+			Tolerations: []*data.Toleration{{Key: "node.kubernetes.io/unschedulable", Operator: "Exists", Effect: "NoSchedule"}},
+			Affinity: &data.Affinity{
+				RequiredNodeTerms: []string{"topology.kubernetes.io/zone in (us-west-2a,us-west-2b),!spot"},
+				PodAntiAffinity:   []*data.AffinityTerm{{LabelSelector: "app=web", TopologyKey: "kubernetes.io/hostname", Required: true}},
+			},
+			PreemptionPolicy: "Never",
+			Snapshots: data.PodSnapshots{{
+				Timestamp: t0,
+				Status:    data.PodPhaseRunning,
+				Containers: []*data.ContainerSnapshot{
+					{
+						Name: "web", Image: "nginx:1.27", ContainerID: "containerd://abc", Ready: true, Running: true, RestartCount: 1,
+						Resources: data.ContainerResources{Requests: data.ContainerResource{Cpu: "100m", Memory: "128Mi"}},
+						State:     data.ContainerState{StartedAt: t0},
+						LastState: data.ContainerState{ExitCode: 137, Reason: "OOMKilled", FinishedAt: t0},
+					},
+					{Name: "sidecar", Image: "envoy:1.30", State: data.ContainerState{Reason: "CrashLoopBackOff"}},
+				},
+			}},
+		}},
+	}}
+	for _, node := range nodes {
+		for _, pod := range node.Pods {
+			pod.SetDynamoAttributes(node.ID)
+		}
+	}
+
+	m := manifests.FromState(data.StateAt(nodes, t0))
+	g.Expect(m.Timestamp).To(gomega.Equal(t0))
+	g.Expect(m.Nodes.Kind).To(gomega.Equal("NodeList"))
+	g.Expect(m.Nodes.Items).To(gomega.HaveLen(1))
+	g.Expect(m.Pods.Items).To(gomega.HaveLen(1))
+
+	node := m.Nodes.Items[0]
+	g.Expect(node.Kind).To(gomega.Equal("Node"))
+	g.Expect(node.Name).To(gomega.Equal("a"))
+	g.Expect(string(node.UID)).To(gomega.Equal("node-a"))
+	g.Expect(node.Labels).To(gomega.HaveKeyWithValue("node-role.kubernetes.io/worker", ""))
+	g.Expect(node.Labels).To(gomega.HaveKeyWithValue("topology.kubernetes.io/zone", "us-west-2a"))
+	g.Expect(node.Spec.ProviderID).To(gomega.Equal("aws:///us-west-2a/i-0123"))
+	g.Expect(node.Spec.Unschedulable).To(gomega.BeTrue())
+	g.Expect(node.Spec.Taints).To(gomega.HaveLen(1))
+	g.Expect(node.Spec.Taints[0].Effect).To(gomega.Equal(corev1.TaintEffectNoSchedule))
+	g.Expect(node.Status.Capacity.Cpu().Equal(resource.MustParse("4"))).To(gomega.BeTrue())
+	g.Expect(node.Status.Allocatable.Memory().Equal(resource.MustParse("15Gi"))).To(gomega.BeTrue())
+	g.Expect(node.Status.Allocatable.Pods().Value()).To(gomega.Equal(int64(110)))
+	g.Expect(node.Status.Conditions[0].Status).To(gomega.Equal(corev1.ConditionFalse))
+	g.Expect(node.Status.NodeInfo.KubeletVersion).To(gomega.Equal("v1.32.1"))
+
+	pod := m.Pods.Items[0]
+	g.Expect(pod.Kind).To(gomega.Equal("Pod"))
+	g.Expect(pod.Namespace).To(gomega.Equal("shop"))
+	g.Expect(pod.Spec.NodeName).To(gomega.Equal("a"))
+	g.Expect(pod.Spec.Containers).To(gomega.HaveLen(2))
+	g.Expect(pod.Spec.Containers[0].Resources.Requests.Cpu().Equal(resource.MustParse("100m"))).To(gomega.BeTrue())
+	g.Expect(pod.Spec.Containers[0].Resources.Limits).To(gomega.BeNil())
+	g.Expect(pod.Spec.Tolerations[0].Operator).To(gomega.Equal(corev1.TolerationOpExists))
+	g.Expect(*pod.Spec.PreemptionPolicy).To(gomega.Equal(corev1.PreemptNever))
+	g.Expect(pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions).To(gomega.ConsistOf(
+		corev1.NodeSelectorRequirement{Key: "topology.kubernetes.io/zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"us-west-2a", "us-west-2b"}},
+		corev1.NodeSelectorRequirement{Key: "spot", Operator: corev1.NodeSelectorOpDoesNotExist, Values: []string{}},
+	))
+	g.Expect(pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0].LabelSelector.MatchLabels).To(gomega.Equal(map[string]string{"app": "web"}))
+	g.Expect(pod.Status.Phase).To(gomega.Equal(corev1.PodRunning))
+	g.Expect(pod.Status.QOSClass).To(gomega.Equal(corev1.PodQOSBurstable))
+	g.Expect(pod.Status.StartTime.Time).To(gomega.Equal(t0))
+	g.Expect(pod.Status.Conditions[0].Status).To(gomega.Equal(corev1.ConditionFalse))
+
+	web, sidecar := pod.Status.ContainerStatuses[0], pod.Status.ContainerStatuses[1]
+	g.Expect(web.RestartCount).To(gomega.Equal(int32(1)))
+	g.Expect(web.State.Running).NotTo(gomega.BeNil())
+	g.Expect(web.LastTerminationState.Terminated.Reason).To(gomega.Equal("OOMKilled"))
+	g.Expect(web.LastTerminationState.Terminated.ExitCode).To(gomega.Equal(int32(137)))
+	g.Expect(sidecar.State.Waiting.Reason).To(gomega.Equal("CrashLoopBackOff"))
+}
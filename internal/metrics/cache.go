@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ccpeng/kube-replay/internal/repositories"
+)
+
+// RegisterCacheStats registers collectors reading the statistics of the cached store to reg on every scrape
+func RegisterCacheStats(store repositories.CachedStore, reg prometheus.Registerer) {
+	counter := func(name, help string, value func(repositories.CacheStats) uint64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      name,
+			Help:      help,
+		}, func() float64 {
+			return float64(value(store.Stats()))
+		})
+	}
+
+	reg.MustRegister(
+		counter("hits_total", "Node trees served from the cache.", func(s repositories.CacheStats) uint64 { return s.Hits }),
+		counter("misses_total", "Node trees read through to the store.", func(s repositories.CacheStats) uint64 { return s.Misses }),
+		counter("evictions_total", "Node trees dropped to make room for others.", func(s repositories.CacheStats) uint64 { return s.Evictions }),
+		counter("invalidations_total", "Node trees dropped by writes to their node.", func(s repositories.CacheStats) uint64 { return s.Invalidations }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "entries",
+			Help:      "Node trees currently cached.",
+		}, func() float64 {
+			return float64(store.Stats().Entries)
+		}),
+	)
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// otherOperation labels operations not selecting exactly one root field of the schema
+const otherOperation = "other"
+
+// graphQLMetrics is a gqlgen extension recording the count and latency of operations by the root field they select.
+// Operation names are chosen by clients, so labelling by them would let clients create any number of series.
+type graphQLMetrics struct {
+	operations *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	rootFields map[string]bool
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = &graphQLMetrics{}
+
+func (m *graphQLMetrics) ExtensionName() string {
+	return "Metrics"
+}
+
+func (m *graphQLMetrics) Validate(schema graphql.ExecutableSchema) error {
+	m.rootFields = map[string]bool{}
+	for _, root := range []*ast.Definition{schema.Schema().Query, schema.Schema().Mutation, schema.Schema().Subscription} {
+		if root == nil {
+			continue
+		}
+		for _, field := range root.Fields {
+			m.rootFields[field.Name] = true
+		}
+	}
+
+	return nil
+}
+
+func (m *graphQLMetrics) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	start := time.Now()
+	operation := otherOperation
+	if graphql.HasOperationContext(ctx) {
+		oc := graphql.GetOperationContext(ctx)
+		if !oc.Stats.OperationStart.IsZero() {
+			start = oc.Stats.OperationStart
+		}
+		if oc.Operation != nil {
+			operation = m.rootFieldOf(oc.Operation)
+		}
+	}
+
+	resp := next(ctx)
+
+	status := "ok"
+	if resp == nil || len(resp.Errors) > 0 {
+		status = "error"
+	}
+	m.operations.WithLabelValues(operation, status).Inc()
+	m.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	return resp
+}
+
+// rootFieldOf returns the root field of the schema the operation selects, or otherOperation unless there's exactly one
+func (m *graphQLMetrics) rootFieldOf(operation *ast.OperationDefinition) string {
+	if len(operation.SelectionSet) != 1 {
+		return otherOperation
+	}
+	field, ok := operation.SelectionSet[0].(*ast.Field)
+	if !ok || !m.rootFields[field.Name] {
+		return otherOperation
+	}
+
+	return field.Name
+}
+
+// NewGraphQLExtension returns a gqlgen extension recording operations with collectors registered to reg
+func NewGraphQLExtension(reg prometheus.Registerer) graphql.HandlerExtension {
+	factory := promauto.With(reg)
+	return &graphQLMetrics{
+		operations: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "operations_total",
+			Help:      "GraphQL operations by root field and status.",
+		}, []string{"operation", "status"}),
+		duration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of GraphQL operations by root field.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+		}, []string{"operation"}),
+	}
+}
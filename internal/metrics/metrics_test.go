@@ -0,0 +1,164 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/aws/smithy-go"
+	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/ccpeng/kube-replay/graph"
+	"github.com/ccpeng/kube-replay/internal/auth"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/metrics"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+func TestInstrumentedStore(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	ctx := context.Background()
+
+	reg := metrics.NewRegistry()
+	store := metrics.NewInstrumentedStore(repositories.NewMemoryStore(), reg)
+
+	err := store.Upsert(ctx, &data.NodeMeta{
+		ID:        "node-a",
+		Snapshots: data.NodeSnapshots{{Timestamp: t0}, {Timestamp: t0.Add(time.Minute)}},
+		Pods: []*data.PodMeta{
+			{ID: "pod-a", Snapshots: data.PodSnapshots{{Timestamp: t0}}},
+		},
+	})
+	g.Expect(err).To(gomega.BeNil())
+
+	err = store.UpsertNodeSnapshots(ctx, "node-a", []*data.NodeSnapshot{{}})
+	g.Expect(err).NotTo(gomega.BeNil())
+
+	_, err = store.Get(ctx, "node-a")
+	g.Expect(err).To(gomega.BeNil())
+
+	expected := `
+# HELP kube_replay_store_call_errors_total Calls to the store that failed by method.
+# TYPE kube_replay_store_call_errors_total counter
+kube_replay_store_call_errors_total{method="UpsertNodeSnapshots"} 1
+# HELP kube_replay_store_items_written_total Items written to the store by kind.
+# TYPE kube_replay_store_items_written_total counter
+kube_replay_store_items_written_total{kind="node_meta"} 1
+kube_replay_store_items_written_total{kind="node_snapshot"} 2
+kube_replay_store_items_written_total{kind="pod_meta"} 1
+kube_replay_store_items_written_total{kind="pod_snapshot"} 1
+`
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"kube_replay_store_call_errors_total", "kube_replay_store_items_written_total")
+	g.Expect(err).To(gomega.BeNil())
+
+	count, err := testutil.GatherAndCount(reg, "kube_replay_store_call_duration_seconds")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(3))
+}
+
+func TestCacheStats(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	ctx := context.Background()
+
+	reg := metrics.NewRegistry()
+	store, err := repositories.NewCachedStore(repositories.NewMemoryStore(), 10)
+	g.Expect(err).To(gomega.BeNil())
+	metrics.RegisterCacheStats(store, reg)
+
+	err = store.Upsert(ctx, &data.NodeMeta{ID: "node-a", Snapshots: data.NodeSnapshots{{Timestamp: t0}}})
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 3; i++ {
+		_, err = store.GetBetween(ctx, "node-a", t0, t0.Add(time.Hour))
+		g.Expect(err).To(gomega.BeNil())
+	}
+
+	expected := `
+# HELP kube_replay_cache_entries Node trees currently cached.
+# TYPE kube_replay_cache_entries gauge
+kube_replay_cache_entries 1
+# HELP kube_replay_cache_hits_total Node trees served from the cache.
+# TYPE kube_replay_cache_hits_total counter
+kube_replay_cache_hits_total 2
+# HELP kube_replay_cache_misses_total Node trees read through to the store.
+# TYPE kube_replay_cache_misses_total counter
+kube_replay_cache_misses_total 1
+`
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"kube_replay_cache_entries", "kube_replay_cache_hits_total", "kube_replay_cache_misses_total")
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestGraphQLExtension(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	reg := metrics.NewRegistry()
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers: &graph.Resolver{Replayer: services.NewReplayerWithStore(repositories.NewMemoryStore())},
+	}))
+	srv.AddTransport(transport.POST{})
+	srv.Use(metrics.NewGraphQLExtension(reg))
+	c := client.New(auth.Middleware(auth.Disabled)(srv))
+
+	var resp map[string]interface{}
+	query := `query AtTimestamp { nodeStatesAtTimestamp(timestamp: "2025-04-27T00:00:00Z") { timestamp } }`
+	g.Expect(c.Post(query, &resp)).To(gomega.Succeed())
+	g.Expect(c.Post(query, &resp)).To(gomega.Succeed())
+	g.Expect(c.Post(`query Broken { nodeStatesRange(start: "2025-04-27T00:00:00Z", end: "2025-04-27T01:00:00Z", step: 0) { timestamp } }`, &resp)).NotTo(gomega.Succeed())
+	// operation names are up to clients, so only the root field is labelled, and several of them aren't
+	g.Expect(c.Post(`query Renamed { nodeStatesAtTimestamp(timestamp: "2025-04-27T00:00:00Z") { timestamp } }`, &resp)).To(gomega.Succeed())
+	g.Expect(c.Post(`query { a: nodeStatesAtTimestamp(timestamp: "2025-04-27T00:00:00Z") { timestamp } __typename }`, &resp)).To(gomega.Succeed())
+
+	expected := `
+# HELP kube_replay_graphql_operations_total GraphQL operations by root field and status.
+# TYPE kube_replay_graphql_operations_total counter
+kube_replay_graphql_operations_total{operation="nodeStatesAtTimestamp",status="ok"} 3
+kube_replay_graphql_operations_total{operation="nodeStatesRange",status="error"} 1
+kube_replay_graphql_operations_total{operation="other",status="ok"} 1
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "kube_replay_graphql_operations_total")
+	g.Expect(err).To(gomega.BeNil())
+
+	rec := httptest.NewRecorder()
+	metrics.Handler(reg).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	g.Expect(rec.Body.String()).To(gomega.ContainSubstring(`kube_replay_graphql_operation_duration_seconds_count{operation="nodeStatesAtTimestamp"} 3`))
+}
+
+func TestRetryer(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	reg := metrics.NewRegistry()
+	newRetryer := metrics.NewRetryer(reg)
+
+	// the SDK creates a retryer per client
+	throttled := &smithy.GenericAPIError{Code: "ProvisionedThroughputExceededException"}
+	for i := 0; i < 2; i++ {
+		retryer := newRetryer()
+		g.Expect(retryer.IsErrorRetryable(throttled)).To(gomega.BeTrue())
+		_, err := retryer.RetryDelay(1, throttled)
+		g.Expect(err).To(gomega.BeNil())
+	}
+
+	expected := `
+# HELP kube_replay_dynamodb_retries_total DynamoDB requests that were retried.
+# TYPE kube_replay_dynamodb_retries_total counter
+kube_replay_dynamodb_retries_total 2
+# HELP kube_replay_dynamodb_throttles_total DynamoDB requests that were throttled.
+# TYPE kube_replay_dynamodb_throttles_total counter
+kube_replay_dynamodb_throttles_total 2
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"kube_replay_dynamodb_retries_total", "kube_replay_dynamodb_throttles_total")
+	g.Expect(err).To(gomega.BeNil())
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// retryer counts the throttled and retried attempts of AWS API calls decided on by the wrapped retryer
+type retryer struct {
+	aws.RetryerV2
+	throttles prometheus.Counter
+	retries   prometheus.Counter
+}
+
+func (r *retryer) IsErrorRetryable(err error) bool {
+	if retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary {
+		r.throttles.Inc()
+	}
+
+	return r.RetryerV2.IsErrorRetryable(err)
+}
+
+func (r *retryer) RetryDelay(attempt int, opErr error) (time.Duration, error) {
+	r.retries.Inc()
+	return r.RetryerV2.RetryDelay(attempt, opErr)
+}
+
+// NewRetryer returns a function creating standard retryers for the SDK config, counting their throttles and retries
+// with collectors registered to reg once, however many clients are created
+func NewRetryer(reg prometheus.Registerer) func() aws.Retryer {
+	factory := promauto.With(reg)
+	throttles := factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "dynamodb",
+		Name:      "throttles_total",
+		Help:      "DynamoDB requests that were throttled.",
+	})
+	retries := factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "dynamodb",
+		Name:      "retries_total",
+		Help:      "DynamoDB requests that were retried.",
+	})
+
+	return func() aws.Retryer {
+		return &retryer{
+			RetryerV2: retry.NewStandard(),
+			throttles: throttles,
+			retries:   retries,
+		}
+	}
+}
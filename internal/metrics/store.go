@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+)
+
+// instrumentedStore records the latency and errors of every call to the wrapped store, and the items written per kind
+type instrumentedStore struct {
+	store        repositories.Store
+	duration     *prometheus.HistogramVec
+	errors       *prometheus.CounterVec
+	itemsWritten *prometheus.CounterVec
+}
+
+func (s *instrumentedStore) Ping(ctx context.Context) error {
+	return s.store.Ping(ctx)
+}
+
+func (s *instrumentedStore) ListNodeIDs(ctx context.Context) (nodeIDs []string, err error) {
+	defer s.observe("ListNodeIDs", time.Now(), &err)
+	return s.store.ListNodeIDs(ctx)
+}
+
+func (s *instrumentedStore) GetAll(ctx context.Context) (nodeMetas []*data.NodeMeta, err error) {
+	defer s.observe("GetAll", time.Now(), &err)
+	return s.store.GetAll(ctx)
+}
+
+func (s *instrumentedStore) Get(ctx context.Context, nodeID string) (nodeMeta *data.NodeMeta, err error) {
+	defer s.observe("Get", time.Now(), &err)
+	return s.store.Get(ctx, nodeID)
+}
+
+func (s *instrumentedStore) GetAllBetween(ctx context.Context, beginAt, endAt time.Time) (nodeMetas []*data.NodeMeta, err error) {
+	defer s.observe("GetAllBetween", time.Now(), &err)
+	return s.store.GetAllBetween(ctx, beginAt, endAt)
+}
+
+func (s *instrumentedStore) GetBetween(ctx context.Context, nodeID string, beginAt, endAt time.Time) (nodeMeta *data.NodeMeta, err error) {
+	defer s.observe("GetBetween", time.Now(), &err)
+	return s.store.GetBetween(ctx, nodeID, beginAt, endAt)
+}
+
+func (s *instrumentedStore) GetPodMetasByName(ctx context.Context, namespace, name string) (podMetas []*data.PodMeta, err error) {
+	defer s.observe("GetPodMetasByName", time.Now(), &err)
+	return s.store.GetPodMetasByName(ctx, namespace, name)
+}
+
+func (s *instrumentedStore) Upsert(ctx context.Context, nodeMeta *data.NodeMeta) (err error) {
+	defer s.observe("Upsert", time.Now(), &err)
+	if err = s.store.Upsert(ctx, nodeMeta); err != nil {
+		return err
+	}
+
+	s.written("node_meta", 1)
+	s.written("node_snapshot", len(nodeMeta.Snapshots))
+	s.writtenPods(nodeMeta.Pods)
+	return nil
+}
+
+func (s *instrumentedStore) UpsertNodeSnapshots(ctx context.Context, nodeID string, nodeSnapshots []*data.NodeSnapshot) (err error) {
+	defer s.observe("UpsertNodeSnapshots", time.Now(), &err)
+	if err = s.store.UpsertNodeSnapshots(ctx, nodeID, nodeSnapshots); err != nil {
+		return err
+	}
+
+	s.written("node_snapshot", len(nodeSnapshots))
+	return nil
+}
+
+func (s *instrumentedStore) UpsertPodMetas(ctx context.Context, nodeID string, podMetas []*data.PodMeta) (err error) {
+	defer s.observe("UpsertPodMetas", time.Now(), &err)
+	if err = s.store.UpsertPodMetas(ctx, nodeID, podMetas); err != nil {
+		return err
+	}
+
+	s.writtenPods(podMetas)
+	return nil
+}
+
+func (s *instrumentedStore) UpsertPodSnapshots(ctx context.Context, nodeID string, podID string, podSnapshots []*data.PodSnapshot) (err error) {
+	defer s.observe("UpsertPodSnapshots", time.Now(), &err)
+	if err = s.store.UpsertPodSnapshots(ctx, nodeID, podID, podSnapshots); err != nil {
+		return err
+	}
+
+	s.written("pod_snapshot", len(podSnapshots))
+	return nil
+}
+
+func (s *instrumentedStore) UpdateNodeMetaAttributes(ctx context.Context, nodeID string, updates map[string]interface{}) (err error) {
+	defer s.observe("UpdateNodeMetaAttributes", time.Now(), &err)
+	if err = s.store.UpdateNodeMetaAttributes(ctx, nodeID, updates); err != nil {
+		return err
+	}
+
+	s.written("node_meta", 1)
+	return nil
+}
+
+func (s *instrumentedStore) UpdatePodMetaAttributes(ctx context.Context, nodeID, podID string, updates map[string]interface{}) (err error) {
+	defer s.observe("UpdatePodMetaAttributes", time.Now(), &err)
+	if err = s.store.UpdatePodMetaAttributes(ctx, nodeID, podID, updates); err != nil {
+		return err
+	}
+
+	s.written("pod_meta", 1)
+	return nil
+}
+
+func (s *instrumentedStore) observe(method string, start time.Time, err *error) {
+	s.duration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if *err != nil {
+		s.errors.WithLabelValues(method).Inc()
+	}
+}
+
+func (s *instrumentedStore) written(kind string, n int) {
+	s.itemsWritten.WithLabelValues(kind).Add(float64(n))
+}
+
+func (s *instrumentedStore) writtenPods(podMetas []*data.PodMeta) {
+	s.written("pod_meta", len(podMetas))
+	for _, podMeta := range podMetas {
+		s.written("pod_snapshot", len(podMeta.Snapshots))
+	}
+}
+
+// NewInstrumentedStore returns a Store recording the calls to the given store with collectors registered to reg
+func NewInstrumentedStore(store repositories.Store, reg prometheus.Registerer) repositories.Store {
+	factory := promauto.With(reg)
+	return &instrumentedStore{
+		store: store,
+		duration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "store",
+			Name:      "call_duration_seconds",
+			Help:      "Latency of calls to the store by method.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+		}, []string{"method"}),
+		errors: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "store",
+			Name:      "call_errors_total",
+			Help:      "Calls to the store that failed by method.",
+		}, []string{"method"}),
+		itemsWritten: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "store",
+			Name:      "items_written_total",
+			Help:      "Items written to the store by kind.",
+		}, []string{"kind"}),
+	}
+}
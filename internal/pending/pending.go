@@ -0,0 +1,247 @@
+package pending
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/simulator"
+)
+
+// Cause is why a node couldn't take a pending pod, by the replayed state of the cluster
+type Cause int
+
+const (
+	// NoEligibleNode is a node the pod wasn't eligible for, being cordoned, tainted or not matching its node selector or affinity
+	NoEligibleNode Cause = iota
+	// InsufficientAllocatable is a node without enough allocatable left for the requests of the pod
+	InsufficientAllocatable
+	// PodLimitReached is a node already running as many pods as it allows
+	PodLimitReached
+	// Schedulable is a node the pod fit on by the replayed state, so something replays don't record held it back,
+	// such as its volumes or pod affinity
+	Schedulable
+)
+
+func (c Cause) String() string {
+	switch c {
+	case NoEligibleNode:
+		return "NoEligibleNode"
+	case InsufficientAllocatable:
+		return "InsufficientAllocatable"
+	case PodLimitReached:
+		return "PodLimitReached"
+	case Schedulable:
+		return "Schedulable"
+	}
+
+	return "Unknown"
+}
+
+// CauseCount is how many nodes couldn't take a pod for a cause
+type CauseCount struct {
+	Cause Cause
+	Nodes int
+}
+
+// Pod is a pod that was pending for a while, and why no node could take it if the scheduler hadn't bound it
+type Pod struct {
+	Meta        *data.PodMeta
+	NodeID      string // of the node it was bound to when explained, empty if it wasn't
+	NodeName    string
+	Since       time.Time
+	Until       time.Time // when it left Pending, was deleted or the window closed
+	Ongoing     bool      // still pending when the window closed
+	ExplainedAt time.Time // instant of the replayed state explaining it
+	Causes      []CauseCount
+	Reasons     []string // of each node, prefixed by its name
+
+	explainedBy *data.PodSnapshot // in effect at ExplainedAt
+}
+
+// Duration returns how long the pod was pending
+func (p *Pod) Duration() time.Duration {
+	return p.Until.Sub(p.Since)
+}
+
+// Find returns the pods of the nodes that were pending for at least threshold at some point between beginAt and
+// endAt, the longest pending first. A pod is followed across the trees it was recorded under, from the unscheduled tree
+// to the node it was bound to. One the scheduler hadn't bound when first seen pending within the window is explained by
+// fitting it onto the replayed state of the cluster then, while one bound to a node was pending for something other
+// than scheduling, such as pulling images, and isn't. Node histories are expected to be trimmed to the window,
+// preceded by the snapshots in effect when it opens.
+func Find(nodes []*data.NodeMeta, beginAt, endAt time.Time, threshold time.Duration) []*Pod {
+	var pods []*Pod
+	states := map[time.Time]*data.ClusterState{}
+	for _, history := range histories(nodes) {
+		for _, pod := range pendingSpans(history.merged, beginAt, endAt) {
+			if pod.Duration() < threshold {
+				continue
+			}
+
+			recorded := history.recorded[pod.explainedBy]
+			if !recorded.node.Unscheduled() {
+				pod.NodeID, pod.NodeName = recorded.node.ID, recorded.node.Name
+				pod.Causes, pod.Reasons = []CauseCount{}, []string{}
+				pods = append(pods, pod)
+				continue
+			}
+
+			state, ok := states[pod.ExplainedAt]
+			if !ok {
+				state = data.StateAt(nodes, pod.ExplainedAt)
+				states[pod.ExplainedAt] = state
+			}
+			explain(pod, &data.PodAt{Meta: recorded.meta, Snapshot: pod.explainedBy}, state)
+			pods = append(pods, pod)
+		}
+	}
+
+	sort.SliceStable(pods, func(i, j int) bool {
+		a, b := pods[i], pods[j]
+		if a.Duration() != b.Duration() {
+			return a.Duration() > b.Duration()
+		}
+		if a.Meta.Namespace != b.Meta.Namespace {
+			return a.Meta.Namespace < b.Meta.Namespace
+		}
+		return a.Meta.Name < b.Meta.Name
+	})
+
+	return pods
+}
+
+// history is the snapshots of a pod across the trees it was recorded under
+type history struct {
+	merged   *data.PodMeta // with the snapshots of every tree, deleted as of the earliest deletion recorded
+	recorded map[*data.PodSnapshot]recording
+}
+
+// recording is the tree a snapshot of a pod was recorded under, and the pod meta it was recorded with
+type recording struct {
+	node *data.NodeMeta
+	meta *data.PodMeta
+}
+
+// histories returns the history of each pod of the nodes, in the order the pods are first found
+func histories(nodes []*data.NodeMeta) []*history {
+	var histories []*history
+	byID := map[string]*history{}
+	for _, node := range nodes {
+		for _, meta := range node.Pods {
+			h, ok := byID[meta.ID]
+			if !ok {
+				merged := *meta
+				merged.Snapshots, merged.DeletedAt = nil, time.Time{}
+				h = &history{merged: &merged, recorded: map[*data.PodSnapshot]recording{}}
+				byID[meta.ID] = h
+				histories = append(histories, h)
+			}
+
+			if !meta.DeletedAt.IsZero() && (h.merged.DeletedAt.IsZero() || meta.DeletedAt.Before(h.merged.DeletedAt)) {
+				h.merged.DeletedAt = meta.DeletedAt
+			}
+			for _, snapshot := range meta.Snapshots {
+				h.merged.Snapshots = append(h.merged.Snapshots, snapshot)
+				h.recorded[snapshot] = recording{node: node, meta: meta}
+			}
+		}
+	}
+
+	for _, h := range histories {
+		h.merged.Snapshots = h.merged.Snapshots.Sorted()
+	}
+
+	return histories
+}
+
+// pendingSpans returns the spans of time the pod was pending that overlap the window. A pod first seen pending is taken
+// to have been since it started, if that's earlier.
+func pendingSpans(meta *data.PodMeta, beginAt, endAt time.Time) []*Pod {
+	var spans []*Pod
+	var span *Pod
+	for i, snapshot := range meta.Snapshots.Sorted() {
+		if snapshot.Timestamp.After(endAt) || meta.DeletedAsOf(snapshot.Timestamp) {
+			break
+		}
+
+		if snapshot.Status != data.PodPhasePending {
+			if span != nil {
+				span.Until = snapshot.Timestamp
+				spans = append(spans, span)
+				span = nil
+			}
+			continue
+		}
+		if span != nil {
+			if !snapshot.Timestamp.After(span.ExplainedAt) {
+				span.explainedBy = snapshot
+			}
+			continue
+		}
+
+		span = &Pod{Meta: meta, Since: snapshot.Timestamp, ExplainedAt: snapshot.Timestamp, explainedBy: snapshot}
+		if i == 0 && !meta.StartedAt.IsZero() && meta.StartedAt.Before(span.Since) {
+			span.Since = meta.StartedAt
+		}
+		if span.ExplainedAt.Before(beginAt) {
+			span.ExplainedAt = beginAt
+		}
+	}
+	if span != nil {
+		span.Until, span.Ongoing = endAt, true
+		if meta.DeletedAsOf(endAt) {
+			span.Until, span.Ongoing = meta.DeletedAt, false
+		}
+		spans = append(spans, span)
+	}
+
+	overlapping := spans[:0]
+	for _, span := range spans {
+		if span.Until.After(beginAt) {
+			overlapping = append(overlapping, span)
+		}
+	}
+
+	return overlapping
+}
+
+// explain fits the pod, which isn't bound to a node of the cluster state, onto it, counting the nodes that couldn't
+// take it by cause
+func explain(pod *Pod, podAt *data.PodAt, state *data.ClusterState) {
+	pod.Causes, pod.Reasons = []CauseCount{}, []string{}
+
+	counts := map[Cause]int{}
+	for _, fit := range simulator.NewCluster(state).Fit(simulator.NewPod(podAt)) {
+		counts[causeOf(fit)]++
+		for _, reason := range fit.Reasons {
+			pod.Reasons = append(pod.Reasons, fmt.Sprintf("node %s: %s", fit.NodeName, reason))
+		}
+	}
+	for _, cause := range []Cause{NoEligibleNode, InsufficientAllocatable, PodLimitReached, Schedulable} {
+		if counts[cause] > 0 {
+			pod.Causes = append(pod.Causes, CauseCount{Cause: cause, Nodes: counts[cause]})
+		}
+	}
+}
+
+// causeOf returns why the node couldn't take the pod, a node it wasn't eligible for ruling it out whatever its
+// resources
+func causeOf(fit *simulator.NodeFit) Cause {
+	cause := Schedulable
+	for _, constraint := range fit.Constraints {
+		switch constraint {
+		case simulator.Cordoned, simulator.Tainted, simulator.NodeSelector:
+			return NoEligibleNode
+		case simulator.InsufficientResources:
+			cause = InsufficientAllocatable
+		case simulator.TooManyPods:
+			if cause == Schedulable {
+				cause = PodLimitReached
+			}
+		}
+	}
+
+	return cause
+}
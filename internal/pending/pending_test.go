@@ -0,0 +1,91 @@
+package pending_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/pending"
+)
+
+func TestFind(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	at := func(minutes int) time.Time { return t0.Add(time.Duration(minutes) * time.Minute) }
+	pod := func(id, cpu string, phases map[int]data.PodPhase) *data.PodMeta {
+		meta := &data.PodMeta{ID: id, Name: id, Namespace: "shop"}
+		for minutes, phase := range phases {
+			meta.Snapshots = append(meta.Snapshots, &data.PodSnapshot{Timestamp: at(minutes), Status: phase, Containers: []*data.ContainerSnapshot{
+				{Resources: data.ContainerResources{Requests: data.ContainerResource{Cpu: cpu}}},
+			}})
+		}
+		return meta
+	}
+	node := func(id string, state data.NodeState, pods ...*data.PodMeta) *data.NodeMeta {
+		for _, p := range pods {
+			p.SetDynamoAttributes(id)
+		}
+		return &data.NodeMeta{ID: id, Name: id, Snapshots: data.NodeSnapshots{{Timestamp: at(-30), State: state}}, Pods: pods}
+	}
+	room := func(cpu string, pods int64) data.NodeState {
+		return data.NodeState{Condition: data.NodeStateReady, Allocatable: data.NodeCapacity{Cpu: cpu, Memory: "8Gi", Pods: pods}}
+	}
+	cordoned := room("4", 110)
+	cordoned.Unschedulable = true
+
+	// big waits unbound until it's bound to a at 20
+	unscheduled := &data.NodeMeta{ID: data.UnscheduledID, Name: data.UnscheduledID, Pods: []*data.PodMeta{
+		pod("big", "2", map[int]data.PodPhase{-10: data.PodPhasePending}),
+	}}
+	unscheduled.Pods[0].SetDynamoAttributes(data.UnscheduledID)
+
+	nodes := []*data.NodeMeta{
+		unscheduled,
+		// a has 1 CPU left, and big needs 2
+		node("a", room("2", 110),
+			pod("web-0", "1", map[int]data.PodPhase{-20: data.PodPhaseRunning}),
+			pod("big", "2", map[int]data.PodPhase{20: data.PodPhaseRunning}),
+			pod("slow", "100m", map[int]data.PodPhase{30: data.PodPhasePending, 32: data.PodPhaseRunning})),
+		node("b", cordoned),
+		node("c", room("4", 1), pod("job-0", "", map[int]data.PodPhase{-20: data.PodPhaseRunning})),
+		// pulling is bound to d, so it's pending for something other than scheduling
+		node("d", room("4", 110), pod("pulling", "500m", map[int]data.PodPhase{10: data.PodPhasePending})),
+	}
+
+	pods := pending.Find(nodes, t0, at(60), 5*time.Minute)
+	g.Expect(pods).To(gomega.HaveLen(2))
+
+	pulling := pods[0]
+	g.Expect(pulling.Meta.Name).To(gomega.Equal("pulling"))
+	g.Expect(pulling.NodeName).To(gomega.Equal("d"))
+	g.Expect(pulling.Ongoing).To(gomega.BeTrue())
+	g.Expect(pulling.Duration()).To(gomega.Equal(50 * time.Minute))
+	g.Expect(pulling.Causes).To(gomega.BeEmpty())
+	g.Expect(pulling.Reasons).To(gomega.BeEmpty())
+
+	// pending since before the window and followed onto a, explained as of when the window opens
+	big := pods[1]
+	g.Expect(big.Meta.Name).To(gomega.Equal("big"))
+	g.Expect(big.NodeID).To(gomega.BeEmpty())
+	g.Expect(big.Since).To(gomega.Equal(at(-10)))
+	g.Expect(big.Until).To(gomega.Equal(at(20)))
+	g.Expect(big.Ongoing).To(gomega.BeFalse())
+	g.Expect(big.ExplainedAt).To(gomega.Equal(t0))
+	g.Expect(big.Causes).To(gomega.Equal([]pending.CauseCount{
+		{Cause: pending.NoEligibleNode, Nodes: 1},
+		{Cause: pending.InsufficientAllocatable, Nodes: 1},
+		{Cause: pending.PodLimitReached, Nodes: 1},
+		{Cause: pending.Schedulable, Nodes: 1},
+	}))
+	g.Expect(big.Reasons).To(gomega.ContainElements(
+		"node a: insufficient cpu, 2 requested but 1 free",
+		"node b: node is unschedulable",
+		"node c: too many pods, 1 allowed",
+	))
+
+	// big left Pending before the window
+	g.Expect(pending.Find(nodes, at(25), at(60), 5*time.Minute)).To(gomega.HaveLen(1))
+}
@@ -0,0 +1,52 @@
+package policy
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/ccpeng/kube-replay/internal/auth"
+	"github.com/ccpeng/kube-replay/internal/manifests"
+)
+
+// applyToManifests drops the pods in namespaces the identity may not replay and redacts the rest, the same as for
+// snapshots. Manifests are built for every call, so they're modified in place.
+func (p policy) applyToManifests(m *manifests.Manifests) {
+	if p.identity.Redacts(auth.RedactNodeIdentifiers) {
+		for i := range m.Nodes.Items {
+			node := &m.Nodes.Items[i]
+			node.Spec.ProviderID = Redacted
+			node.Status.NodeInfo.MachineID, node.Status.NodeInfo.SystemUUID = Redacted, Redacted
+		}
+	}
+
+	pods := []corev1.Pod{}
+	for _, pod := range m.Pods.Items {
+		if !p.identity.CanReadNamespace(pod.Namespace) {
+			continue
+		}
+
+		if p.identity.Redacts(auth.RedactImages) {
+			for i := range pod.Spec.InitContainers {
+				pod.Spec.InitContainers[i].Image = Redacted
+			}
+			for i := range pod.Spec.Containers {
+				pod.Spec.Containers[i].Image = Redacted
+			}
+			for i := range pod.Spec.EphemeralContainers {
+				pod.Spec.EphemeralContainers[i].Image = Redacted
+			}
+		}
+		for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses, pod.Status.EphemeralContainerStatuses} {
+			for i := range statuses {
+				if p.identity.Redacts(auth.RedactImages) {
+					statuses[i].Image, statuses[i].ImageID = Redacted, Redacted
+				}
+				if p.identity.Redacts(auth.RedactContainerIDs) {
+					statuses[i].ContainerID = Redacted
+				}
+			}
+		}
+
+		pods = append(pods, pod)
+	}
+	m.Pods.Items = pods
+}
@@ -0,0 +1,109 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/auth"
+)
+
+// Redacted replaces the values hidden from an identity
+const Redacted = "<redacted>"
+
+// policy applies the namespace scope and redactions of an identity to replayed snapshots. Snapshots are modified in
+// place, so values are only ever replaced rather than written through pointers that may be shared with the store.
+type policy struct {
+	identity *auth.Identity
+}
+
+func (p policy) applyToSnapshots(snapshots ...*model.TimedNodeSnapshots) {
+	for _, snapshot := range snapshots {
+		if snapshot == nil {
+			continue
+		}
+
+		for _, node := range snapshot.Nodes {
+			p.applyToNode(node)
+		}
+	}
+}
+
+func (p policy) applyToNode(node *model.NodeSnapshot) {
+	if p.identity.Redacts(auth.RedactNodeIdentifiers) {
+		redacted := Redacted
+		node.ProviderID = &redacted
+		if node.Info != nil {
+			info := *node.Info
+			info.MachineID, info.SystemUUID = Redacted, Redacted
+			node.Info = &info
+		}
+	}
+
+	pods := []*model.PodSnapshot{}
+	for _, pod := range node.Pods {
+		if p.allowsPod(pod) {
+			p.applyToPod(pod)
+			pods = append(pods, pod)
+		}
+	}
+	node.Pods = pods
+}
+
+func (p policy) applyToPod(pod *model.PodSnapshot) {
+	for _, containers := range [][]*model.ContainerSnapshot{pod.InitContainers, pod.Containers, pod.EphemeralContainers} {
+		for i, container := range containers {
+			redacted := *container
+			if p.identity.Redacts(auth.RedactImages) {
+				redacted.Image, redacted.ImageID = Redacted, Redacted
+			}
+			if p.identity.Redacts(auth.RedactContainerIDs) {
+				redacted.ContainerID = Redacted
+			}
+			containers[i] = &redacted
+		}
+	}
+}
+
+// applyToEvents returns the events of nodes, and of pods in namespaces the identity may replay
+func (p policy) applyToEvents(events []*model.ClusterEvent) []*model.ClusterEvent {
+	allowed := []*model.ClusterEvent{}
+	for _, event := range events {
+		if event.PodID == nil || p.identity.CanReadNamespace(valueOf(event.Namespace)) {
+			allowed = append(allowed, event)
+		}
+	}
+
+	return allowed
+}
+
+// authorizeImages returns an error if images are redacted for the identity, since querying by them would reveal them
+func (p policy) authorizeImages() error {
+	if p.identity.Redacts(auth.RedactImages) {
+		return fmt.Errorf("%w: images are redacted for %s", auth.ErrForbidden, p.identity.Subject)
+	}
+
+	return nil
+}
+
+// authorizeFilter returns an error if the filter matches pods by image while images are redacted for the identity,
+// since which pods match would reveal them
+func (p policy) authorizeFilter(filter *model.SnapshotFilter) error {
+	if filter == nil || filter.Pods == nil || filter.Pods.Image == nil {
+		return nil
+	}
+
+	return p.authorizeImages()
+}
+
+// allowsPod returns whether the pod is in a namespace the identity may replay
+func (p policy) allowsPod(pod *model.PodSnapshot) bool {
+	return p.identity.CanReadNamespace(valueOf(pod.Namespace))
+}
+
+func valueOf(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
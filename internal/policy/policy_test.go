@@ -0,0 +1,122 @@
+package policy_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/auth"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/policy"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+func TestReplayer(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	ctx := context.Background()
+
+	store, err := repositories.NewCachedStore(repositories.NewMemoryStore(), 10)
+	g.Expect(err).To(gomega.BeNil())
+	err = store.Upsert(ctx, &data.NodeMeta{
+		ID:         "node-a",
+		ProviderID: "aws:///us-west-2a/i-0123456789",
+		MachineID:  "machine-a",
+		SystemUUID: "uuid-a",
+		Snapshots:  data.NodeSnapshots{{Timestamp: t0}},
+		Pods: []*data.PodMeta{
+			{ID: "pod-a", Name: "api", Namespace: "team-a", Snapshots: data.PodSnapshots{{
+				Timestamp:  t0,
+				Containers: []*data.ContainerSnapshot{{Name: "api", Image: "registry/api:1.0", ImageID: "sha256:a", ContainerID: "containerd://a"}},
+			}}},
+			{ID: "pod-b", Name: "db", Namespace: "team-b", Snapshots: data.PodSnapshots{{Timestamp: t0}}},
+		},
+	})
+	g.Expect(err).To(gomega.BeNil())
+
+	replayer := policy.NewReplayer(services.NewReplayerWithStore(store))
+	restricted := auth.WithIdentity(ctx, &auth.Identity{
+		Subject:    "team-a",
+		Roles:      []auth.Role{auth.RoleReader},
+		Namespaces: []string{"team-a"},
+		Redactions: []auth.Redaction{auth.RedactImages, auth.RedactContainerIDs, auth.RedactNodeIdentifiers},
+	})
+
+	// windows in the past are served from the cache, so it's replayed twice to check redaction doesn't reach it
+	for i := 0; i < 2; i++ {
+		snapshots, err := replayer.IntervalSnapshots(restricted, t0, t0.Add(time.Minute), 60, nil)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(snapshots).To(gomega.HaveLen(2))
+
+		node := snapshots[0].Nodes[0]
+		g.Expect(*node.ProviderID).To(gomega.Equal(policy.Redacted))
+		g.Expect(node.Info.MachineID).To(gomega.Equal(policy.Redacted))
+		g.Expect(node.Info.SystemUUID).To(gomega.Equal(policy.Redacted))
+		g.Expect(node.Pods).To(gomega.HaveLen(1))
+		g.Expect(*node.Pods[0].Namespace).To(gomega.Equal("team-a"))
+
+		container := node.Pods[0].Containers[0]
+		g.Expect(container.Name).To(gomega.Equal("api"))
+		g.Expect(container.Image).To(gomega.Equal(policy.Redacted))
+		g.Expect(container.ImageID).To(gomega.Equal(policy.Redacted))
+		g.Expect(container.ContainerID).To(gomega.Equal(policy.Redacted))
+	}
+
+	m, err := replayer.EffectiveAtManifests(restricted, t0, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Nodes.Items[0].Spec.ProviderID).To(gomega.Equal(policy.Redacted))
+	g.Expect(m.Nodes.Items[0].Status.NodeInfo.SystemUUID).To(gomega.Equal(policy.Redacted))
+	g.Expect(m.Pods.Items).To(gomega.HaveLen(1))
+	g.Expect(m.Pods.Items[0].Spec.Containers[0].Image).To(gomega.Equal(policy.Redacted))
+	g.Expect(m.Pods.Items[0].Status.ContainerStatuses[0].ContainerID).To(gomega.Equal(policy.Redacted))
+
+	drain, err := replayer.SimulateNodeDrain(restricted, t0, "")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(drain.Placements).To(gomega.HaveLen(1))
+	g.Expect(drain.Placements[0].Namespace).To(gomega.Equal("team-a"))
+
+	snapshot, err := replayer.EffectiveAtSnapshot(auth.WithIdentity(ctx, auth.Unrestricted), t0, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(*snapshot.Nodes[0].ProviderID).To(gomega.Equal("aws:///us-west-2a/i-0123456789"))
+	g.Expect(snapshot.Nodes[0].Info.MachineID).To(gomega.Equal("machine-a"))
+	g.Expect(snapshot.Nodes[0].Pods).To(gomega.HaveLen(2))
+	g.Expect(snapshot.Nodes[0].Pods[0].Containers[0].Image).To(gomega.Equal("registry/api:1.0"))
+
+	history, err := replayer.PodHistory(restricted, "team-a", "api", t0, t0.Add(time.Minute))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(history.Snapshots[0].Containers[0].Image).To(gomega.Equal(policy.Redacted))
+
+	_, err = replayer.PodHistory(restricted, "team-b", "db", t0, t0.Add(time.Minute))
+	g.Expect(err).To(gomega.MatchError(auth.ErrForbidden))
+
+	_, err = replayer.ImageSightings(restricted, "sha256:a", t0, t0.Add(time.Minute))
+	g.Expect(err).To(gomega.MatchError(auth.ErrForbidden))
+
+	// filtering by image would reveal which pods run it
+	image := "registry/api:1.0"
+	byImage := &model.SnapshotFilter{Pods: &model.PodFilter{Image: &image}}
+	_, err = replayer.EffectiveAtSnapshot(restricted, t0, byImage)
+	g.Expect(err).To(gomega.MatchError(auth.ErrForbidden))
+	_, err = replayer.IntervalSnapshots(restricted, t0, t0.Add(time.Minute), 60, byImage)
+	g.Expect(err).To(gomega.MatchError(auth.ErrForbidden))
+	_, err = replayer.WorkloadsAtTimestamp(restricted, t0, byImage)
+	g.Expect(err).To(gomega.MatchError(auth.ErrForbidden))
+
+	// team-b may see images, but not the pod of team-a running the only one
+	teamB := auth.WithIdentity(ctx, &auth.Identity{Subject: "team-b", Roles: []auth.Role{auth.RoleReader}, Namespaces: []string{"team-b"}})
+	containerImages, err := replayer.ImagesAtTimestamp(teamB, t0, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(containerImages).To(gomega.BeEmpty())
+	sightings, err := replayer.ImageSightings(teamB, "sha256:a", t0, t0.Add(time.Minute))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(sightings.Pods).To(gomega.BeEmpty())
+	g.Expect(sightings.FirstSeen).To(gomega.BeNil())
+
+	_, err = replayer.EffectiveAtSnapshot(ctx, t0, nil)
+	g.Expect(err).To(gomega.MatchError(auth.ErrUnauthenticated))
+}
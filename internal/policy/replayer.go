@@ -0,0 +1,403 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/auth"
+	"github.com/ccpeng/kube-replay/internal/manifests"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+// policedReplayer applies the policy of the identity in the context to everything replayed by the wrapped replayer,
+// leaving recording to it as is
+type policedReplayer struct {
+	replayer services.Replayer
+}
+
+func (r *policedReplayer) RecordNodeSnapshot(ctx context.Context, snapshot *model.NodeSnapshotInput) error {
+	return r.replayer.RecordNodeSnapshot(ctx, snapshot)
+}
+
+func (r *policedReplayer) RecordPodSnapshots(ctx context.Context, snapshots []*model.PodSnapshotInput) error {
+	return r.replayer.RecordPodSnapshots(ctx, snapshots)
+}
+
+func (r *policedReplayer) RecordNodeDeletion(ctx context.Context, deletion *model.NodeDeletionInput) error {
+	return r.replayer.RecordNodeDeletion(ctx, deletion)
+}
+
+func (r *policedReplayer) RecordPodDeletion(ctx context.Context, deletion *model.PodDeletionInput) error {
+	return r.replayer.RecordPodDeletion(ctx, deletion)
+}
+
+func (r *policedReplayer) EventfulSnapshots(ctx context.Context, beginAt, endAt time.Time, filter *model.SnapshotFilter) ([]*model.TimedNodeSnapshots, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorizeFilter(filter); err != nil {
+		return nil, err
+	}
+
+	snapshots, err := r.replayer.EventfulSnapshots(ctx, beginAt, endAt, filter)
+	if err != nil {
+		return nil, err
+	}
+	p.applyToSnapshots(snapshots...)
+
+	return snapshots, nil
+}
+
+func (r *policedReplayer) IntervalSnapshots(ctx context.Context, beginAt, endAt time.Time, intervalInSec int64, filter *model.SnapshotFilter) ([]*model.TimedNodeSnapshots, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorizeFilter(filter); err != nil {
+		return nil, err
+	}
+
+	snapshots, err := r.replayer.IntervalSnapshots(ctx, beginAt, endAt, intervalInSec, filter)
+	if err != nil {
+		return nil, err
+	}
+	p.applyToSnapshots(snapshots...)
+
+	return snapshots, nil
+}
+
+func (r *policedReplayer) EffectiveAtSnapshot(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) (*model.TimedNodeSnapshots, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorizeFilter(filter); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := r.replayer.EffectiveAtSnapshot(ctx, effectiveAt, filter)
+	if err != nil {
+		return nil, err
+	}
+	p.applyToSnapshots(snapshot)
+
+	return snapshot, nil
+}
+
+func (r *policedReplayer) PodHistory(ctx context.Context, namespace, name string, beginAt, endAt time.Time) (*model.PodHistory, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !p.identity.CanReadNamespace(namespace) {
+		return nil, fmt.Errorf("%w: %s may not replay namespace %s", auth.ErrForbidden, p.identity.Subject, namespace)
+	}
+
+	history, err := r.replayer.PodHistory(ctx, namespace, name, beginAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range history.Snapshots {
+		p.applyToPod(pod)
+	}
+
+	return history, nil
+}
+
+func (r *policedReplayer) ClusterEvents(ctx context.Context, beginAt, endAt time.Time, filter *model.SnapshotFilter) ([]*model.ClusterEvent, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorizeFilter(filter); err != nil {
+		return nil, err
+	}
+
+	events, err := r.replayer.ClusterEvents(ctx, beginAt, endAt, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.applyToEvents(events), nil
+}
+
+func (r *policedReplayer) EffectiveAtManifests(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) (*manifests.Manifests, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorizeFilter(filter); err != nil {
+		return nil, err
+	}
+
+	m, err := r.replayer.EffectiveAtManifests(ctx, effectiveAt, filter)
+	if err != nil {
+		return nil, err
+	}
+	p.applyToManifests(m)
+
+	return m, nil
+}
+
+func (r *policedReplayer) SimulatePodFit(ctx context.Context, effectiveAt time.Time, pod *model.HypotheticalPodInput) (*model.PodFitSimulation, error) {
+	if _, err := policyFrom(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.replayer.SimulatePodFit(ctx, effectiveAt, pod)
+}
+
+// SimulateNodeDrain leaves out the placements of pods the identity may not see, though they're still rescheduled
+// along with the others
+func (r *policedReplayer) SimulateNodeDrain(ctx context.Context, effectiveAt time.Time, nodeName string) (*model.DrainSimulation, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	simulation, err := r.replayer.SimulateNodeDrain(ctx, effectiveAt, nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	placements := []*model.PodPlacement{}
+	for _, placement := range simulation.Placements {
+		if p.identity.CanReadNamespace(placement.Namespace) {
+			placements = append(placements, placement)
+		}
+	}
+	simulation.Placements = placements
+
+	return simulation, nil
+}
+
+func (r *policedReplayer) CapacityPlan(ctx context.Context, beginAt, endAt time.Time) (*model.CapacityPlan, error) {
+	if _, err := policyFrom(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.replayer.CapacityPlan(ctx, beginAt, endAt)
+}
+
+// CostReport keeps the cluster-wide totals, since nodes aren't namespaced, but drops the namespaces out of scope
+func (r *policedReplayer) CostReport(ctx context.Context, beginAt, endAt time.Time) (*model.CostReport, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := r.replayer.CostReport(ctx, beginAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := []*model.NamespaceCost{}
+	for _, namespace := range report.Namespaces {
+		if p.identity.CanReadNamespace(namespace.Namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	report.Namespaces = namespaces
+
+	return report, nil
+}
+
+// ImagesAtTimestamp leaves out the pods the identity may not see, and the images only they ran
+func (r *policedReplayer) ImagesAtTimestamp(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) ([]*model.ContainerImage, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorizeImages(); err != nil {
+		return nil, err
+	}
+
+	containerImages, err := r.replayer.ImagesAtTimestamp(ctx, effectiveAt, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := []*model.ContainerImage{}
+	for _, containerImage := range containerImages {
+		pods := []*model.ImagePod{}
+		for _, pod := range containerImage.Pods {
+			if p.identity.CanReadNamespace(pod.Namespace) {
+				pods = append(pods, pod)
+			}
+		}
+		if len(pods) > 0 {
+			containerImage.Pods = pods
+			allowed = append(allowed, containerImage)
+		}
+	}
+
+	return allowed, nil
+}
+
+// ImageSightings leaves out the pods the identity may not see, so the image is seen when any it may see ran it
+func (r *policedReplayer) ImageSightings(ctx context.Context, image string, beginAt, endAt time.Time) (*model.ImageSightings, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorizeImages(); err != nil {
+		return nil, err
+	}
+
+	sightings, err := r.replayer.ImageSightings(ctx, image, beginAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := []*model.ImagePodSighting{}
+	for _, pod := range sightings.Pods {
+		if p.identity.CanReadNamespace(pod.Namespace) {
+			pods = append(pods, pod)
+		}
+	}
+	sightings.Pods = pods
+	services.SeenBetween(sightings)
+
+	return sightings, nil
+}
+
+func (r *policedReplayer) RolloutTimeline(ctx context.Context, namespace, workload string, beginAt, endAt time.Time) ([]*model.RolloutFrame, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorizeImages(); err != nil {
+		return nil, err
+	}
+	if !p.identity.CanReadNamespace(namespace) {
+		return nil, fmt.Errorf("%w: %s may not replay namespace %s", auth.ErrForbidden, p.identity.Subject, namespace)
+	}
+
+	return r.replayer.RolloutTimeline(ctx, namespace, workload, beginAt, endAt)
+}
+
+func (r *policedReplayer) VersionReport(ctx context.Context, effectiveAt time.Time, reference *string, maxMinorSkew int) (*model.VersionReport, error) {
+	if _, err := policyFrom(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.replayer.VersionReport(ctx, effectiveAt, reference, maxMinorSkew)
+}
+
+func (r *policedReplayer) UpgradeProgress(ctx context.Context, beginAt, endAt time.Time, component model.NodeComponent, target *string) (*model.UpgradeProgress, error) {
+	if _, err := policyFrom(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.replayer.UpgradeProgress(ctx, beginAt, endAt, component, target)
+}
+
+func (r *policedReplayer) RestartAnomalies(ctx context.Context, beginAt, endAt time.Time, namespace *string) ([]*model.RestartAnomaly, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	restartAnomalies, err := r.replayer.RestartAnomalies(ctx, beginAt, endAt, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := []*model.RestartAnomaly{}
+	for _, anomaly := range restartAnomalies {
+		if p.identity.CanReadNamespace(anomaly.Namespace) {
+			allowed = append(allowed, anomaly)
+		}
+	}
+
+	return allowed, nil
+}
+
+func (r *policedReplayer) NodeIncidents(ctx context.Context, beginAt, endAt time.Time, nodeName *string) ([]*model.NodeIncident, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeIncidents, err := r.replayer.NodeIncidents(ctx, beginAt, endAt, nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	// incidents are about nodes, which anyone may see, but not the pods of namespaces out of scope
+	for _, incident := range nodeIncidents {
+		allowed := []*model.AffectedPod{}
+		for _, pod := range incident.Pods {
+			if p.identity.CanReadNamespace(pod.Namespace) {
+				allowed = append(allowed, pod)
+			}
+		}
+		incident.Pods = allowed
+	}
+
+	return nodeIncidents, nil
+}
+
+func (r *policedReplayer) PendingPods(ctx context.Context, beginAt, endAt time.Time, minPending time.Duration, namespace *string) ([]*model.PendingPod, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingPods, err := r.replayer.PendingPods(ctx, beginAt, endAt, minPending, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := []*model.PendingPod{}
+	for _, pod := range pendingPods {
+		if p.identity.CanReadNamespace(pod.Namespace) {
+			allowed = append(allowed, pod)
+		}
+	}
+
+	return allowed, nil
+}
+
+func (r *policedReplayer) WorkloadsAtTimestamp(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) ([]*model.WorkloadSummary, error) {
+	p, err := policyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorizeFilter(filter); err != nil {
+		return nil, err
+	}
+
+	workloads, err := r.replayer.WorkloadsAtTimestamp(ctx, effectiveAt, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := []*model.WorkloadSummary{}
+	for _, workload := range workloads {
+		if p.identity.CanReadNamespace(workload.Namespace) {
+			allowed = append(allowed, workload)
+		}
+	}
+
+	return allowed, nil
+}
+
+// policyFrom returns the policy of the identity in the context
+func policyFrom(ctx context.Context) (policy, error) {
+	identity := auth.IdentityFrom(ctx)
+	if identity == nil {
+		return policy{}, auth.ErrUnauthenticated
+	}
+
+	return policy{identity: identity}, nil
+}
+
+// NewReplayer returns a Replayer only replaying the pods in namespaces the caller may see, with the data hidden from
+// them redacted
+func NewReplayer(replayer services.Replayer) services.Replayer {
+	return &policedReplayer{replayer: replayer}
+}
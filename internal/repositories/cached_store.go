@@ -0,0 +1,225 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+// CachedStore is a Store serving trimmed node trees from memory once they've been read
+type CachedStore interface {
+	Store
+	Stats() CacheStats
+}
+
+// CacheStats counts how the lookups of a CachedStore were served
+type CacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64 // trees dropped to make room for others
+	Invalidations uint64 // trees dropped by writes to their node
+	Entries       int
+}
+
+// cacheKey identifies a node tree trimmed to a window
+type cacheKey struct {
+	nodeID  string
+	beginAt int64
+	endAt   int64
+}
+
+// cachedStore reads through to the wrapped store for node trees trimmed to a window, keeping up to a fixed number of
+// them in an LRU. Trees are keyed by their exact window, so only repeated reads of the same window hit, not windows
+// contained in a cached one. Only windows ending before now are cached since later history is still being written,
+// and every write to a node drops the trees cached for it. The IDs of the nodes are cached too, until a node is
+// upserted that isn't among them. Cached trees and IDs are shared between callers, which must not modify them.
+type cachedStore struct {
+	Store
+	cache *lru.Cache[cacheKey, *data.NodeMeta]
+
+	mu                sync.Mutex
+	generations       map[string]uint64 // nodeID -> count of writes, to not cache reads racing with a write
+	nodeIDs           map[string]bool   // nil until listed
+	nodeIDsList       []string
+	nodeIDsGeneration uint64 // count of upserts of new nodes, to not cache lists racing with one
+
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	evictions     atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+func (c *cachedStore) ListNodeIDs(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	if c.nodeIDs != nil {
+		defer c.mu.Unlock()
+		return c.nodeIDsList, nil
+	}
+	generation := c.nodeIDsGeneration
+	c.mu.Unlock()
+
+	nodeIDs, err := c.Store.ListNodeIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nodeIDsGeneration == generation {
+		c.nodeIDs = make(map[string]bool, len(nodeIDs))
+		for _, nodeID := range nodeIDs {
+			c.nodeIDs[nodeID] = true
+		}
+		c.nodeIDsList = nodeIDs
+	}
+
+	return nodeIDs, nil
+}
+
+func (c *cachedStore) GetAllBetween(ctx context.Context, beginAt, endAt time.Time) ([]*data.NodeMeta, error) {
+	nodeIDs, err := c.ListNodeIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeMetas, err := getTrees(ctx, nodeIDs, func(ctx context.Context, nodeID string) (*data.NodeMeta, error) {
+		return c.GetBetween(ctx, nodeID, beginAt, endAt)
+	})
+	if err != nil {
+		// a listed tree may have expired since, so the nodes are listed again on the next read
+		c.invalidateNodeIDs()
+		return nil, err
+	}
+
+	return nodeMetas, nil
+}
+
+func (c *cachedStore) GetBetween(ctx context.Context, nodeID string, beginAt, endAt time.Time) (*data.NodeMeta, error) {
+	if !endAt.Before(time.Now()) {
+		return c.Store.GetBetween(ctx, nodeID, beginAt, endAt)
+	}
+
+	key := cacheKey{nodeID: nodeID, beginAt: beginAt.UnixNano(), endAt: endAt.UnixNano()}
+	if nodeMeta, ok := c.cache.Get(key); ok {
+		c.hits.Add(1)
+		return nodeMeta, nil
+	}
+	c.misses.Add(1)
+
+	generation := c.generation(nodeID)
+	nodeMeta, err := c.Store.GetBetween(ctx, nodeID, beginAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generations[nodeID] == generation && c.cache.Add(key, nodeMeta) {
+		c.evictions.Add(1)
+	}
+
+	return nodeMeta, nil
+}
+
+func (c *cachedStore) Upsert(ctx context.Context, nodeMeta *data.NodeMeta) error {
+	defer c.invalidate(nodeMeta.ID)
+	defer c.addNodeID(nodeMeta.ID)
+	return c.Store.Upsert(ctx, nodeMeta)
+}
+
+func (c *cachedStore) UpsertNodeSnapshots(ctx context.Context, nodeID string, nodeSnapshots []*data.NodeSnapshot) error {
+	defer c.invalidate(nodeID)
+	return c.Store.UpsertNodeSnapshots(ctx, nodeID, nodeSnapshots)
+}
+
+func (c *cachedStore) UpsertPodMetas(ctx context.Context, nodeID string, podMetas []*data.PodMeta) error {
+	defer c.invalidate(nodeID)
+	return c.Store.UpsertPodMetas(ctx, nodeID, podMetas)
+}
+
+func (c *cachedStore) UpsertPodSnapshots(ctx context.Context, nodeID string, podID string, podSnapshots []*data.PodSnapshot) error {
+	defer c.invalidate(nodeID)
+	return c.Store.UpsertPodSnapshots(ctx, nodeID, podID, podSnapshots)
+}
+
+func (c *cachedStore) UpdateNodeMetaAttributes(ctx context.Context, nodeID string, updates map[string]interface{}) error {
+	defer c.invalidate(nodeID)
+	return c.Store.UpdateNodeMetaAttributes(ctx, nodeID, updates)
+}
+
+func (c *cachedStore) UpdatePodMetaAttributes(ctx context.Context, nodeID, podID string, updates map[string]interface{}) error {
+	defer c.invalidate(nodeID)
+	return c.Store.UpdatePodMetaAttributes(ctx, nodeID, podID, updates)
+}
+
+func (c *cachedStore) Stats() CacheStats {
+	return CacheStats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Evictions:     c.evictions.Load(),
+		Invalidations: c.invalidations.Load(),
+		Entries:       c.cache.Len(),
+	}
+}
+
+func (c *cachedStore) generation(nodeID string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generations[nodeID]
+}
+
+// invalidate drops every tree cached for the node, and keeps reads started before now from caching theirs
+func (c *cachedStore) invalidate(nodeID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generations[nodeID]++
+	for _, key := range c.cache.Keys() {
+		if key.nodeID == nodeID && c.cache.Remove(key) {
+			c.invalidations.Add(1)
+		}
+	}
+}
+
+// addNodeID drops the cached IDs of the nodes if the node isn't among them, and keeps lists started before now from
+// caching theirs
+func (c *cachedStore) addNodeID(nodeID string) {
+	c.mu.Lock()
+	known := c.nodeIDs[nodeID]
+	c.mu.Unlock()
+
+	if !known {
+		c.invalidateNodeIDs()
+	}
+}
+
+// invalidateNodeIDs drops the cached IDs of the nodes, and keeps lists started before now from caching theirs
+func (c *cachedStore) invalidateNodeIDs() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nodeIDsGeneration++
+	c.nodeIDs = nil
+	c.nodeIDsList = nil
+}
+
+// NewCachedStore returns a CachedStore reading through to the store, keeping up to maxEntries node trees
+func NewCachedStore(store Store, maxEntries int) (CachedStore, error) {
+	cache, err := lru.New[cacheKey, *data.NodeMeta](maxEntries)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cache: %w", err)
+	}
+
+	return &cachedStore{
+		Store:       store,
+		cache:       cache,
+		generations: map[string]uint64{},
+	}, nil
+}
@@ -0,0 +1,99 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+)
+
+// countingStore counts the node trees read from the wrapped store, and the times its nodes were listed
+type countingStore struct {
+	repositories.Store
+	reads int
+	lists int
+}
+
+func (c *countingStore) ListNodeIDs(ctx context.Context) ([]string, error) {
+	c.lists++
+	return c.Store.ListNodeIDs(ctx)
+}
+
+func (c *countingStore) GetBetween(ctx context.Context, nodeID string, beginAt, endAt time.Time) (*data.NodeMeta, error) {
+	c.reads++
+	return c.Store.GetBetween(ctx, nodeID, beginAt, endAt)
+}
+
+func TestCachedStore(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	ctx := context.Background()
+
+	inner := &countingStore{Store: repositories.NewMemoryStore()}
+	for _, nodeID := range []string{"node-a", "node-b"} {
+		err := inner.Upsert(ctx, &data.NodeMeta{ID: nodeID, Snapshots: data.NodeSnapshots{{Timestamp: t0}}})
+		g.Expect(err).To(gomega.BeNil())
+	}
+
+	store, err := repositories.NewCachedStore(inner, 3)
+	g.Expect(err).To(gomega.BeNil())
+
+	nodes, err := store.GetAllBetween(ctx, t0, t0.Add(time.Hour))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(nodes).To(gomega.HaveLen(2))
+	g.Expect(inner.reads).To(gomega.Equal(2))
+
+	nodes, err = store.GetAllBetween(ctx, t0, t0.Add(time.Hour))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(nodes).To(gomega.HaveLen(2))
+	g.Expect(inner.reads).To(gomega.Equal(2))
+	g.Expect(store.Stats()).To(gomega.Equal(repositories.CacheStats{Hits: 2, Misses: 2, Entries: 2}))
+	g.Expect(inner.lists).To(gomega.Equal(1))
+
+	// a write to node-a drops only its trees
+	err = store.UpsertNodeSnapshots(ctx, "node-a", []*data.NodeSnapshot{{Timestamp: t0.Add(time.Minute)}})
+	g.Expect(err).To(gomega.BeNil())
+
+	nodeMeta, err := store.GetBetween(ctx, "node-a", t0, t0.Add(time.Hour))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(nodeMeta.Snapshots).To(gomega.HaveLen(2))
+	_, err = store.GetBetween(ctx, "node-b", t0, t0.Add(time.Hour))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(inner.reads).To(gomega.Equal(3))
+	g.Expect(store.Stats().Invalidations).To(gomega.BeEquivalentTo(1))
+
+	// the least recently used tree makes room for others
+	_, err = store.GetBetween(ctx, "node-b", t0, t0.Add(2*time.Hour))
+	g.Expect(err).To(gomega.BeNil())
+	_, err = store.GetBetween(ctx, "node-b", t0, t0.Add(3*time.Hour))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(store.Stats().Evictions).To(gomega.BeEquivalentTo(1))
+	g.Expect(store.Stats().Entries).To(gomega.Equal(3))
+
+	// history up to now may still change, so it isn't cached
+	reads := inner.reads
+	for i := 0; i < 2; i++ {
+		_, err = store.GetBetween(ctx, "node-b", t0, time.Now().Add(time.Hour))
+		g.Expect(err).To(gomega.BeNil())
+	}
+	g.Expect(inner.reads).To(gomega.Equal(reads + 2))
+
+	// the nodes are only listed again once a new one is upserted
+	err = store.Upsert(ctx, &data.NodeMeta{ID: "node-b", Snapshots: data.NodeSnapshots{{Timestamp: t0}}})
+	g.Expect(err).To(gomega.BeNil())
+	_, err = store.GetAllBetween(ctx, t0, t0.Add(time.Hour))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(inner.lists).To(gomega.Equal(1))
+
+	err = store.Upsert(ctx, &data.NodeMeta{ID: "node-c", Snapshots: data.NodeSnapshots{{Timestamp: t0}}})
+	g.Expect(err).To(gomega.BeNil())
+	nodes, err = store.GetAllBetween(ctx, t0, t0.Add(time.Hour))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(nodes).To(gomega.HaveLen(3))
+	g.Expect(inner.lists).To(gomega.Equal(2))
+}
@@ -16,33 +16,82 @@ import (
 
 const ttl = time.Hour * 24 * 90 // 90 days TTL
 
+// PodIndex is the global secondary index over pod metas keyed by namespace/name, used to follow a pod across nodes
+// and recreations. Only pod metas with a PodKey are in it, which setup.go sets on those written before it existed.
+var PodIndex = dynamo.Index{
+	Name:           "PodIndex",
+	HashKey:        "PodKey",
+	HashKeyType:    dynamo.StringType,
+	RangeKey:       "TreePath",
+	RangeKeyType:   dynamo.StringType,
+	ProjectionType: dynamo.AllProjection,
+}
+
 type treeStore struct {
 	table dynamo.Table
 }
 
-func (t *treeStore) GetAll(ctx context.Context) ([]*data.NodeMeta, error) {
-	var items []dynamo.Item
-	err := t.table.Scan().Filter("TreePath = ?", "root").All(ctx, &items)
-	if err != nil {
-		return nil, err
-	}
+// Ping checks the table can be reached
+func (t *treeStore) Ping(ctx context.Context) error {
+	_, err := t.table.Describe().Run(ctx)
+	return err
+}
 
-	var nodeMetas []*data.NodeMeta
-	for _, item := range items {
+// ListNodeIDs returns the IDs of all node trees in the table, paging through the scan
+func (t *treeStore) ListNodeIDs(ctx context.Context) ([]string, error) {
+	iter := t.table.Scan().Filter("TreePath = ?", "root").Project("ID").Iter()
+
+	var nodeIDs []string
+	var item dynamo.Item
+	for iter.Next(ctx, &item) {
 		v, ok := item["ID"].(*types.AttributeValueMemberS)
 		if !ok {
 			return nil, errors.New("failed to cast item ID attribute to string")
 		}
 
-		nodeMeta, err := t.Get(ctx, v.Value)
-		if err != nil {
-			return nil, err
-		}
+		nodeIDs = append(nodeIDs, v.Value)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return nodeIDs, nil
+}
 
-		nodeMetas = append(nodeMetas, nodeMeta)
+func (t *treeStore) GetAll(ctx context.Context) ([]*data.NodeMeta, error) {
+	nodeIDs, err := t.ListNodeIDs(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return nodeMetas, nil
+	return getTrees(ctx, nodeIDs, t.Get)
+}
+
+// GetAllBetween returns the trees of all nodes, trimmed to the history needed to replay between beginAt and endAt
+func (t *treeStore) GetAllBetween(ctx context.Context, beginAt, endAt time.Time) ([]*data.NodeMeta, error) {
+	nodeIDs, err := t.ListNodeIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return getTrees(ctx, nodeIDs, func(ctx context.Context, nodeID string) (*data.NodeMeta, error) {
+		return t.GetBetween(ctx, nodeID, beginAt, endAt)
+	})
+}
+
+// GetBetween returns the tree of the node trimmed to the history needed to replay between beginAt and endAt, or nil
+// if the node has nothing to replay then. Timestamps aren't part of any key, so the whole tree is read and trimmed.
+func (t *treeStore) GetBetween(ctx context.Context, nodeID string, beginAt, endAt time.Time) (*data.NodeMeta, error) {
+	nodeMeta, err := t.Get(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !nodeMeta.TrimTo(beginAt, endAt) {
+		return nil, nil
+	}
+
+	return nodeMeta, nil
 }
 
 func (t *treeStore) Get(ctx context.Context, nodeID string) (*data.NodeMeta, error) {
@@ -99,7 +148,7 @@ func (t *treeStore) Get(ctx context.Context, nodeID string) (*data.NodeMeta, err
 		return nil, errors.New("failed to find items to build node meta aka. tree root")
 	}
 
-	nodeMeta.Snapshots = nodeSnapshots
+	nodeMeta.Snapshots = data.NodeSnapshots(nodeSnapshots).Sorted()
 
 	for _, podSnapshot := range podSnapshots {
 		for i, podMeta := range podMetas {
@@ -110,11 +159,38 @@ func (t *treeStore) Get(ctx context.Context, nodeID string) (*data.NodeMeta, err
 		}
 	}
 
+	for _, podMeta := range podMetas {
+		podMeta.Snapshots = podMeta.Snapshots.Sorted()
+	}
+
 	nodeMeta.Pods = podMetas
 
 	return nodeMeta, nil
 }
 
+// GetPodMetasByName returns every pod meta, with its snapshots, that had the namespace and name across all nodes
+func (t *treeStore) GetPodMetasByName(ctx context.Context, namespace, name string) ([]*data.PodMeta, error) {
+	var podMetas []*data.PodMeta
+	err := t.table.Get("PodKey", data.PodKey(namespace, name)).Index(PodIndex.Name).All(ctx, &podMetas)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, podMeta := range podMetas {
+		var podSnapshots []*data.PodSnapshot
+		err := t.table.Get("TreeID", podMeta.NodeID()).Index("TreeIndex").
+			Range("TreePath", dynamo.Equal, fmt.Sprintf("%s#%s", podMeta.NodeID(), podMeta.ID)).
+			All(ctx, &podSnapshots)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pod snapshots of pod %s: %w", podMeta.ID, err)
+		}
+
+		podMeta.Snapshots = data.PodSnapshots(podSnapshots).Sorted()
+	}
+
+	return podMetas, nil
+}
+
 func (t *treeStore) Upsert(ctx context.Context, nodeMeta *data.NodeMeta) error {
 	if len(nodeMeta.ID) == 0 {
 		return errors.New("unable to upsert NodeMeta vertex since node ID is empty")
@@ -122,7 +198,7 @@ func (t *treeStore) Upsert(ctx context.Context, nodeMeta *data.NodeMeta) error {
 	nodeMeta.ExpireAt = time.Now().Add(ttl)
 
 	nodeMeta.SetDynamoAttributes()
-	err := t.table.Put(nodeMeta).Run(ctx)
+	err := t.putNodeMeta(ctx, nodeMeta)
 	if err != nil {
 		return fmt.Errorf("error when upserting NodeMeta vertex: %w", err)
 	}
@@ -140,6 +216,27 @@ func (t *treeStore) Upsert(ctx context.Context, nodeMeta *data.NodeMeta) error {
 	return nil
 }
 
+// putNodeMeta puts the node meta, keeping the deletion recorded for the node if it carries none
+func (t *treeStore) putNodeMeta(ctx context.Context, nodeMeta *data.NodeMeta) error {
+	if !nodeMeta.DeletedAt.IsZero() {
+		return t.table.Put(nodeMeta).Run(ctx)
+	}
+
+	err := t.table.Put(nodeMeta).If("attribute_not_exists(DeletedAt)").Run(ctx)
+	if !dynamo.IsCondCheckFailed(err) {
+		return err
+	}
+
+	var stored data.NodeMeta
+	err = t.table.Get("ID", nodeMeta.ID).Range("TreePath", dynamo.Equal, nodeMeta.TreePath).Project("DeletedAt").One(ctx, &stored)
+	if err != nil {
+		return fmt.Errorf("failed to get deletion of node %s: %w", nodeMeta.ID, err)
+	}
+	nodeMeta.DeletedAt = stored.DeletedAt
+
+	return t.table.Put(nodeMeta).Run(ctx)
+}
+
 func (t *treeStore) UpsertNodeSnapshots(ctx context.Context, nodeID string, nodeSnapshots []*data.NodeSnapshot) error {
 	batchSize := len(nodeSnapshots)
 	items := make([]interface{}, batchSize)
@@ -168,39 +265,51 @@ func (t *treeStore) UpsertNodeSnapshots(ctx context.Context, nodeID string, node
 	return nil
 }
 
+// UpsertPodMetas puts the pod metas one at a time rather than in batches, since keeping recorded deletions takes a
+// conditional put
 func (t *treeStore) UpsertPodMetas(ctx context.Context, nodeID string, podMetas []*data.PodMeta) error {
-	batchSize := len(podMetas)
-	items := make([]interface{}, batchSize)
-
-	for i, podMeta := range podMetas {
+	for _, podMeta := range podMetas {
 		if len(podMeta.ID) == 0 {
 			return errors.New("unable to upsert PodMetas vertex since pod meta ID is empty")
 		}
 		podMeta.ExpireAt = time.Now().Add(ttl)
 
 		podMeta.SetDynamoAttributes(nodeID)
-		items[i] = podMeta
-
 		err := t.UpsertPodSnapshots(ctx, nodeID, podMeta.ID, podMeta.Snapshots)
 		if err != nil {
 			return err
 		}
-	}
 
-	if len(items) > 0 {
-		wc, err := t.table.Batch().Write().Put(items...).Run(ctx)
+		err = t.putPodMeta(ctx, podMeta)
 		if err != nil {
-			return fmt.Errorf("error when upserting PodMeta vertexes: %w", err)
-		}
-
-		if wc != batchSize {
-			return fmt.Errorf("while upsetting pod meta vertexes: persisted count (%v) != length of pod metas (%v)", wc, batchSize)
+			return fmt.Errorf("error when upserting PodMeta vertex: %w", err)
 		}
 	}
 
 	return nil
 }
 
+// putPodMeta puts the pod meta, keeping the deletion recorded for the pod if it carries none
+func (t *treeStore) putPodMeta(ctx context.Context, podMeta *data.PodMeta) error {
+	if !podMeta.DeletedAt.IsZero() {
+		return t.table.Put(podMeta).Run(ctx)
+	}
+
+	err := t.table.Put(podMeta).If("attribute_not_exists(DeletedAt)").Run(ctx)
+	if !dynamo.IsCondCheckFailed(err) {
+		return err
+	}
+
+	var stored data.PodMeta
+	err = t.table.Get("ID", podMeta.ID).Range("TreePath", dynamo.Equal, podMeta.TreePath).Project("DeletedAt", "DeletedBy").One(ctx, &stored)
+	if err != nil {
+		return fmt.Errorf("failed to get deletion of pod %s: %w", podMeta.ID, err)
+	}
+	podMeta.DeletedAt, podMeta.DeletedBy = stored.DeletedAt, stored.DeletedBy
+
+	return t.table.Put(podMeta).Run(ctx)
+}
+
 func (t *treeStore) UpsertPodSnapshots(ctx context.Context, nodeID string, podID string, podSnapshots []*data.PodSnapshot) error {
 	batchSize := len(podSnapshots)
 	items := make([]interface{}, batchSize)
@@ -229,24 +338,37 @@ func (t *treeStore) UpsertPodSnapshots(ctx context.Context, nodeID string, podID
 	return nil
 }
 
+// UpdateNodeMetaAttributes sets attributes of the node meta, returning ErrNotFound if the node was never recorded
 func (t *treeStore) UpdateNodeMetaAttributes(ctx context.Context, nodeID string, updates map[string]interface{}) error {
-	update := t.table.Update("ID", nodeID).Range("TreePath", "root")
+	update := t.table.Update("ID", nodeID).Range("TreePath", "root").If("attribute_exists(ID)")
 
 	for k, v := range updates {
 		update = update.Set(k, v)
 	}
 
-	return update.Run(ctx)
+	err := update.Run(ctx)
+	if dynamo.IsCondCheckFailed(err) {
+		return fmt.Errorf("%w: node %s", ErrNotFound, nodeID)
+	}
+
+	return err
 }
 
+// UpdatePodMetaAttributes sets attributes of the pod meta, returning ErrNotFound if the pod was never recorded on
+// the node
 func (t *treeStore) UpdatePodMetaAttributes(ctx context.Context, nodeID, podID string, updates map[string]interface{}) error {
-	update := t.table.Update("ID", podID).Range("TreePath", nodeID)
+	update := t.table.Update("ID", podID).Range("TreePath", nodeID).If("attribute_exists(ID)")
 
 	for k, v := range updates {
 		update = update.Set(k, v)
 	}
 
-	return update.Run(ctx)
+	err := update.Run(ctx)
+	if dynamo.IsCondCheckFailed(err) {
+		return fmt.Errorf("%w: pod %s on node %s", ErrNotFound, podID, nodeID)
+	}
+
+	return err
 }
 
 func NewStore(cfg aws.Config, table string) Store {
@@ -166,6 +166,17 @@ func TestDynamodbStore(t *testing.T) {
 	g.Expect(len(nodeMeta.Pods[0].Snapshots)).Should(gomega.BeEquivalentTo(1))
 	g.Expect(len(nodeMeta.Pods[0].Snapshots[0].Containers)).Should(gomega.BeEquivalentTo(2))
 
+	// the latest snapshots before the window are kept, as the state in effect when it opens
+	between, err := k8sStore.GetBetween(context.Background(), nodeMeta.ID, nodeSnap.Add(48*time.Hour), nodeSnap.Add(49*time.Hour))
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(between.Snapshots).Should(gomega.Equal(nodeMeta.Snapshots))
+	g.Expect(between.Pods).Should(gomega.HaveLen(1))
+	g.Expect(between.Pods[0].Snapshots).Should(gomega.Equal(nodeMeta.Pods[0].Snapshots))
+
+	between, err = k8sStore.GetBetween(context.Background(), nodeMeta.ID, nodeSnap.Add(-2*time.Hour), nodeSnap.Add(-time.Hour))
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(between).Should(gomega.BeNil())
+
 	nodeMetas, err := k8sStore.GetAll(context.Background())
 	g.Expect(err).Should(gomega.BeNil())
 	g.Expect(len(nodeMetas)).Should(gomega.BeEquivalentTo(1))
@@ -0,0 +1,4 @@
+package repositories
+
+// GetTrees exposes getTrees to the tests of the package
+var GetTrees = getTrees
@@ -0,0 +1,340 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+// memoryStore keeps node trees in memory, mirroring the item layout of the DynamoDB table. Every read returns copies
+// so callers can't mutate what's stored, the same as they can't with items read back from DynamoDB.
+type memoryStore struct {
+	mu           sync.RWMutex
+	nodeMetas    map[string]*data.NodeMeta
+	nodeSnaps    map[string]map[string]*data.NodeSnapshot           // nodeID -> snapshotID
+	podMetas     map[string]map[string]*data.PodMeta                // nodeID -> podID
+	podSnapshots map[string]map[string]map[string]*data.PodSnapshot // nodeID -> podID -> snapshotID
+}
+
+func (m *memoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *memoryStore) ListNodeIDs(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	nodeIDs := make([]string, 0, len(m.nodeMetas))
+	for nodeID := range m.nodeMetas {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+
+	return nodeIDs, nil
+}
+
+func (m *memoryStore) GetAll(ctx context.Context) ([]*data.NodeMeta, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var nodeMetas []*data.NodeMeta
+	for nodeID := range m.nodeMetas {
+		nodeMeta, err := m.get(nodeID)
+		if err != nil {
+			return nil, err
+		}
+
+		nodeMetas = append(nodeMetas, nodeMeta)
+	}
+
+	return nodeMetas, nil
+}
+
+func (m *memoryStore) Get(ctx context.Context, nodeID string) (*data.NodeMeta, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.get(nodeID)
+}
+
+func (m *memoryStore) GetAllBetween(ctx context.Context, beginAt, endAt time.Time) ([]*data.NodeMeta, error) {
+	nodeMetas, err := m.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var trimmed []*data.NodeMeta
+	for _, nodeMeta := range nodeMetas {
+		if nodeMeta.TrimTo(beginAt, endAt) {
+			trimmed = append(trimmed, nodeMeta)
+		}
+	}
+
+	return trimmed, nil
+}
+
+func (m *memoryStore) GetBetween(ctx context.Context, nodeID string, beginAt, endAt time.Time) (*data.NodeMeta, error) {
+	nodeMeta, err := m.Get(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !nodeMeta.TrimTo(beginAt, endAt) {
+		return nil, nil
+	}
+
+	return nodeMeta, nil
+}
+
+func (m *memoryStore) get(nodeID string) (*data.NodeMeta, error) {
+	stored, ok := m.nodeMetas[nodeID]
+	if !ok {
+		return nil, errors.New("failed to find items to build node meta aka. tree root")
+	}
+
+	var nodeMeta *data.NodeMeta
+	if err := clone(stored, &nodeMeta); err != nil {
+		return nil, err
+	}
+
+	nodeMeta.Snapshots = nil
+	for _, stored := range m.nodeSnaps[nodeID] {
+		var nodeSnapshot *data.NodeSnapshot
+		if err := clone(stored, &nodeSnapshot); err != nil {
+			return nil, err
+		}
+
+		nodeMeta.Snapshots = append(nodeMeta.Snapshots, nodeSnapshot)
+	}
+	nodeMeta.Snapshots = nodeMeta.Snapshots.Sorted()
+
+	nodeMeta.Pods = nil
+	for podID := range m.podMetas[nodeID] {
+		podMeta, err := m.getPodMeta(nodeID, podID)
+		if err != nil {
+			return nil, err
+		}
+
+		nodeMeta.Pods = append(nodeMeta.Pods, podMeta)
+	}
+
+	return nodeMeta, nil
+}
+
+func (m *memoryStore) getPodMeta(nodeID, podID string) (*data.PodMeta, error) {
+	var podMeta *data.PodMeta
+	if err := clone(m.podMetas[nodeID][podID], &podMeta); err != nil {
+		return nil, err
+	}
+
+	podMeta.Snapshots = nil
+	for _, stored := range m.podSnapshots[nodeID][podID] {
+		var podSnapshot *data.PodSnapshot
+		if err := clone(stored, &podSnapshot); err != nil {
+			return nil, err
+		}
+
+		podMeta.Snapshots = append(podMeta.Snapshots, podSnapshot)
+	}
+	podMeta.Snapshots = podMeta.Snapshots.Sorted()
+
+	return podMeta, nil
+}
+
+func (m *memoryStore) GetPodMetasByName(ctx context.Context, namespace, name string) ([]*data.PodMeta, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var podMetas []*data.PodMeta
+	for nodeID, pods := range m.podMetas {
+		for podID, pod := range pods {
+			if pod.PodKey != data.PodKey(namespace, name) {
+				continue
+			}
+
+			podMeta, err := m.getPodMeta(nodeID, podID)
+			if err != nil {
+				return nil, err
+			}
+
+			podMetas = append(podMetas, podMeta)
+		}
+	}
+
+	return podMetas, nil
+}
+
+func (m *memoryStore) Upsert(ctx context.Context, nodeMeta *data.NodeMeta) error {
+	if len(nodeMeta.ID) == 0 {
+		return errors.New("unable to upsert NodeMeta vertex since node ID is empty")
+	}
+
+	nodeMeta.SetDynamoAttributes()
+	var stored *data.NodeMeta
+	if err := clone(nodeMeta, &stored); err != nil {
+		return err
+	}
+	stored.Snapshots, stored.Pods = nil, nil
+
+	m.mu.Lock()
+	if existing, ok := m.nodeMetas[nodeMeta.ID]; ok && stored.DeletedAt.IsZero() {
+		stored.DeletedAt = existing.DeletedAt
+	}
+	m.nodeMetas[nodeMeta.ID] = stored
+	m.mu.Unlock()
+
+	if err := m.UpsertNodeSnapshots(ctx, nodeMeta.ID, nodeMeta.Snapshots); err != nil {
+		return err
+	}
+
+	return m.UpsertPodMetas(ctx, nodeMeta.ID, nodeMeta.Pods)
+}
+
+func (m *memoryStore) UpsertNodeSnapshots(ctx context.Context, nodeID string, nodeSnapshots []*data.NodeSnapshot) error {
+	for _, nodeSnapshot := range nodeSnapshots {
+		if nodeSnapshot.Timestamp.IsZero() {
+			return errors.New("unable to upsert NodeSnapshot vertex since timestamp is zero")
+		}
+
+		nodeSnapshot.SetDynamoAttributes(nodeID)
+		var stored *data.NodeSnapshot
+		if err := clone(nodeSnapshot, &stored); err != nil {
+			return err
+		}
+
+		m.mu.Lock()
+		if m.nodeSnaps[nodeID] == nil {
+			m.nodeSnaps[nodeID] = map[string]*data.NodeSnapshot{}
+		}
+		m.nodeSnaps[nodeID][stored.ID] = stored
+		m.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (m *memoryStore) UpsertPodMetas(ctx context.Context, nodeID string, podMetas []*data.PodMeta) error {
+	for _, podMeta := range podMetas {
+		if len(podMeta.ID) == 0 {
+			return errors.New("unable to upsert PodMetas vertex since pod meta ID is empty")
+		}
+
+		podMeta.SetDynamoAttributes(nodeID)
+		var stored *data.PodMeta
+		if err := clone(podMeta, &stored); err != nil {
+			return err
+		}
+		stored.Snapshots = nil
+
+		m.mu.Lock()
+		if m.podMetas[nodeID] == nil {
+			m.podMetas[nodeID] = map[string]*data.PodMeta{}
+		}
+		if existing, ok := m.podMetas[nodeID][stored.ID]; ok && stored.DeletedAt.IsZero() {
+			stored.DeletedAt, stored.DeletedBy = existing.DeletedAt, existing.DeletedBy
+		}
+		m.podMetas[nodeID][stored.ID] = stored
+		m.mu.Unlock()
+
+		if err := m.UpsertPodSnapshots(ctx, nodeID, podMeta.ID, podMeta.Snapshots); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryStore) UpsertPodSnapshots(ctx context.Context, nodeID string, podID string, podSnapshots []*data.PodSnapshot) error {
+	for _, podSnapshot := range podSnapshots {
+		if podSnapshot.Timestamp.IsZero() {
+			return errors.New("unable to upsert PodSnapshot vertex since timestamp is zero")
+		}
+
+		podSnapshot.SetDynamoAttributes(nodeID, podID)
+		var stored *data.PodSnapshot
+		if err := clone(podSnapshot, &stored); err != nil {
+			return err
+		}
+
+		m.mu.Lock()
+		if m.podSnapshots[nodeID] == nil {
+			m.podSnapshots[nodeID] = map[string]map[string]*data.PodSnapshot{}
+		}
+		if m.podSnapshots[nodeID][podID] == nil {
+			m.podSnapshots[nodeID][podID] = map[string]*data.PodSnapshot{}
+		}
+		m.podSnapshots[nodeID][podID][stored.ID] = stored
+		m.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (m *memoryStore) UpdateNodeMetaAttributes(ctx context.Context, nodeID string, updates map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	nodeMeta, ok := m.nodeMetas[nodeID]
+	if !ok {
+		return fmt.Errorf("%w: node %s", ErrNotFound, nodeID)
+	}
+
+	return setAttributes(nodeMeta, updates)
+}
+
+func (m *memoryStore) UpdatePodMetaAttributes(ctx context.Context, nodeID, podID string, updates map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	podMeta, ok := m.podMetas[nodeID][podID]
+	if !ok {
+		return fmt.Errorf("%w: pod %s on node %s", ErrNotFound, podID, nodeID)
+	}
+
+	return setAttributes(podMeta, updates)
+}
+
+// clone deep copies from into the pointer to
+func clone(from, to interface{}) error {
+	b, err := json.Marshal(from)
+	if err != nil {
+		return fmt.Errorf("failed to copy item: %w", err)
+	}
+
+	return json.Unmarshal(b, to)
+}
+
+// setAttributes sets the fields of item named by the keys of updates, like an UpdateItem on the table would
+func setAttributes(item interface{}, updates map[string]interface{}) error {
+	v := reflect.ValueOf(item).Elem()
+	for k, update := range updates {
+		field := v.FieldByName(k)
+		if !field.IsValid() || !field.CanSet() {
+			return fmt.Errorf("unable to update attribute %s", k)
+		}
+
+		value := reflect.ValueOf(update)
+		if !value.Type().ConvertibleTo(field.Type()) {
+			return fmt.Errorf("unable to update attribute %s with a %s", k, value.Type())
+		}
+
+		field.Set(value.Convert(field.Type()))
+	}
+
+	return nil
+}
+
+// NewMemoryStore returns a Store kept in memory, for tests and local runs without DynamoDB
+func NewMemoryStore() Store {
+	return &memoryStore{
+		nodeMetas:    map[string]*data.NodeMeta{},
+		nodeSnaps:    map[string]map[string]*data.NodeSnapshot{},
+		podMetas:     map[string]map[string]*data.PodMeta{},
+		podSnapshots: map[string]map[string]map[string]*data.PodSnapshot{},
+	}
+}
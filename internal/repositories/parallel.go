@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+// maxConcurrentReads bounds how many node trees are read from the table at once
+const maxConcurrentReads = 16
+
+// getTrees reads the tree of every node with up to maxConcurrentReads calls of get in flight, keeping the order of
+// nodeIDs and dropping nil trees. Every failed read is reported, joined in a single error, and no more reads are
+// started once ctx is done.
+func getTrees(ctx context.Context, nodeIDs []string, get func(ctx context.Context, nodeID string) (*data.NodeMeta, error)) ([]*data.NodeMeta, error) {
+	trees := make([]*data.NodeMeta, len(nodeIDs))
+	errs := make([]error, len(nodeIDs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentReads)
+	for i, nodeID := range nodeIDs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+		}
+		if errs[i] != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			tree, err := get(ctx, nodeID)
+			if err != nil {
+				errs[i] = fmt.Errorf("unable to get tree of node %s: %w", nodeID, err)
+				return
+			}
+			trees[i] = tree
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	nodeMetas := make([]*data.NodeMeta, 0, len(trees))
+	for _, tree := range trees {
+		if tree != nil {
+			nodeMetas = append(nodeMetas, tree)
+		}
+	}
+
+	return nodeMetas, nil
+}
@@ -0,0 +1,111 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+)
+
+// blockingReads reads trees that only return once released, recording how many reads were in flight at most
+type blockingReads struct {
+	started chan string
+	release chan struct{}
+	failing map[string]bool
+	missing map[string]bool
+
+	mu       sync.Mutex
+	inFlight int
+	maxSeen  int
+}
+
+func (b *blockingReads) get(ctx context.Context, nodeID string) (*data.NodeMeta, error) {
+	b.mu.Lock()
+	b.inFlight++
+	b.maxSeen = max(b.maxSeen, b.inFlight)
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.inFlight--
+		b.mu.Unlock()
+	}()
+
+	b.started <- nodeID
+	<-b.release
+
+	switch {
+	case b.failing[nodeID]:
+		return nil, errors.New("throttled")
+	case b.missing[nodeID]:
+		return nil, nil
+	}
+	return &data.NodeMeta{ID: nodeID}, nil
+}
+
+func TestGetTrees(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	nodeIDs := make([]string, 40)
+	for i := range nodeIDs {
+		nodeIDs[i] = fmt.Sprintf("node-%d", i)
+	}
+	reads := &blockingReads{
+		started: make(chan string, len(nodeIDs)),
+		release: make(chan struct{}),
+		missing: map[string]bool{"node-5": true},
+	}
+
+	var trees []*data.NodeMeta
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		trees, err = repositories.GetTrees(context.Background(), nodeIDs, reads.get)
+	}()
+
+	// as many reads as allowed start before any returns
+	for i := 0; i < 16; i++ {
+		<-reads.started
+	}
+	close(reads.release)
+	<-done
+
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(reads.maxSeen).To(gomega.Equal(16))
+	g.Expect(trees).To(gomega.HaveLen(39))
+	for i, tree := range trees[:5] {
+		g.Expect(tree.ID).To(gomega.Equal(nodeIDs[i]))
+	}
+	g.Expect(trees[5].ID).To(gomega.Equal("node-6"))
+}
+
+func TestGetTrees_Errors(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	nodeIDs := []string{"node-1", "node-2", "node-3"}
+	reads := &blockingReads{
+		started: make(chan string, len(nodeIDs)),
+		release: make(chan struct{}),
+		failing: map[string]bool{"node-1": true, "node-3": true},
+	}
+	close(reads.release)
+
+	// every failed read is reported
+	_, err := repositories.GetTrees(context.Background(), nodeIDs, reads.get)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("node node-1: throttled")))
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("node node-3: throttled")))
+
+	// reads stop once the context is done
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = repositories.GetTrees(cancelled, nodeIDs, func(ctx context.Context, nodeID string) (*data.NodeMeta, error) {
+		return nil, ctx.Err()
+	})
+	g.Expect(errors.Is(err, context.Canceled)).To(gomega.BeTrue())
+}
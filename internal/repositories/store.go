@@ -2,13 +2,27 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/ccpeng/kube-replay/internal/data"
 )
 
+// ErrNotFound is returned when updating a node or pod that was never recorded
+var ErrNotFound = errors.New("not found")
+
+// Store keeps node trees. Upserting a node or pod meta keeps the deletion already recorded for it, unless the meta
+// carries one.
 type Store interface {
+	// Ping checks the store can be reached. It's called by every readiness probe, so decorators pass it through
+	// without recording it.
+	Ping(ctx context.Context) error
+	ListNodeIDs(ctx context.Context) ([]string, error)
 	GetAll(ctx context.Context) ([]*data.NodeMeta, error)
 	Get(ctx context.Context, nodeID string) (*data.NodeMeta, error)
+	GetAllBetween(ctx context.Context, beginAt, endAt time.Time) ([]*data.NodeMeta, error)
+	GetBetween(ctx context.Context, nodeID string, beginAt, endAt time.Time) (*data.NodeMeta, error)
+	GetPodMetasByName(ctx context.Context, namespace, name string) ([]*data.PodMeta, error)
 	Upsert(ctx context.Context, nodeMeta *data.NodeMeta) error
 	UpsertNodeSnapshots(ctx context.Context, nodeID string, nodeSnapshots []*data.NodeSnapshot) error
 	UpsertPodMetas(ctx context.Context, nodeID string, podMetas []*data.PodMeta) error
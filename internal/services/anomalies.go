@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/anomalies"
+)
+
+var anomalyKinds = map[anomalies.Kind]model.RestartAnomalyKind{
+	anomalies.CrashLoop:       model.RestartAnomalyKindCrashLoop,
+	anomalies.RepeatedOOMKill: model.RestartAnomalyKindRepeatedOOMKill,
+	anomalies.RestartSpike:    model.RestartAnomalyKindRestartSpike,
+}
+
+// RestartAnomalies returns the containers that crash looped, were OOMKilled repeatedly or restarted in a spike between
+// beginAt and endAt, of pods in the namespace or all of them if nil, the most severe first
+func (r *replayer) RestartAnomalies(ctx context.Context, beginAt, endAt time.Time, namespace *string) ([]*model.RestartAnomaly, error) {
+	if endAt.Before(beginAt) {
+		return nil, fmt.Errorf("%w: end %s is before start %s", ErrInvalidRange, endAt.Format(time.RFC3339), beginAt.Format(time.RFC3339))
+	}
+
+	nodes, err := r.store.GetAllBetween(ctx, beginAt, endAt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get all nodes in cluster: %v", err)
+	}
+
+	restartAnomalies := []*model.RestartAnomaly{}
+	for _, anomaly := range anomalies.Detect(nodes, beginAt, endAt) {
+		if namespace != nil && anomaly.Namespace != *namespace {
+			continue
+		}
+
+		restartAnomaly := &model.RestartAnomaly{
+			PodID:       anomaly.PodID,
+			Namespace:   anomaly.Namespace,
+			Name:        anomaly.PodName,
+			Workload:    anomaly.Workload.Name,
+			Container:   anomaly.Container,
+			NodeID:      anomaly.NodeID,
+			Severity:    anomalyKinds[anomaly.Severity()],
+			Occurrences: []*model.RestartAnomalyOccurrence{},
+			Restarts:    int32(anomaly.Restarts),
+			OomKills:    int32(anomaly.OOMKills),
+		}
+		for _, kind := range anomaly.Kinds {
+			restartAnomaly.Occurrences = append(restartAnomaly.Occurrences, &model.RestartAnomalyOccurrence{
+				Kind:      anomalyKinds[kind],
+				FirstSeen: anomaly.FirstSeen[kind],
+			})
+		}
+		restartAnomalies = append(restartAnomalies, restartAnomaly)
+	}
+
+	return restartAnomalies, nil
+}
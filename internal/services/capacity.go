@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/capacity"
+)
+
+// CapacityPlan returns right-sizing recommendations for each group of nodes from the cluster at beginAt and at every
+// recorded event up to endAt
+func (r *replayer) CapacityPlan(ctx context.Context, beginAt, endAt time.Time) (*model.CapacityPlan, error) {
+	states, err := r.eventStates(ctx, beginAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &model.CapacityPlan{
+		Start:  beginAt,
+		End:    endAt,
+		Groups: []*model.NodeGroupRecommendation{},
+	}
+	for _, recommendation := range capacity.Plan(states, endAt) {
+		plan.Groups = append(plan.Groups, &model.NodeGroupRecommendation{
+			Roles:                recommendation.Roles,
+			Architecture:         recommendation.Architecture,
+			Provider:             recommendation.Provider,
+			InstanceType:         recommendation.InstanceType,
+			NodeCount:            int32(recommendation.NodeCount),
+			RecommendedNodeCount: int32(recommendation.RecommendedNodeCount),
+			NodeAllocatable:      resourceQuantities(recommendation.NodeAllocatable),
+			PeakRequested:        resourceQuantities(recommendation.PeakRequested),
+			P95Requested:         resourceQuantities(recommendation.P95Requested),
+			PeakLimits:           resourceQuantities(recommendation.PeakLimits),
+			Stranded:             resourceQuantities(recommendation.Stranded),
+		})
+	}
+
+	return plan, nil
+}
+
+func resourceQuantities(resources corev1.ResourceList) *model.ResourceQuantities {
+	return &model.ResourceQuantities{
+		CPU:    resources.Cpu().String(),
+		Memory: resources.Memory().String(),
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/cost"
+)
+
+// CostReport returns the cost of the nodes from beginAt to endAt attributed to the namespaces and pods requesting
+// them, from the cluster at beginAt and at every recorded event up to endAt
+func (r *replayer) CostReport(ctx context.Context, beginAt, endAt time.Time) (*model.CostReport, error) {
+	states, err := r.eventStates(ctx, beginAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+
+	attributed := cost.Attribute(states, endAt, r.prices)
+	report := &model.CostReport{
+		Start:                 beginAt,
+		End:                   endAt,
+		TotalCost:             attributed.Total,
+		IdleCost:              attributed.Idle,
+		UnpricedInstanceTypes: append([]string{}, attributed.Unpriced...),
+		Namespaces:            []*model.NamespaceCost{},
+	}
+
+	// pods are ordered by namespace, so each namespace's are contiguous
+	var namespace *model.NamespaceCost
+	for _, pod := range attributed.Pods {
+		if namespace == nil || namespace.Namespace != pod.Namespace {
+			namespace = &model.NamespaceCost{Namespace: pod.Namespace, Pods: []*model.PodCost{}}
+			report.Namespaces = append(report.Namespaces, namespace)
+		}
+		namespace.Cost += pod.Cost
+		namespace.Pods = append(namespace.Pods, &model.PodCost{Namespace: pod.Namespace, Name: pod.Name, Cost: pod.Cost})
+	}
+
+	return report, nil
+}
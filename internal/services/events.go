@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"time"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+// ClusterEvents returns every change to the cluster between beginAt and endAt, diffing the snapshot at each recorded
+// event against the one before, starting from the snapshot at beginAt
+func (r *replayer) ClusterEvents(ctx context.Context, beginAt, endAt time.Time, filter *model.SnapshotFilter) ([]*model.ClusterEvent, error) {
+	snapshotFilter, err := newSnapshotFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := r.eventStates(ctx, beginAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the snapshot before is kept, however many events there are
+	events := []*model.ClusterEvent{}
+	var previous *model.TimedNodeSnapshots
+	for state := range states {
+		frame := timedNodeSnapshot(state, snapshotFilter)
+		if previous != nil {
+			events = append(events, Diff(previous, frame)...)
+		}
+		previous = frame
+	}
+
+	return events, nil
+}
+
+// eventStates returns the cluster at beginAt and at every recorded event up to endAt, reconstructed as it's iterated
+// so that queries aggregating over them don't hold every state at once
+func (r *replayer) eventStates(ctx context.Context, beginAt, endAt time.Time) (iter.Seq[*data.ClusterState], error) {
+	if endAt.Before(beginAt) {
+		return nil, fmt.Errorf("%w: end %s is before start %s", ErrInvalidRange, endAt.Format(time.RFC3339), beginAt.Format(time.RFC3339))
+	}
+
+	nodes, err := r.store.GetAllBetween(ctx, beginAt, endAt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get all nodes in cluster: %v", err)
+	}
+
+	times := slices.CompactFunc(append([]time.Time{beginAt}, eventTimes(nodes, beginAt, endAt)...), time.Time.Equal)
+	if int64(len(times)) > r.maxEventFrames {
+		return nil, fmt.Errorf("%w: %d events between %s and %s exceed the maximum of %d, narrow the range",
+			ErrTooManyFrames, len(times), beginAt.Format(time.RFC3339), endAt.Format(time.RFC3339), r.maxEventFrames)
+	}
+
+	return data.SweepSeq(nodes, times), nil
+}
+
+// Diff returns the changes from one snapshot of the cluster to a later one, timestamped at the later one. Events are
+// ordered as the nodes and pods of the snapshots, with those removed last.
+func Diff(from, to *model.TimedNodeSnapshots) []*model.ClusterEvent {
+	events := []*model.ClusterEvent{}
+	event := func(kind model.ClusterEventKind, node *model.NodeSnapshot, pod *model.PodSnapshot, format string, args ...interface{}) {
+		e := &model.ClusterEvent{
+			Timestamp: to.Timestamp,
+			Kind:      kind,
+			NodeID:    node.ID,
+			NodeName:  node.Name,
+			Message:   fmt.Sprintf(format, args...),
+		}
+		if pod != nil {
+			e.PodID, e.Namespace, e.PodName = &pod.ID, pod.Namespace, &pod.Name
+		}
+		events = append(events, e)
+	}
+
+	fromNodes := map[string]*model.NodeSnapshot{}
+	fromPods := map[string]*model.PodSnapshot{}
+	for _, node := range from.Nodes {
+		fromNodes[node.ID] = node
+		for _, pod := range node.Pods {
+			fromPods[pod.ID] = pod
+		}
+	}
+
+	toNodes := map[string]bool{}
+	toPods := map[string]bool{}
+	for _, node := range to.Nodes {
+		toNodes[node.ID] = true
+
+		was, ok := fromNodes[node.ID]
+		switch {
+		case !ok:
+			event(model.ClusterEventKindNodeAdded, node, nil, "Node %s joined the cluster", node.Name)
+		case was.State.Status != node.State.Status:
+			event(model.ClusterEventKindNodeStatusChanged, node, nil, "Node %s went from %s to %s", node.Name, was.State.Status, node.State.Status)
+		}
+		if ok && unschedulable(was) != unschedulable(node) {
+			if unschedulable(node) {
+				event(model.ClusterEventKindNodeCordoned, node, nil, "Node %s was cordoned", node.Name)
+			} else {
+				event(model.ClusterEventKindNodeUncordoned, node, nil, "Node %s was uncordoned", node.Name)
+			}
+		}
+
+		for _, pod := range node.Pods {
+			toPods[pod.ID] = true
+
+			was, ok := fromPods[pod.ID]
+			if !ok {
+				event(model.ClusterEventKindPodAdded, node, pod, "Pod %s was bound to node %s as %s", podName(pod), node.Name, pod.Status)
+				continue
+			}
+
+			if was.NodeID != pod.NodeID {
+				event(model.ClusterEventKindPodMoved, node, pod, "Pod %s moved from node %s to %s", podName(pod), fromNodes[was.NodeID].Name, node.Name)
+			}
+			if was.Status != pod.Status {
+				event(model.ClusterEventKindPodPhaseChanged, node, pod, "Pod %s went from %s to %s", podName(pod), was.Status, pod.Status)
+			}
+			for _, container := range pod.Containers {
+				before, after := restartCount(was, container.Name), restartCount(pod, container.Name)
+				if after > before {
+					event(model.ClusterEventKindContainerRestarted, node, pod, "Container %s of pod %s restarted, from %d to %d restarts", container.Name, podName(pod), before, after)
+				}
+			}
+		}
+	}
+
+	for _, node := range from.Nodes {
+		for _, pod := range node.Pods {
+			if !toPods[pod.ID] {
+				event(model.ClusterEventKindPodRemoved, node, pod, "Pod %s was removed from node %s", podName(pod), node.Name)
+			}
+		}
+		if !toNodes[node.ID] {
+			event(model.ClusterEventKindNodeRemoved, node, nil, "Node %s left the cluster", node.Name)
+		}
+	}
+
+	return events
+}
+
+func unschedulable(node *model.NodeSnapshot) bool {
+	return node.State.Unschedulable != nil && *node.State.Unschedulable
+}
+
+func podName(pod *model.PodSnapshot) string {
+	if pod.Namespace == nil {
+		return pod.Name
+	}
+
+	return *pod.Namespace + "/" + pod.Name
+}
+
+func restartCount(pod *model.PodSnapshot, containerName string) int64 {
+	for _, container := range pod.Containers {
+		if container.Name == containerName && container.RestartCount != nil {
+			return *container.RestartCount
+		}
+	}
+
+	return 0
+}
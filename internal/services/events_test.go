@@ -0,0 +1,93 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+func TestReplayer_ClusterEvents(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	ctx := context.Background()
+	store := repositories.NewMemoryStore()
+
+	// web-0 starts on node-a and crashes once, node-b joins, then node-a is cordoned and web-0 moves to node-b
+	err := store.Upsert(ctx, &data.NodeMeta{
+		ID:   "node-a",
+		Name: "a",
+		Snapshots: data.NodeSnapshots{
+			{Timestamp: t0},
+			{Timestamp: t0.Add(4 * time.Minute), State: data.NodeState{Unschedulable: true}},
+		},
+		Pods: []*data.PodMeta{{
+			ID: "uid-1", Name: "web-0", Namespace: "shop", DeletedAt: t0.Add(5 * time.Minute),
+			Snapshots: data.PodSnapshots{
+				{Timestamp: t0, Status: data.PodPhasePending},
+				{Timestamp: t0.Add(time.Minute), Status: data.PodPhaseRunning, Containers: []*data.ContainerSnapshot{{Name: "web"}}},
+				{Timestamp: t0.Add(2 * time.Minute), Status: data.PodPhaseRunning, Containers: []*data.ContainerSnapshot{{Name: "web", RestartCount: 1}}},
+			},
+		}},
+	})
+	g.Expect(err).To(gomega.BeNil())
+	err = store.Upsert(ctx, &data.NodeMeta{
+		ID:        "node-b",
+		Name:      "b",
+		Snapshots: data.NodeSnapshots{{Timestamp: t0.Add(3 * time.Minute)}},
+		Pods: []*data.PodMeta{{
+			ID: "uid-2", Name: "web-0", Namespace: "shop",
+			Snapshots: data.PodSnapshots{{Timestamp: t0.Add(5 * time.Minute), Status: data.PodPhaseRunning}},
+		}},
+	})
+	g.Expect(err).To(gomega.BeNil())
+
+	replayer := services.NewReplayerWithStore(store)
+	events, err := replayer.ClusterEvents(ctx, t0, t0.Add(time.Hour), nil)
+	g.Expect(err).To(gomega.BeNil())
+
+	var kinds []model.ClusterEventKind
+	for _, event := range events {
+		kinds = append(kinds, event.Kind)
+	}
+	g.Expect(kinds).To(gomega.Equal([]model.ClusterEventKind{
+		model.ClusterEventKindPodPhaseChanged,
+		model.ClusterEventKindContainerRestarted,
+		model.ClusterEventKindNodeAdded,
+		model.ClusterEventKindNodeCordoned,
+		model.ClusterEventKindPodAdded,
+		model.ClusterEventKindPodRemoved,
+	}))
+	g.Expect(events[0].Timestamp).To(gomega.Equal(t0.Add(time.Minute)))
+	g.Expect(events[0].Message).To(gomega.Equal("Pod shop/web-0 went from Pending to Running"))
+	g.Expect(events[1].Message).To(gomega.Equal("Container web of pod shop/web-0 restarted, from 0 to 1 restarts"))
+	g.Expect(*events[5].PodID).To(gomega.Equal("uid-1"))
+	g.Expect(events[5].NodeName).To(gomega.Equal("a"))
+
+	// events are relative to the snapshot at the start
+	events, err = replayer.ClusterEvents(ctx, t0.Add(3*time.Minute), t0.Add(time.Hour), &model.SnapshotFilter{
+		Nodes: &model.NodeFilter{Names: []string{"a"}},
+	})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(events).To(gomega.HaveLen(2))
+	g.Expect(events[0].Kind).To(gomega.Equal(model.ClusterEventKindNodeCordoned))
+	g.Expect(events[1].Kind).To(gomega.Equal(model.ClusterEventKindPodRemoved))
+
+	_, err = replayer.ClusterEvents(ctx, t0.Add(time.Hour), t0, nil)
+	g.Expect(err).To(gomega.MatchError(services.ErrInvalidRange))
+
+	// queries over events are bounded by their own limit, not that of range replays
+	events, err = services.NewReplayerWithStore(store, services.WithMaxFrames(2), services.WithMaxEventFrames(6)).
+		ClusterEvents(ctx, t0, t0.Add(time.Hour), nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(events).To(gomega.HaveLen(6))
+	_, err = services.NewReplayerWithStore(store, services.WithMaxEventFrames(5)).ClusterEvents(ctx, t0, t0.Add(time.Hour), nil)
+	g.Expect(err).To(gomega.MatchError(services.ErrTooManyFrames))
+}
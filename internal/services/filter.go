@@ -0,0 +1,148 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/utils"
+)
+
+// snapshotFilter is a model.SnapshotFilter with its label selector parsed, evaluated against replayed state
+type snapshotFilter struct {
+	nodes       *model.NodeFilter
+	pods        *model.PodFilter
+	selector    labels.Selector
+	podSelector labels.Selector
+}
+
+func newSnapshotFilter(filter *model.SnapshotFilter) (*snapshotFilter, error) {
+	f := &snapshotFilter{}
+	if filter == nil {
+		return f, nil
+	}
+
+	f.nodes, f.pods = filter.Nodes, filter.Pods
+	if f.nodes != nil && f.nodes.LabelSelector != nil {
+		selector, err := labels.Parse(*f.nodes.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node label selector: %v", err)
+		}
+
+		f.selector = selector
+	}
+	if f.pods != nil && f.pods.LabelSelector != nil {
+		selector, err := labels.Parse(*f.pods.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pod label selector: %v", err)
+		}
+
+		f.podSelector = selector
+	}
+
+	return f, nil
+}
+
+// apply returns the state with only the nodes and pods matching the filter
+func (f *snapshotFilter) apply(state *data.ClusterState) *data.ClusterState {
+	if f.nodes == nil && f.pods == nil {
+		return state
+	}
+
+	filtered := &data.ClusterState{
+		Timestamp: state.Timestamp,
+	}
+	for _, node := range state.Nodes {
+		if !f.matchesNode(node) {
+			continue
+		}
+
+		nodeAt := &data.NodeAt{
+			Meta:     node.Meta,
+			Snapshot: node.Snapshot,
+			Pods:     []*data.PodAt{},
+		}
+		for _, pod := range node.Pods {
+			if f.matchesPod(pod) {
+				nodeAt.Pods = append(nodeAt.Pods, pod)
+			}
+		}
+
+		filtered.Nodes = append(filtered.Nodes, nodeAt)
+	}
+
+	return filtered
+}
+
+func (f *snapshotFilter) matchesNode(node *data.NodeAt) bool {
+	if f.nodes == nil {
+		return true
+	}
+
+	if len(f.nodes.Names) > 0 && !slices.Contains(f.nodes.Names, node.Meta.Name) {
+		return false
+	}
+
+	if len(f.nodes.Roles) > 0 && !slices.ContainsFunc(node.Meta.Roles, func(role string) bool {
+		return slices.Contains(f.nodes.Roles, role)
+	}) {
+		return false
+	}
+
+	if f.selector != nil && !f.selector.Matches(labels.Set(node.Meta.Labels)) {
+		return false
+	}
+
+	return true
+}
+
+func (f *snapshotFilter) matchesPod(pod *data.PodAt) bool {
+	if f.pods == nil {
+		return true
+	}
+
+	if len(f.pods.Namespaces) > 0 && !slices.Contains(f.pods.Namespaces, pod.Meta.Namespace) {
+		return false
+	}
+
+	if len(f.pods.Phases) > 0 && !slices.Contains(f.pods.Phases, utils.TransformToModelPodPhase(pod.Snapshot.Status)) {
+		return false
+	}
+
+	if len(f.pods.QosClasses) > 0 && !slices.Contains(f.pods.QosClasses, utils.TransformToModelPodQOSClass(pod.Meta.QOSClass)) {
+		return false
+	}
+
+	if f.pods.Image != nil && !anyContainer(pod.Snapshot, func(container *data.ContainerSnapshot) bool {
+		return strings.Contains(container.Image, *f.pods.Image)
+	}) {
+		return false
+	}
+
+	if f.podSelector != nil && !f.podSelector.Matches(labels.Set(pod.Meta.Labels)) {
+		return false
+	}
+
+	if f.pods.MinRestartCount != nil && !anyContainer(pod.Snapshot, func(container *data.ContainerSnapshot) bool {
+		return container.RestartCount >= *f.pods.MinRestartCount
+	}) {
+		return false
+	}
+
+	return true
+}
+
+// anyContainer reports whether any init, ephemeral or regular container of the pod satisfies the predicate
+func anyContainer(pod *data.PodSnapshot, predicate func(container *data.ContainerSnapshot) bool) bool {
+	for _, containers := range [][]*data.ContainerSnapshot{pod.InitContainers, pod.EphemeralContainers, pod.Containers} {
+		if slices.ContainsFunc(containers, predicate) {
+			return true
+		}
+	}
+
+	return false
+}
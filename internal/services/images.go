@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/images"
+)
+
+// ImagesAtTimestamp returns the distinct images run by pods at effectiveAt that hadn't terminated, with the pods
+// running each
+func (r *replayer) ImagesAtTimestamp(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) ([]*model.ContainerImage, error) {
+	snapshotFilter, err := newSnapshotFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := r.store.GetAllBetween(ctx, effectiveAt, effectiveAt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get all nodes in cluster: %v", err)
+	}
+
+	// uses are ordered by image and image ID, so each image's are contiguous
+	containerImages := []*model.ContainerImage{}
+	var containerImage *model.ContainerImage
+	for _, use := range images.Uses(snapshotFilter.apply(data.StateAt(nodes, effectiveAt))) {
+		if containerImage == nil || containerImage.Image != use.Image || containerImage.ImageID != use.ImageID {
+			containerImage = &model.ContainerImage{Image: use.Image, ImageID: use.ImageID, Pods: []*model.ImagePod{}}
+			containerImages = append(containerImages, containerImage)
+		}
+		containerImage.Pods = append(containerImage.Pods, &model.ImagePod{
+			PodID:     use.PodID,
+			Namespace: use.Namespace,
+			Name:      use.PodName,
+			NodeName:  use.NodeName,
+		})
+	}
+
+	return containerImages, nil
+}
+
+// ImageSightings returns when each pod was seen running the image between beginAt and endAt
+func (r *replayer) ImageSightings(ctx context.Context, image string, beginAt, endAt time.Time) (*model.ImageSightings, error) {
+	states, err := r.eventStates(ctx, beginAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+
+	sightings := &model.ImageSightings{Pods: []*model.ImagePodSighting{}}
+	for _, sighting := range images.Sightings(states, endAt, image) {
+		sightings.Pods = append(sightings.Pods, &model.ImagePodSighting{
+			PodID:     sighting.PodID,
+			Namespace: sighting.Namespace,
+			Name:      sighting.PodName,
+			NodeName:  sighting.NodeName,
+			Image:     sighting.Image,
+			ImageID:   sighting.ImageID,
+			FirstSeen: sighting.FirstSeen,
+			LastSeen:  sighting.LastSeen,
+		})
+	}
+	SeenBetween(sightings)
+
+	return sightings, nil
+}
+
+// SeenBetween sets when the image of the sightings was first and last seen from those of its pods
+func SeenBetween(sightings *model.ImageSightings) {
+	sightings.FirstSeen, sightings.LastSeen = nil, nil
+	for _, pod := range sightings.Pods {
+		if sightings.FirstSeen == nil || pod.FirstSeen.Before(*sightings.FirstSeen) {
+			firstSeen := pod.FirstSeen
+			sightings.FirstSeen = &firstSeen
+		}
+		if sightings.LastSeen == nil || pod.LastSeen.After(*sightings.LastSeen) {
+			lastSeen := pod.LastSeen
+			sightings.LastSeen = &lastSeen
+		}
+	}
+}
+
+// RolloutTimeline returns how many pods of the workload ran each image between beginAt and endAt, at every change
+func (r *replayer) RolloutTimeline(ctx context.Context, namespace, workload string, beginAt, endAt time.Time) ([]*model.RolloutFrame, error) {
+	states, err := r.eventStates(ctx, beginAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := []*model.RolloutFrame{}
+	for _, frame := range images.Rollout(states, namespace, workload) {
+		rolloutFrame := &model.RolloutFrame{Timestamp: frame.Timestamp, Versions: []*model.ImageVersion{}}
+		for image, pods := range frame.Pods {
+			rolloutFrame.Versions = append(rolloutFrame.Versions, &model.ImageVersion{Image: image, Pods: int32(pods)})
+		}
+		sort.Slice(rolloutFrame.Versions, func(i, j int) bool {
+			return rolloutFrame.Versions[i].Image < rolloutFrame.Versions[j].Image
+		})
+		timeline = append(timeline, rolloutFrame)
+	}
+
+	return timeline, nil
+}
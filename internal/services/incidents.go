@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/incidents"
+	"github.com/ccpeng/kube-replay/internal/utils"
+)
+
+var incidentKinds = map[incidents.Kind]model.NodeIncidentKind{
+	incidents.NotReady:           model.NodeIncidentKindNotReady,
+	incidents.Flapping:           model.NodeIncidentKindFlapping,
+	incidents.Cordoned:           model.NodeIncidentKindCordoned,
+	incidents.NoExecuteTaint:     model.NodeIncidentKindNoExecuteTaint,
+	incidents.AllocatableDropped: model.NodeIncidentKindAllocatableDropped,
+}
+
+// NodeIncidents returns the incidents that started between beginAt and endAt of the node named nodeName or all of
+// them if nil, with the pods running on the node when each started, ordered by when they started
+func (r *replayer) NodeIncidents(ctx context.Context, beginAt, endAt time.Time, nodeName *string) ([]*model.NodeIncident, error) {
+	if endAt.Before(beginAt) {
+		return nil, fmt.Errorf("%w: end %s is before start %s", ErrInvalidRange, endAt.Format(time.RFC3339), beginAt.Format(time.RFC3339))
+	}
+
+	nodes, err := r.store.GetAllBetween(ctx, beginAt, endAt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get all nodes in cluster: %v", err)
+	}
+
+	nodeIncidents := []*model.NodeIncident{}
+	for _, incident := range incidents.Detect(nodes, beginAt, endAt) {
+		if nodeName != nil && incident.NodeName != *nodeName {
+			continue
+		}
+
+		nodeIncident := &model.NodeIncident{
+			NodeID:      incident.NodeID,
+			NodeName:    incident.NodeName,
+			Kind:        incidentKinds[incident.Kind],
+			StartedAt:   incident.StartedAt,
+			Detail:      incident.Detail,
+			Transitions: int32(incident.Transitions),
+			Pods:        []*model.AffectedPod{},
+		}
+		if !incident.EndedAt.IsZero() {
+			nodeIncident.EndedAt = &incident.EndedAt
+		}
+
+		for _, pod := range incident.Pods {
+			affectedPod := &model.AffectedPod{
+				PodID:     pod.Meta.ID,
+				Namespace: pod.Meta.Namespace,
+				Name:      pod.Meta.Name,
+				Phase:     utils.TransformToModelPodPhase(pod.Phase),
+				Changes:   []*model.PodPhaseChange{},
+			}
+			for _, change := range pod.Changes {
+				affectedPod.Changes = append(affectedPod.Changes, &model.PodPhaseChange{
+					Timestamp: change.At,
+					From:      utils.TransformToModelPodPhase(change.From),
+					To:        utils.TransformToModelPodPhase(change.To),
+				})
+			}
+			if !pod.DeletedAt.IsZero() {
+				affectedPod.DeletedAt = &pod.DeletedAt
+			}
+			nodeIncident.Pods = append(nodeIncident.Pods, affectedPod)
+		}
+		nodeIncidents = append(nodeIncidents, nodeIncident)
+	}
+
+	return nodeIncidents, nil
+}
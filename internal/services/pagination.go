@@ -0,0 +1,100 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+)
+
+// PaginateNodes returns the Relay‑style page of at most first nodes following the after cursor
+func PaginateNodes(nodes []*model.NodeSnapshot, first *int32, after *string) (*model.NodeSnapshotConnection, error) {
+	page, pageInfo, err := paginate(nodes, func(node *model.NodeSnapshot) string {
+		return encodeCursor("node", node.ID)
+	}, first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &model.NodeSnapshotConnection{
+		TotalCount: int32(len(nodes)),
+		Edges:      []*model.NodeSnapshotEdge{},
+		PageInfo:   pageInfo,
+	}
+	for _, node := range page {
+		connection.Edges = append(connection.Edges, &model.NodeSnapshotEdge{
+			Cursor: encodeCursor("node", node.ID),
+			Node:   node,
+		})
+	}
+
+	return connection, nil
+}
+
+// PaginatePods returns the Relay‑style page of at most first pods following the after cursor
+func PaginatePods(pods []*model.PodSnapshot, first *int32, after *string) (*model.PodSnapshotConnection, error) {
+	page, pageInfo, err := paginate(pods, func(pod *model.PodSnapshot) string {
+		return encodeCursor("pod", pod.ID)
+	}, first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &model.PodSnapshotConnection{
+		TotalCount: int32(len(pods)),
+		Edges:      []*model.PodSnapshotEdge{},
+		PageInfo:   pageInfo,
+	}
+	for _, pod := range page {
+		connection.Edges = append(connection.Edges, &model.PodSnapshotEdge{
+			Cursor: encodeCursor("pod", pod.ID),
+			Node:   pod,
+		})
+	}
+
+	return connection, nil
+}
+
+func paginate[T any](items []T, cursorOf func(T) string, first *int32, after *string) ([]T, *model.PageInfo, error) {
+	start := 0
+	if after != nil {
+		start = -1
+		for i, item := range items {
+			if cursorOf(item) == *after {
+				start = i + 1
+				break
+			}
+		}
+
+		if start < 0 {
+			return nil, nil, fmt.Errorf("cursor %q doesn't match any item", *after)
+		}
+	}
+
+	end := len(items)
+	if first != nil {
+		if *first < 0 {
+			return nil, nil, fmt.Errorf("first must be >= 0, got %d", *first)
+		}
+
+		end = min(start+int(*first), len(items))
+	}
+
+	page := items[start:end]
+	pageInfo := &model.PageInfo{
+		HasNextPage:     end < len(items),
+		HasPreviousPage: start > 0,
+	}
+	if len(page) > 0 {
+		startCursor, endCursor := cursorOf(page[0]), cursorOf(page[len(page)-1])
+		pageInfo.StartCursor, pageInfo.EndCursor = &startCursor, &endCursor
+	}
+
+	return page, pageInfo, nil
+}
+
+// encodeCursor returns an opaque cursor for the item of the kind
+func encodeCursor(kind, id string) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Join([]string{kind, id}, ":")))
+}
@@ -0,0 +1,39 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+func TestPaginateNodes(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	nodes := []*model.NodeSnapshot{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+
+	first := int32(2)
+	page, err := services.PaginateNodes(nodes, &first, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(page.TotalCount).To(gomega.BeEquivalentTo(3))
+	g.Expect(page.Edges).To(gomega.HaveLen(2))
+	g.Expect(page.PageInfo.HasNextPage).To(gomega.BeTrue())
+	g.Expect(page.PageInfo.HasPreviousPage).To(gomega.BeFalse())
+
+	page, err = services.PaginateNodes(nodes, &first, page.PageInfo.EndCursor)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(page.Edges).To(gomega.HaveLen(1))
+	g.Expect(page.Edges[0].Node.ID).To(gomega.Equal("c"))
+	g.Expect(page.PageInfo.HasNextPage).To(gomega.BeFalse())
+	g.Expect(page.PageInfo.HasPreviousPage).To(gomega.BeTrue())
+
+	page, err = services.PaginateNodes(nodes, nil, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(page.Edges).To(gomega.HaveLen(3))
+
+	unknown := "bm9kZTp6"
+	_, err = services.PaginateNodes(nodes, &first, &unknown)
+	g.Expect(err).ToNot(gomega.BeNil())
+}
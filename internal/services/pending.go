@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/pending"
+)
+
+var pendingCauses = map[pending.Cause]model.PendingCause{
+	pending.NoEligibleNode:          model.PendingCauseNoEligibleNode,
+	pending.InsufficientAllocatable: model.PendingCauseInsufficientAllocatable,
+	pending.PodLimitReached:         model.PendingCausePodLimitReached,
+	pending.Schedulable:             model.PendingCauseSchedulable,
+}
+
+// PendingPods returns the pods that were pending for at least minPending between beginAt and endAt, of the namespace
+// or all of them if nil, with why no node could take those the scheduler hadn't bound by the replayed state, the
+// longest pending first
+func (r *replayer) PendingPods(ctx context.Context, beginAt, endAt time.Time, minPending time.Duration, namespace *string) ([]*model.PendingPod, error) {
+	if endAt.Before(beginAt) {
+		return nil, fmt.Errorf("%w: end %s is before start %s", ErrInvalidRange, endAt.Format(time.RFC3339), beginAt.Format(time.RFC3339))
+	}
+
+	nodes, err := r.store.GetAllBetween(ctx, beginAt, endAt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get all nodes in cluster: %v", err)
+	}
+
+	pendingPods := []*model.PendingPod{}
+	for _, pod := range pending.Find(nodes, beginAt, endAt, minPending) {
+		if namespace != nil && pod.Meta.Namespace != *namespace {
+			continue
+		}
+
+		pendingPod := &model.PendingPod{
+			PodID:          pod.Meta.ID,
+			Namespace:      pod.Meta.Namespace,
+			Name:           pod.Meta.Name,
+			Since:          pod.Since,
+			Until:          pod.Until,
+			PendingSeconds: int64(pod.Duration().Seconds()),
+			Ongoing:        pod.Ongoing,
+			ExplainedAt:    pod.ExplainedAt,
+			Causes:         []*model.PendingCauseCount{},
+			Reasons:        pod.Reasons,
+		}
+		if pod.NodeID != "" {
+			pendingPod.NodeID, pendingPod.NodeName = &pod.NodeID, &pod.NodeName
+		}
+		for _, count := range pod.Causes {
+			pendingPod.Causes = append(pendingPod.Causes, &model.PendingCauseCount{
+				Cause: pendingCauses[count.Cause],
+				Nodes: int32(count.Nodes),
+			})
+		}
+		pendingPods = append(pendingPods, pendingPod)
+	}
+
+	return pendingPods, nil
+}
@@ -2,43 +2,152 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/cost"
 	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/manifests"
 	"github.com/ccpeng/kube-replay/internal/repositories"
 	"github.com/ccpeng/kube-replay/internal/utils"
 )
 
-func NewReplayer(clusterName string) (Replayer, error) {
+const (
+	defaultMaxFrames      = 1000
+	defaultMaxEventFrames = 100000
+)
+
+var (
+	// ErrInvalidRange is returned for a range replay that ends before it begins or doesn't step forward
+	ErrInvalidRange = errors.New("invalid range")
+	// ErrTooManyFrames is returned for a range replay that would return more snapshots than the replayer allows, or a
+	// query over events that would reconstruct the cluster at more of them
+	ErrTooManyFrames = errors.New("too many frames")
+	// ErrInvalidPod is returned for a hypothetical pod that can't be simulated, such as one with unparsable requests,
+	// or a pod recorded with affinity terms that can't be parsed
+	ErrInvalidPod = errors.New("invalid pod")
+	// ErrInvalidVersion is returned for a version to compare nodes to that can't be parsed, or a negative skew
+	ErrInvalidVersion = errors.New("invalid version")
+)
+
+// Option configures a Replayer
+type Option func(r *replayer)
+
+// WithMaxFrames caps how many snapshots a single range replay may return
+func WithMaxFrames(maxFrames int64) Option {
+	return func(r *replayer) {
+		r.maxFrames = maxFrames
+	}
+}
+
+// WithMaxEventFrames caps how many recorded events a single query over events, such as a cost report, may
+// reconstruct the cluster at
+func WithMaxEventFrames(maxEventFrames int64) Option {
+	return func(r *replayer) {
+		r.maxEventFrames = maxEventFrames
+	}
+}
+
+// WithPodAnnotations records only the pod annotations with the keys, or with a key prefix when ending with *
+func WithPodAnnotations(keys []string) Option {
+	return func(r *replayer) {
+		r.annotations = keys
+	}
+}
+
+// WithPrices prices nodes by instance type for cost reports
+func WithPrices(prices cost.Prices) Option {
+	return func(r *replayer) {
+		r.prices = prices
+	}
+}
+
+func NewReplayer(clusterName string, opts ...Option) (Replayer, error) {
 	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion("us-west-2"))
 	if err != nil {
 		return nil, fmt.Errorf("unable to load SDK config, %v", err)
 	}
 
-	return &replayer{
-		store: repositories.NewStore(cfg, clusterName),
-	}, nil
+	return NewReplayerWithStore(repositories.NewStore(cfg, clusterName), opts...), nil
+}
+
+// NewReplayerWithStore returns a Replayer persisting to and replaying from the given store
+func NewReplayerWithStore(store repositories.Store, opts ...Option) Replayer {
+	r := &replayer{
+		store:          store,
+		maxFrames:      defaultMaxFrames,
+		maxEventFrames: defaultMaxEventFrames,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// FrameCount returns how many snapshots a range replay from beginAt to endAt every intervalInSec seconds returns
+func FrameCount(beginAt, endAt time.Time, intervalInSec int64) (int64, error) {
+	if intervalInSec < 1 {
+		return 0, fmt.Errorf("%w: step must be >= 1 second, got %d", ErrInvalidRange, intervalInSec)
+	}
+	if endAt.Before(beginAt) {
+		return 0, fmt.Errorf("%w: end %s is before start %s", ErrInvalidRange, endAt.Format(time.RFC3339), beginAt.Format(time.RFC3339))
+	}
+
+	return int64(endAt.Sub(beginAt)/time.Second)/intervalInSec + 1, nil
 }
 
 type Replayer interface {
 	RecordNodeSnapshot(ctx context.Context, snapshot *model.NodeSnapshotInput) error
 	RecordPodSnapshots(ctx context.Context, snapshots []*model.PodSnapshotInput) error
-	EventfulSnapshots(ctx context.Context, beginAt, endAt time.Time) ([]*model.TimedNodeSnapshots, error)
-	IntervalSnapshots(ctx context.Context, beginAt, endAt time.Time, intervalInSec int64) ([]*model.TimedNodeSnapshots, error)
-	EffectiveAtSnapshot(ctx context.Context, effectiveAt time.Time) (*model.TimedNodeSnapshots, error)
+	RecordNodeDeletion(ctx context.Context, deletion *model.NodeDeletionInput) error
+	RecordPodDeletion(ctx context.Context, deletion *model.PodDeletionInput) error
+	EventfulSnapshots(ctx context.Context, beginAt, endAt time.Time, filter *model.SnapshotFilter) ([]*model.TimedNodeSnapshots, error)
+	IntervalSnapshots(ctx context.Context, beginAt, endAt time.Time, intervalInSec int64, filter *model.SnapshotFilter) ([]*model.TimedNodeSnapshots, error)
+	EffectiveAtSnapshot(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) (*model.TimedNodeSnapshots, error)
+	PodHistory(ctx context.Context, namespace, name string, beginAt, endAt time.Time) (*model.PodHistory, error)
+	ClusterEvents(ctx context.Context, beginAt, endAt time.Time, filter *model.SnapshotFilter) ([]*model.ClusterEvent, error)
+	EffectiveAtManifests(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) (*manifests.Manifests, error)
+	SimulatePodFit(ctx context.Context, effectiveAt time.Time, pod *model.HypotheticalPodInput) (*model.PodFitSimulation, error)
+	SimulateNodeDrain(ctx context.Context, effectiveAt time.Time, nodeName string) (*model.DrainSimulation, error)
+	CapacityPlan(ctx context.Context, beginAt, endAt time.Time) (*model.CapacityPlan, error)
+	CostReport(ctx context.Context, beginAt, endAt time.Time) (*model.CostReport, error)
+	ImagesAtTimestamp(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) ([]*model.ContainerImage, error)
+	ImageSightings(ctx context.Context, image string, beginAt, endAt time.Time) (*model.ImageSightings, error)
+	RolloutTimeline(ctx context.Context, namespace, workload string, beginAt, endAt time.Time) ([]*model.RolloutFrame, error)
+	VersionReport(ctx context.Context, effectiveAt time.Time, reference *string, maxMinorSkew int) (*model.VersionReport, error)
+	UpgradeProgress(ctx context.Context, beginAt, endAt time.Time, component model.NodeComponent, target *string) (*model.UpgradeProgress, error)
+	RestartAnomalies(ctx context.Context, beginAt, endAt time.Time, namespace *string) ([]*model.RestartAnomaly, error)
+	NodeIncidents(ctx context.Context, beginAt, endAt time.Time, nodeName *string) ([]*model.NodeIncident, error)
+	PendingPods(ctx context.Context, beginAt, endAt time.Time, minPending time.Duration, namespace *string) ([]*model.PendingPod, error)
+	WorkloadsAtTimestamp(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) ([]*model.WorkloadSummary, error)
 }
 type replayer struct {
-	store repositories.Store
+	store          repositories.Store
+	maxFrames      int64
+	maxEventFrames int64
+	prices         cost.Prices
+	annotations    []string // keys of the pod annotations recorded
 }
 
 // RecordNodeSnapshot TODO: enhance so it won't override
 // RecordNodeSnapshot persists the node snapshot
 func (r *replayer) RecordNodeSnapshot(ctx context.Context, snapshot *model.NodeSnapshotInput) error {
+	if snapshot.ID == data.UnscheduledID {
+		return fmt.Errorf("node ID %q is reserved for pods not bound to a node", snapshot.ID)
+	}
+	if err := validateAffinities(snapshot.Pods); err != nil {
+		return err
+	}
+
 	var taints = make([]*data.Taint, 0)
 	for _, taint := range snapshot.State.Taints {
 		taints = append(taints, &data.Taint{
@@ -64,6 +173,7 @@ func (r *replayer) RecordNodeSnapshot(ctx context.Context, snapshot *model.NodeS
 		SystemUUID:              snapshot.Info.SystemUUID,
 		BootID:                  snapshot.Info.BootID,
 		Roles:                   snapshot.Roles,
+		Labels:                  utils.TransformToDataLabels(snapshot.Labels),
 		Snapshots: data.NodeSnapshots{
 			{
 				ID:        snapshot.ID,
@@ -84,6 +194,13 @@ func (r *replayer) RecordNodeSnapshot(ctx context.Context, snapshot *model.NodeS
 					},
 					Taints:        taints,
 					Unschedulable: *snapshot.State.Unschedulable,
+					Versions: data.NodeVersions{
+						KubeletVersion:          snapshot.Info.KubeletVersion,
+						KubeProxyVersion:        snapshot.Info.KubeProxyVersion,
+						KernelVersion:           snapshot.Info.KernelVersion,
+						ContainerRuntimeVersion: snapshot.Info.ContainerRuntimeVersion,
+						OsImage:                 snapshot.Info.OsImage,
+					},
 				},
 			},
 		},
@@ -95,21 +212,34 @@ func (r *replayer) RecordNodeSnapshot(ctx context.Context, snapshot *model.NodeS
 	return r.RecordPodSnapshots(ctx, snapshot.Pods)
 }
 
-// RecordPodSnapshots persists the pod snapshots (theoretically can be associated across different nodes)
+// RecordPodSnapshots persists the pod snapshots (theoretically can be associated across different nodes). Pods
+// without a node are recorded under the unscheduled tree, which is created along with them.
 func (r *replayer) RecordPodSnapshots(ctx context.Context, snapshots []*model.PodSnapshotInput) error {
+	if err := validateAffinities(snapshots); err != nil {
+		return err
+	}
+
 	// map of nodeID to list of pod snapshots
 	nodesPodsMap := map[string][]*model.PodSnapshotInput{}
 
 	for _, snapshot := range snapshots {
-		if len(nodesPodsMap[snapshot.NodeID]) == 0 {
-			nodesPodsMap[snapshot.NodeID] = []*model.PodSnapshotInput{snapshot}
-		} else {
-			nodesPodsMap[snapshot.NodeID] = append(nodesPodsMap[snapshot.NodeID], snapshot)
+		nodeID := treeIDOf(snapshot.NodeID)
+		nodesPodsMap[nodeID] = append(nodesPodsMap[nodeID], snapshot)
+	}
+
+	if _, ok := nodesPodsMap[data.UnscheduledID]; ok {
+		if err := r.store.Upsert(ctx, &data.NodeMeta{ID: data.UnscheduledID, Name: data.UnscheduledID}); err != nil {
+			return err
 		}
 	}
 
 	for nodeID, pods := range nodesPodsMap {
-		if err := r.store.UpsertPodMetas(ctx, nodeID, utils.TransformToDataPods(pods)); err != nil {
+		podMetas := utils.TransformToDataPods(pods)
+		for _, podMeta := range podMetas {
+			podMeta.Annotations = selectAnnotations(podMeta.Annotations, r.annotations)
+		}
+
+		if err := r.store.UpsertPodMetas(ctx, nodeID, podMetas); err != nil {
 			return err
 		}
 	}
@@ -117,27 +247,118 @@ func (r *replayer) RecordPodSnapshots(ctx context.Context, snapshots []*model.Po
 	return nil
 }
 
-// EventfulSnapshots returns snapshots timestamped at every captured node or pod snapshot
-func (r *replayer) EventfulSnapshots(ctx context.Context, beginAt, endAt time.Time) ([]*model.TimedNodeSnapshots, error) {
-	var times []time.Time
+// validateAffinities checks the affinity terms of the pods parse as label selectors, so none is dropped when the pods
+// are simulated or turned into manifests
+func validateAffinities(snapshots []*model.PodSnapshotInput) error {
+	for _, snapshot := range snapshots {
+		if snapshot.Affinity == nil {
+			continue
+		}
+
+		affinity := snapshot.Affinity
+		podTerms := func(terms []*model.AffinityTermInput) []string {
+			selectors := make([]string, 0, len(terms))
+			for _, term := range terms {
+				selectors = append(selectors, term.LabelSelector)
+			}
+			return selectors
+		}
+		for _, terms := range []struct {
+			kind      string
+			selectors []string
+		}{
+			{"required node", affinity.RequiredNodeTerms},
+			{"preferred node", affinity.PreferredNodeTerms},
+			{"pod affinity", podTerms(affinity.PodAffinity)},
+			{"pod anti-affinity", podTerms(affinity.PodAntiAffinity)},
+		} {
+			for _, selector := range terms.selectors {
+				if _, err := labels.Parse(selector); err != nil {
+					return fmt.Errorf("%w: %s %s term %q: %v", ErrInvalidPod, snapshot.Name, terms.kind, selector, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// treeIDOf returns the ID of the tree a pod bound to the node is recorded under, the unscheduled tree if it isn't
+func treeIDOf(nodeID *string) string {
+	if nodeID == nil {
+		return data.UnscheduledID
+	}
+
+	return *nodeID
+}
 
-	nodes, err := r.store.GetAll(ctx)
+// RecordNodeDeletion marks the node as removed from the cluster, so it's left out of replays from then on
+func (r *replayer) RecordNodeDeletion(ctx context.Context, deletion *model.NodeDeletionInput) error {
+	return r.store.UpdateNodeMetaAttributes(ctx, deletion.ID, map[string]interface{}{
+		"DeletedAt": deletion.DeletedAt,
+	})
+}
+
+// RecordPodDeletion marks the pod as deleted from its node, so it's left out of replays from then on
+func (r *replayer) RecordPodDeletion(ctx context.Context, deletion *model.PodDeletionInput) error {
+	updates := map[string]interface{}{
+		"DeletedAt": deletion.DeletedAt,
+	}
+	if deletion.DeletedBy != nil {
+		updates["DeletedBy"] = *deletion.DeletedBy
+	}
+
+	return r.store.UpdatePodMetaAttributes(ctx, treeIDOf(deletion.NodeID), deletion.ID, updates)
+}
+
+// EventfulSnapshots returns snapshots timestamped at every distinct instant a node or pod snapshot or deletion was
+// captured
+func (r *replayer) EventfulSnapshots(ctx context.Context, beginAt, endAt time.Time, filter *model.SnapshotFilter) ([]*model.TimedNodeSnapshots, error) {
+	snapshotFilter, err := newSnapshotFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := r.store.GetAllBetween(ctx, beginAt, endAt)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get all nodes in cluster: %v", err)
 	}
 
+	times := eventTimes(nodes, beginAt, endAt)
+	if int64(len(times)) > r.maxFrames {
+		return nil, fmt.Errorf("%w: %d events between %s and %s exceed the maximum of %d, narrow the range",
+			ErrTooManyFrames, len(times), beginAt.Format(time.RFC3339), endAt.Format(time.RFC3339), r.maxFrames)
+	}
+
+	return timedNodeSnapshots(data.Sweep(nodes, times), snapshotFilter), nil
+}
+
+// eventTimes returns every distinct time between beginAt and endAt that a node or pod was recorded or deleted at, in
+// ascending order
+func eventTimes(nodes []*data.NodeMeta, beginAt, endAt time.Time) []time.Time {
+	var times []time.Time
+	within := func(t time.Time) bool {
+		return (t.After(beginAt) || t.Equal(beginAt)) && (t.Before(endAt) || t.Equal(endAt))
+	}
+
 	for _, node := range nodes {
+		if within(node.DeletedAt) {
+			times = append(times, node.DeletedAt)
+		}
+
 		for _, nodeSnapshots := range node.Snapshots {
-			if (nodeSnapshots.Timestamp.After(beginAt) || nodeSnapshots.Timestamp.Equal(beginAt)) &&
-				(nodeSnapshots.Timestamp.Before(endAt) || nodeSnapshots.Timestamp.Equal(endAt)) {
+			if within(nodeSnapshots.Timestamp) {
 				times = append(times, nodeSnapshots.Timestamp)
 			}
 		}
 
 		for _, pod := range node.Pods {
+			if within(pod.DeletedAt) {
+				times = append(times, pod.DeletedAt)
+			}
+
 			for _, podSnapshots := range pod.Snapshots {
-				if (podSnapshots.Timestamp.After(beginAt) || podSnapshots.Timestamp.Equal(beginAt)) &&
-					(podSnapshots.Timestamp.Before(endAt) || podSnapshots.Timestamp.Equal(endAt)) {
+				if within(podSnapshots.Timestamp) {
 					times = append(times, podSnapshots.Timestamp)
 				}
 			}
@@ -148,133 +369,264 @@ func (r *replayer) EventfulSnapshots(ctx context.Context, beginAt, endAt time.Ti
 		return times[i].Before(times[j])
 	})
 
-	eventfulTimedSnapshots := make([]*model.TimedNodeSnapshots, 0)
-	for _, effectiveAt := range times {
-		timedNodeSnapshots, err := r.EffectiveAtSnapshot(ctx, effectiveAt)
-		if err != nil {
-			return nil, err
-		}
+	return slices.CompactFunc(times, time.Time.Equal)
+}
 
-		eventfulTimedSnapshots = append(eventfulTimedSnapshots, timedNodeSnapshots)
+// IntervalSnapshots returns effective snapshots at every regular interval between beginAt and endAt
+func (r *replayer) IntervalSnapshots(ctx context.Context, beginAt, endAt time.Time, intervalInSec int64, filter *model.SnapshotFilter) ([]*model.TimedNodeSnapshots, error) {
+	frames, err := FrameCount(beginAt, endAt, intervalInSec)
+	if err != nil {
+		return nil, err
+	}
+	if frames > r.maxFrames {
+		return nil, fmt.Errorf("%w: %d snapshots between %s and %s every %ds exceed the maximum of %d, increase the step or narrow the range",
+			ErrTooManyFrames, frames, beginAt.Format(time.RFC3339), endAt.Format(time.RFC3339), intervalInSec, r.maxFrames)
 	}
 
-	return eventfulTimedSnapshots, nil
-}
+	snapshotFilter, err := newSnapshotFilter(filter)
+	if err != nil {
+		return nil, err
+	}
 
-// IntervalSnapshots returns effective snapshots at every regular interval between beginAt and endAt
-func (r *replayer) IntervalSnapshots(ctx context.Context, beginAt, endAt time.Time, intervalInSec int64) ([]*model.TimedNodeSnapshots, error) {
 	var times []time.Time
 	for t := beginAt; t.Before(endAt) || t.Equal(endAt); t = t.Add(time.Duration(intervalInSec) * time.Second) {
 		times = append(times, t)
 	}
 
-	intervaledTimedSnapshots := make([]*model.TimedNodeSnapshots, 0)
-	for _, effectiveAt := range times {
-		timedNodeSnapshots, err := r.EffectiveAtSnapshot(ctx, effectiveAt)
-		if err != nil {
-			return nil, err
-		}
-
-		intervaledTimedSnapshots = append(intervaledTimedSnapshots, timedNodeSnapshots)
+	nodes, err := r.store.GetAllBetween(ctx, beginAt, endAt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get all nodes in cluster: %v", err)
 	}
 
-	return intervaledTimedSnapshots, nil
+	return timedNodeSnapshots(data.Sweep(nodes, times), snapshotFilter), nil
 }
 
-// EffectiveAtSnapshot returns the snapshots that are true/effective at given timestamp
-func (r *replayer) EffectiveAtSnapshot(ctx context.Context, effectiveAt time.Time) (*model.TimedNodeSnapshots, error) {
-	timedSnapshots := model.TimedNodeSnapshots{
-		Timestamp: effectiveAt,
+// EffectiveAtSnapshot returns the snapshots that are true/effective at given timestamp and match the filter
+func (r *replayer) EffectiveAtSnapshot(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) (*model.TimedNodeSnapshots, error) {
+	snapshotFilter, err := newSnapshotFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := r.store.GetAllBetween(ctx, effectiveAt, effectiveAt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get all nodes in cluster: %v", err)
 	}
 
-	var nodeSnapshots []*model.NodeSnapshot
+	return timedNodeSnapshots([]*data.ClusterState{data.StateAt(nodes, effectiveAt)}, snapshotFilter)[0], nil
+}
 
-	nodes, err := r.store.GetAll(ctx)
+// EffectiveAtManifests returns the nodes and pods effective at the timestamp as Kubernetes API objects
+func (r *replayer) EffectiveAtManifests(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) (*manifests.Manifests, error) {
+	snapshotFilter, err := newSnapshotFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := r.store.GetAllBetween(ctx, effectiveAt, effectiveAt)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get all nodes in cluster: %v", err)
 	}
 
-	for _, node := range nodes {
-		nodeInTime := node.Snapshots.EffectiveAt(effectiveAt)
-		nodeSnapshotTaints := []*model.NodeTaint{}
-
-		for _, taint := range nodeInTime.State.Taints {
-			nodeSnapshotTaints = append(nodeSnapshotTaints, &model.NodeTaint{
-				Key:       taint.Key,
-				Value:     &taint.Value,
-				Effect:    taint.Effect,
-				TimeAdded: &taint.TimeAdded,
-			})
+	return manifests.FromState(snapshotFilter.apply(data.StateAt(nodes, effectiveAt))), nil
+}
+
+// PodHistory returns every snapshot between beginAt and endAt of the pods named namespace/name, following them across
+// nodes they were rescheduled onto and across recreations with a new UID
+func (r *replayer) PodHistory(ctx context.Context, namespace, name string, beginAt, endAt time.Time) (*model.PodHistory, error) {
+	podMetas, err := r.store.GetPodMetasByName(ctx, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get pods named %s: %v", data.PodKey(namespace, name), err)
+	}
+
+	history := model.PodHistory{
+		Namespace: namespace,
+		Name:      name,
+		Bindings:  []*model.PodBinding{},
+		Snapshots: []*model.PodSnapshot{},
+	}
+
+	for _, binding := range data.PodBindings(podMetas) {
+		if binding.To.Before(beginAt) || binding.From.After(endAt) {
+			continue
 		}
 
-		podSnapshots := []*model.PodSnapshot{}
-		for _, pod := range node.Pods {
-			podInTime := pod.Snapshots.EffectiveAt(effectiveAt)
-			podSnapshotInitContainers := containerSnapshots(podInTime.InitContainers)
-			podSnapshotEphemeralContainers := containerSnapshots(podInTime.EphemeralContainers)
-			podSnapshotContainers := containerSnapshots(podInTime.Containers)
-
-			podSnapshot := model.PodSnapshot{
-				ID:                  pod.ID,
-				Timestamp:           podInTime.Timestamp,
-				Name:                pod.Name,
-				Namespace:           &pod.Namespace,
-				Status:              utils.TransformToModelPodPhase(podInTime.Status),
-				StartedAt:           pod.StartedAt,
-				DeletedAt:           &pod.DeletedAt,
-				FinishedAt:          &pod.FinishedAt,
-				DeletedBy:           &pod.DeletedBy,
-				QosClass:            utils.TransformToModelPodQOSClass(pod.QOSClass),
-				InitContainers:      podSnapshotInitContainers,
-				Containers:          podSnapshotContainers,
-				EphemeralContainers: podSnapshotEphemeralContainers,
+		history.Bindings = append(history.Bindings, &model.PodBinding{
+			PodID:  binding.PodID,
+			NodeID: binding.NodeID,
+			From:   binding.From,
+			To:     binding.To,
+		})
+	}
+
+	for _, podMeta := range podMetas {
+		for _, snapshot := range podMeta.Snapshots {
+			if snapshot.Timestamp.Before(beginAt) || snapshot.Timestamp.After(endAt) {
+				continue
 			}
-			podSnapshots = append(podSnapshots, &podSnapshot)
+
+			history.Snapshots = append(history.Snapshots, podSnapshot(&data.PodAt{Meta: podMeta, Snapshot: snapshot}))
 		}
+	}
+
+	sort.SliceStable(history.Snapshots, func(i, j int) bool {
+		return history.Snapshots[i].Timestamp.Before(history.Snapshots[j].Timestamp)
+	})
 
-		nodeSnapshot := &model.NodeSnapshot{
-			ID:         node.ID,
-			Timestamp:  nodeInTime.Timestamp,
-			Name:       node.Name,
-			Roles:      node.Roles,
-			ProviderID: &node.ProviderID,
-			Info: &model.NodeInfo{
-				Architecture:            node.Architecture,
-				ContainerRuntimeVersion: node.ContainerRuntimeVersion,
-				KernelVersion:           node.KernelVersion,
-				KubeletVersion:          node.KubeletVersion,
-				KubeProxyVersion:        node.KubeProxyVersion,
-				OsImage:                 node.OsImage,
-				OperatingSystem:         &node.OperatingSystem,
-				MachineID:               node.MachineID,
-				SystemUUID:              node.SystemUUID,
-				BootID:                  node.BootID,
+	return &history, nil
+}
+
+// timedNodeSnapshots transforms the reconstructed cluster states, filtered, into the graph model
+func timedNodeSnapshots(states []*data.ClusterState, filter *snapshotFilter) []*model.TimedNodeSnapshots {
+	timedSnapshots := make([]*model.TimedNodeSnapshots, 0, len(states))
+	for _, state := range states {
+		timedSnapshots = append(timedSnapshots, timedNodeSnapshot(state, filter))
+	}
+
+	return timedSnapshots
+}
+
+func timedNodeSnapshot(state *data.ClusterState, filter *snapshotFilter) *model.TimedNodeSnapshots {
+	state = filter.apply(state)
+
+	timedSnapshot := &model.TimedNodeSnapshots{
+		Timestamp: state.Timestamp,
+		Nodes:     []*model.NodeSnapshot{},
+	}
+	for _, node := range state.Nodes {
+		timedSnapshot.Nodes = append(timedSnapshot.Nodes, nodeSnapshot(node))
+	}
+
+	return timedSnapshot
+}
+
+func nodeSnapshot(node *data.NodeAt) *model.NodeSnapshot {
+	nodeInTime := node.Snapshot
+	nodeSnapshotTaints := []*model.NodeTaint{}
+
+	for _, taint := range nodeInTime.State.Taints {
+		nodeSnapshotTaints = append(nodeSnapshotTaints, &model.NodeTaint{
+			Key:       taint.Key,
+			Value:     &taint.Value,
+			Effect:    taint.Effect,
+			TimeAdded: &taint.TimeAdded,
+		})
+	}
+
+	podSnapshots := []*model.PodSnapshot{}
+	for _, pod := range node.Pods {
+		podSnapshots = append(podSnapshots, podSnapshot(pod))
+	}
+	versions := node.Versions()
+
+	return &model.NodeSnapshot{
+		ID:         node.Meta.ID,
+		Timestamp:  nodeInTime.Timestamp,
+		Name:       node.Meta.Name,
+		Roles:      node.Meta.Roles,
+		Labels:     utils.TransformToModelLabels(node.Meta.Labels),
+		ProviderID: &node.Meta.ProviderID,
+		Info: &model.NodeInfo{
+			Architecture:            node.Meta.Architecture,
+			ContainerRuntimeVersion: versions.ContainerRuntimeVersion,
+			KernelVersion:           versions.KernelVersion,
+			KubeletVersion:          versions.KubeletVersion,
+			KubeProxyVersion:        versions.KubeProxyVersion,
+			OsImage:                 versions.OsImage,
+			OperatingSystem:         &node.Meta.OperatingSystem,
+			MachineID:               node.Meta.MachineID,
+			SystemUUID:              node.Meta.SystemUUID,
+			BootID:                  node.Meta.BootID,
+		},
+		State: &model.NodeState{
+			Status: utils.TransformToModelNodeCondition(nodeInTime.State.Condition),
+			Capacity: &model.NodeCapacity{
+				CPU:              nodeInTime.State.Capacity.Cpu,
+				Memory:           nodeInTime.State.Capacity.Memory,
+				EphemeralStorage: nodeInTime.State.Capacity.EphemeralStorage,
+				Pods:             &nodeInTime.State.Capacity.Pods,
 			},
-			State: &model.NodeState{
-				Status: utils.TransformToModelNodeCondition(nodeInTime.State.Condition),
-				Capacity: &model.NodeCapacity{
-					CPU:              nodeInTime.State.Capacity.Cpu,
-					Memory:           nodeInTime.State.Capacity.Memory,
-					EphemeralStorage: nodeInTime.State.Capacity.EphemeralStorage,
-					Pods:             &nodeInTime.State.Capacity.Pods,
-				},
-				Allocatable: &model.NodeCapacity{
-					CPU:              nodeInTime.State.Allocatable.Cpu,
-					Memory:           nodeInTime.State.Allocatable.Memory,
-					EphemeralStorage: nodeInTime.State.Allocatable.EphemeralStorage,
-					Pods:             &nodeInTime.State.Allocatable.Pods,
-				},
-				Taints:        nodeSnapshotTaints,
-				Unschedulable: &nodeInTime.State.Unschedulable,
+			Allocatable: &model.NodeCapacity{
+				CPU:              nodeInTime.State.Allocatable.Cpu,
+				Memory:           nodeInTime.State.Allocatable.Memory,
+				EphemeralStorage: nodeInTime.State.Allocatable.EphemeralStorage,
+				Pods:             &nodeInTime.State.Allocatable.Pods,
 			},
-			Pods: podSnapshots,
+			Taints:        nodeSnapshotTaints,
+			Unschedulable: &nodeInTime.State.Unschedulable,
+		},
+		Pods:      podSnapshots,
+		DeletedAt: deletedAt(node.Meta.DeletedAt),
+	}
+}
+
+// selectAnnotations returns the annotations whose keys are selected, by key or by key prefix ending with *
+func selectAnnotations(annotations map[string]string, keys []string) map[string]string {
+	selected := map[string]string{}
+	for key, value := range annotations {
+		if slices.ContainsFunc(keys, func(k string) bool {
+			prefix, ok := strings.CutSuffix(k, "*")
+			return k == key || ok && strings.HasPrefix(key, prefix)
+		}) {
+			selected[key] = value
 		}
+	}
+
+	return selected
+}
 
-		nodeSnapshots = append(nodeSnapshots, nodeSnapshot)
+// workload returns the model of the workload
+func workload(w data.Workload) *model.Workload {
+	return &model.Workload{Kind: w.Kind, Name: w.Name}
+}
+
+// optional returns nil for an empty string, i.e. one that wasn't recorded
+func optional(s string) *string {
+	if s == "" {
+		return nil
 	}
 
-	timedSnapshots.Nodes = nodeSnapshots
-	return &timedSnapshots, nil
+	return &s
+}
+
+// deletedAt returns nil for a zero deletion timestamp, i.e. one that hasn't been deleted
+func deletedAt(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+func podSnapshot(pod *data.PodAt) *model.PodSnapshot {
+	podInTime := pod.Snapshot
+
+	return &model.PodSnapshot{
+		ID:                  pod.Meta.ID,
+		NodeID:              pod.Meta.NodeID(),
+		Timestamp:           podInTime.Timestamp,
+		Name:                pod.Meta.Name,
+		Namespace:           &pod.Meta.Namespace,
+		Status:              utils.TransformToModelPodPhase(podInTime.Status),
+		StartedAt:           pod.Meta.StartedAt,
+		DeletedAt:           deletedAt(pod.Meta.DeletedAt),
+		FinishedAt:          &pod.Meta.FinishedAt,
+		DeletedBy:           &pod.Meta.DeletedBy,
+		QosClass:            utils.TransformToModelPodQOSClass(pod.Meta.QOSClass),
+		Labels:              utils.TransformToModelLabels(pod.Meta.Labels),
+		Annotations:         utils.TransformToModelLabels(pod.Meta.Annotations),
+		OwnerReferences:     utils.TransformToModelOwners(pod.Meta.Owners),
+		Workload:            workload(pod.Meta.Workload()),
+		Tolerations:         utils.TransformToModelTolerations(pod.Meta.Tolerations),
+		NodeSelector:        utils.TransformToModelLabels(pod.Meta.NodeSelector),
+		Affinity:            utils.TransformToModelAffinity(pod.Meta.Affinity),
+		Priority:            pod.Meta.Priority,
+		PriorityClassName:   optional(pod.Meta.PriorityClassName),
+		PreemptionPolicy:    optional(pod.Meta.PreemptionPolicy),
+		InitContainers:      containerSnapshots(podInTime.InitContainers),
+		Containers:          containerSnapshots(podInTime.Containers),
+		EphemeralContainers: containerSnapshots(podInTime.EphemeralContainers),
+	}
 }
 
 func containerSnapshots(containers []*data.ContainerSnapshot) []*model.ContainerSnapshot {
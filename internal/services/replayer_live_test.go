@@ -18,7 +18,7 @@ func TestReplayer_EffectiveAtSnapshot(t *testing.T) {
 
 	effectiveAt, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
 
-	timedNodeSnapshots, err := replayer.EffectiveAtSnapshot(context.Background(), effectiveAt)
+	timedNodeSnapshots, err := replayer.EffectiveAtSnapshot(context.Background(), effectiveAt, nil)
 	g.Expect(err).To(gomega.BeNil())
 
 	g.Expect(timedNodeSnapshots).ToNot(gomega.BeNil())
@@ -0,0 +1,398 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+func TestReplayer_PodHistory(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	store := repositories.NewMemoryStore()
+
+	// web-0 ran on node-a, then was recreated with a new UID on node-b
+	err := store.Upsert(context.Background(), &data.NodeMeta{
+		ID:        "node-a",
+		Snapshots: data.NodeSnapshots{{Timestamp: t0}},
+		Pods: []*data.PodMeta{
+			{ID: "uid-1", Name: "web-0", Namespace: "shop", Snapshots: data.PodSnapshots{{Timestamp: t0}}},
+		},
+	})
+	g.Expect(err).To(gomega.BeNil())
+	err = store.Upsert(context.Background(), &data.NodeMeta{
+		ID:        "node-b",
+		Snapshots: data.NodeSnapshots{{Timestamp: t0}},
+		Pods: []*data.PodMeta{
+			{ID: "uid-2", Name: "web-0", Namespace: "shop", Snapshots: data.PodSnapshots{{Timestamp: t0.Add(time.Minute)}}},
+			{ID: "uid-3", Name: "web-1", Namespace: "shop", Snapshots: data.PodSnapshots{{Timestamp: t0.Add(time.Minute)}}},
+		},
+	})
+	g.Expect(err).To(gomega.BeNil())
+
+	replayer := services.NewReplayerWithStore(store)
+
+	history, err := replayer.PodHistory(context.Background(), "shop", "web-0", t0, t0.Add(time.Hour))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(history.Bindings).To(gomega.HaveLen(2))
+	g.Expect(history.Bindings[0].NodeID).To(gomega.Equal("node-a"))
+	g.Expect(history.Bindings[1].NodeID).To(gomega.Equal("node-b"))
+	g.Expect(history.Snapshots).To(gomega.HaveLen(2))
+	g.Expect(history.Snapshots[0].ID).To(gomega.Equal("uid-1"))
+	g.Expect(history.Snapshots[1].ID).To(gomega.Equal("uid-2"))
+	g.Expect(history.Snapshots[1].NodeID).To(gomega.Equal("node-b"))
+
+	snapshots, err := replayer.EffectiveAtSnapshot(context.Background(), t0.Add(time.Minute), nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(snapshots.Nodes).To(gomega.HaveLen(2))
+}
+
+func TestReplayer_RecordDeletions(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	store := repositories.NewMemoryStore()
+
+	err := store.Upsert(context.Background(), &data.NodeMeta{
+		ID:        "node-a",
+		Snapshots: data.NodeSnapshots{{Timestamp: t0}},
+		Pods: []*data.PodMeta{
+			{ID: "uid-1", Name: "web-0", Namespace: "shop", Snapshots: data.PodSnapshots{{Timestamp: t0}}},
+		},
+	})
+	g.Expect(err).To(gomega.BeNil())
+
+	replayer := services.NewReplayerWithStore(store)
+
+	// the pod has no deletion until one is recorded
+	snapshot, err := replayer.EffectiveAtSnapshot(context.Background(), t0, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(snapshot.Nodes[0].Pods[0].DeletedAt).To(gomega.BeNil())
+
+	nodeID, deletedBy := "node-a", "JohnSmith"
+	err = replayer.RecordPodDeletion(context.Background(), &model.PodDeletionInput{
+		ID: "uid-1", NodeID: &nodeID, DeletedAt: t0.Add(time.Minute), DeletedBy: &deletedBy,
+	})
+	g.Expect(err).To(gomega.BeNil())
+	err = replayer.RecordNodeDeletion(context.Background(), &model.NodeDeletionInput{
+		ID: "node-a", DeletedAt: t0.Add(2 * time.Minute),
+	})
+	g.Expect(err).To(gomega.BeNil())
+
+	snapshots, err := replayer.EventfulSnapshots(context.Background(), t0, t0.Add(time.Hour), nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(snapshots).To(gomega.HaveLen(3))
+	g.Expect(snapshots[0].Timestamp).To(gomega.Equal(t0))
+	g.Expect(snapshots[0].Nodes[0].Pods).To(gomega.HaveLen(1))
+	g.Expect(*snapshots[0].Nodes[0].Pods[0].DeletedAt).To(gomega.Equal(t0.Add(time.Minute)))
+	g.Expect(snapshots[1].Timestamp).To(gomega.Equal(t0.Add(time.Minute)))
+	g.Expect(snapshots[1].Nodes).To(gomega.HaveLen(1))
+	g.Expect(snapshots[1].Nodes[0].Pods).To(gomega.BeEmpty())
+	g.Expect(snapshots[2].Timestamp).To(gomega.Equal(t0.Add(2 * time.Minute)))
+	g.Expect(snapshots[2].Nodes).To(gomega.BeEmpty())
+
+	// a snapshot recorded late doesn't undo the deletions
+	err = store.Upsert(context.Background(), &data.NodeMeta{
+		ID:        "node-a",
+		Snapshots: data.NodeSnapshots{{Timestamp: t0.Add(30 * time.Second)}},
+		Pods: []*data.PodMeta{
+			{ID: "uid-1", Name: "web-0", Namespace: "shop", Snapshots: data.PodSnapshots{{Timestamp: t0.Add(30 * time.Second)}}},
+		},
+	})
+	g.Expect(err).To(gomega.BeNil())
+	node, err := store.Get(context.Background(), "node-a")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(node.DeletedAt).To(gomega.Equal(t0.Add(2 * time.Minute)))
+	g.Expect(node.Pods[0].DeletedAt).To(gomega.Equal(t0.Add(time.Minute)))
+	g.Expect(node.Pods[0].DeletedBy).To(gomega.Equal("JohnSmith"))
+
+	err = replayer.RecordNodeDeletion(context.Background(), &model.NodeDeletionInput{ID: "node-b", DeletedAt: t0})
+	g.Expect(err).To(gomega.MatchError(repositories.ErrNotFound))
+	err = replayer.RecordPodDeletion(context.Background(), &model.PodDeletionInput{ID: "uid-2", NodeID: &nodeID, DeletedAt: t0})
+	g.Expect(err).To(gomega.MatchError(repositories.ErrNotFound))
+	_, err = store.Get(context.Background(), "node-b")
+	g.Expect(err).NotTo(gomega.BeNil())
+}
+
+func TestReplayer_RecordUnboundPods(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	ctx := context.Background()
+	store := repositories.NewMemoryStore()
+	g.Expect(store.Upsert(ctx, &data.NodeMeta{
+		ID: "node-a", Name: "a", Snapshots: data.NodeSnapshots{{Timestamp: t0, State: data.NodeState{Unschedulable: true}}},
+	})).To(gomega.Succeed())
+
+	replayer := services.NewReplayerWithStore(store)
+	namespace := "shop"
+	g.Expect(replayer.RecordPodSnapshots(ctx, []*model.PodSnapshotInput{{
+		ID: "uid-1", Timestamp: t0, Name: "web-0", Namespace: &namespace, Status: model.PodPhasePending,
+		Containers: []*model.ContainerSnapshotInput{}, StartedAt: t0, QosClass: model.PodQOSClassBestEffort,
+	}})).To(gomega.Succeed())
+
+	// the pod isn't replayed on a node, but is explained as pending
+	snapshot, err := replayer.EffectiveAtSnapshot(ctx, t0.Add(time.Hour), nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(snapshot.Nodes).To(gomega.HaveLen(1))
+	g.Expect(snapshot.Nodes[0].Pods).To(gomega.BeEmpty())
+
+	pendingPods, err := replayer.PendingPods(ctx, t0, t0.Add(time.Hour), 5*time.Minute, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(pendingPods).To(gomega.HaveLen(1))
+	g.Expect(pendingPods[0].NodeID).To(gomega.BeNil())
+	g.Expect(pendingPods[0].Reasons).To(gomega.Equal([]string{"node a: node is unschedulable"}))
+
+	g.Expect(replayer.RecordPodDeletion(ctx, &model.PodDeletionInput{ID: "uid-1", DeletedAt: t0.Add(10 * time.Minute)})).To(gomega.Succeed())
+	pendingPods, err = replayer.PendingPods(ctx, t0, t0.Add(time.Hour), 5*time.Minute, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(pendingPods[0].PendingSeconds).To(gomega.Equal(int64(600)))
+
+	err = replayer.RecordNodeSnapshot(ctx, &model.NodeSnapshotInput{ID: data.UnscheduledID})
+	g.Expect(err).NotTo(gomega.BeNil())
+}
+
+func TestReplayer_RecordInvalidAffinity(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	ctx := context.Background()
+	store := repositories.NewMemoryStore()
+	replayer := services.NewReplayerWithStore(store)
+
+	pod := func(affinity *model.AffinityInput) *model.PodSnapshotInput {
+		return &model.PodSnapshotInput{
+			ID: "uid-1", Timestamp: t0, Name: "web-0", Status: model.PodPhasePending, Affinity: affinity,
+			Containers: []*model.ContainerSnapshotInput{}, StartedAt: t0, QosClass: model.PodQOSClassBestEffort,
+		}
+	}
+
+	err := replayer.RecordPodSnapshots(ctx, []*model.PodSnapshotInput{pod(&model.AffinityInput{
+		RequiredNodeTerms: []string{"topology.kubernetes.io/zone in (us-west-2a"},
+	})})
+	g.Expect(err).To(gomega.MatchError(services.ErrInvalidPod))
+	err = replayer.RecordPodSnapshots(ctx, []*model.PodSnapshotInput{pod(&model.AffinityInput{
+		PodAntiAffinity: []*model.AffinityTermInput{{LabelSelector: "app in web", TopologyKey: "kubernetes.io/hostname"}},
+	})})
+	g.Expect(err).To(gomega.MatchError(services.ErrInvalidPod))
+
+	// nothing was recorded
+	nodes, err := store.GetAll(ctx)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(nodes).To(gomega.BeEmpty())
+
+	g.Expect(replayer.RecordPodSnapshots(ctx, []*model.PodSnapshotInput{pod(&model.AffinityInput{
+		RequiredNodeTerms: []string{"topology.kubernetes.io/zone in (us-west-2a),!spot"},
+	})})).To(gomega.Succeed())
+}
+
+func TestReplayer_EffectiveAtSnapshotFilter(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	store := repositories.NewMemoryStore()
+
+	for _, node := range []*data.NodeMeta{
+		{
+			ID: "node-a", Name: "a", Roles: []string{"worker"}, Labels: map[string]string{"zone": "us-west-2a"},
+			Snapshots: data.NodeSnapshots{{Timestamp: t0}},
+			Pods: []*data.PodMeta{
+				{
+					ID: "uid-1", Name: "web-0", Namespace: "shop", QOSClass: data.PodQOSClassBurstable,
+					Snapshots: data.PodSnapshots{{Timestamp: t0, Status: data.PodPhaseRunning, Containers: []*data.ContainerSnapshot{
+						{Name: "app", Image: "shop/web:v2", RestartCount: 3},
+					}}},
+				},
+				{
+					ID: "uid-2", Name: "db-0", Namespace: "shop", QOSClass: data.PodQOSClassGuaranteed,
+					Snapshots: data.PodSnapshots{{Timestamp: t0, Status: data.PodPhaseSucceeded, Containers: []*data.ContainerSnapshot{
+						{Name: "db", Image: "postgres:16"},
+					}}},
+				},
+			},
+		},
+		{
+			ID: "node-b", Name: "b", Roles: []string{"control-plane"}, Labels: map[string]string{"zone": "us-west-2b"},
+			Snapshots: data.NodeSnapshots{{Timestamp: t0}},
+		},
+	} {
+		g.Expect(store.Upsert(context.Background(), node)).To(gomega.Succeed())
+	}
+
+	replayer := services.NewReplayerWithStore(store)
+
+	selector := "zone in (us-west-2a)"
+	snapshots, err := replayer.EffectiveAtSnapshot(context.Background(), t0, &model.SnapshotFilter{
+		Nodes: &model.NodeFilter{LabelSelector: &selector},
+	})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(snapshots.Nodes).To(gomega.HaveLen(1))
+	g.Expect(snapshots.Nodes[0].Name).To(gomega.Equal("a"))
+
+	snapshots, err = replayer.EffectiveAtSnapshot(context.Background(), t0, &model.SnapshotFilter{
+		Nodes: &model.NodeFilter{Roles: []string{"control-plane"}},
+	})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(snapshots.Nodes).To(gomega.HaveLen(1))
+	g.Expect(snapshots.Nodes[0].Name).To(gomega.Equal("b"))
+
+	image, restarts := "web", int64(1)
+	snapshots, err = replayer.EffectiveAtSnapshot(context.Background(), t0, &model.SnapshotFilter{
+		Pods: &model.PodFilter{Namespaces: []string{"shop"}, Image: &image, MinRestartCount: &restarts},
+	})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(snapshots.Nodes).To(gomega.HaveLen(2))
+	g.Expect(snapshots.Nodes[0].Pods).To(gomega.HaveLen(1))
+	g.Expect(snapshots.Nodes[0].Pods[0].Name).To(gomega.Equal("web-0"))
+
+	snapshots, err = replayer.EffectiveAtSnapshot(context.Background(), t0, &model.SnapshotFilter{
+		Pods: &model.PodFilter{Phases: []model.PodPhase{model.PodPhaseSucceeded}, QosClasses: []model.PodQOSClass{model.PodQOSClassGuaranteed}},
+	})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(snapshots.Nodes[0].Pods).To(gomega.HaveLen(1))
+	g.Expect(snapshots.Nodes[0].Pods[0].Name).To(gomega.Equal("db-0"))
+
+	invalid := "zone in ("
+	_, err = replayer.EffectiveAtSnapshot(context.Background(), t0, &model.SnapshotFilter{
+		Nodes: &model.NodeFilter{LabelSelector: &invalid},
+	})
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestReplayer_IntervalSnapshotsLimits(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	replayer := services.NewReplayerWithStore(repositories.NewMemoryStore(), services.WithMaxFrames(10))
+
+	_, err := replayer.IntervalSnapshots(context.Background(), t0, t0.Add(time.Minute), 0, nil)
+	g.Expect(err).To(gomega.MatchError(services.ErrInvalidRange))
+
+	_, err = replayer.IntervalSnapshots(context.Background(), t0, t0.Add(-time.Minute), 10, nil)
+	g.Expect(err).To(gomega.MatchError(services.ErrInvalidRange))
+
+	_, err = replayer.IntervalSnapshots(context.Background(), t0, t0.Add(time.Minute), 1, nil)
+	g.Expect(err).To(gomega.MatchError(services.ErrTooManyFrames))
+
+	snapshots, err := replayer.IntervalSnapshots(context.Background(), t0, t0.Add(time.Minute), 10, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(snapshots).To(gomega.HaveLen(7))
+}
+
+// countingStore counts the calls loading node trees
+type countingStore struct {
+	repositories.Store
+	loads int
+}
+
+func (c *countingStore) GetAll(ctx context.Context) ([]*data.NodeMeta, error) {
+	c.loads++
+	return c.Store.GetAll(ctx)
+}
+
+func (c *countingStore) GetAllBetween(ctx context.Context, beginAt, endAt time.Time) ([]*data.NodeMeta, error) {
+	c.loads++
+	return c.Store.GetAllBetween(ctx, beginAt, endAt)
+}
+
+func TestReplayer_RangeSnapshotsLoadOnce(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	store := &countingStore{Store: repositories.NewMemoryStore()}
+
+	err := store.Upsert(context.Background(), &data.NodeMeta{
+		ID:        "node-a",
+		Snapshots: data.NodeSnapshots{{Timestamp: t0.Add(-time.Hour)}, {Timestamp: t0.Add(30 * time.Minute)}},
+		Pods: []*data.PodMeta{
+			{ID: "uid-1", Name: "web-0", Namespace: "shop", Snapshots: data.PodSnapshots{{Timestamp: t0.Add(10 * time.Minute)}}},
+		},
+	})
+	g.Expect(err).To(gomega.BeNil())
+
+	replayer := services.NewReplayerWithStore(store)
+
+	snapshots, err := replayer.IntervalSnapshots(context.Background(), t0, t0.Add(time.Hour), 60, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(store.loads).To(gomega.Equal(1))
+	g.Expect(snapshots).To(gomega.HaveLen(61))
+	g.Expect(snapshots[0].Nodes).To(gomega.HaveLen(1))
+	g.Expect(snapshots[0].Nodes[0].Timestamp).To(gomega.Equal(t0.Add(-time.Hour)))
+	g.Expect(snapshots[0].Nodes[0].Pods).To(gomega.BeEmpty())
+	g.Expect(snapshots[10].Nodes[0].Pods).To(gomega.HaveLen(1))
+	g.Expect(snapshots[60].Nodes[0].Timestamp).To(gomega.Equal(t0.Add(30 * time.Minute)))
+
+	snapshots, err = replayer.EventfulSnapshots(context.Background(), t0, t0.Add(time.Hour), nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(store.loads).To(gomega.Equal(2))
+	g.Expect(snapshots).To(gomega.HaveLen(2))
+}
+
+// sharedStore returns the same node trees to every caller, like a cache would
+type sharedStore struct {
+	repositories.Store
+	nodes []*data.NodeMeta
+}
+
+func (s *sharedStore) GetAllBetween(ctx context.Context, beginAt, endAt time.Time) ([]*data.NodeMeta, error) {
+	return s.nodes, nil
+}
+
+func TestReplayer_ConcurrentQueries(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	memoryStore := repositories.NewMemoryStore()
+	for n := 0; n < 3; n++ {
+		node := &data.NodeMeta{ID: fmt.Sprintf("node-%d", n), Name: fmt.Sprintf("node-%d", n)}
+		for m := 0; m < 10; m++ {
+			node.Snapshots = append(node.Snapshots, &data.NodeSnapshot{Timestamp: t0.Add(time.Duration(10-m) * time.Minute)})
+			node.Pods = append(node.Pods, &data.PodMeta{
+				ID: fmt.Sprintf("pod-%d-%d", n, m), Name: fmt.Sprintf("web-%d", m), Namespace: "shop",
+				Snapshots: data.PodSnapshots{{Timestamp: t0.Add(time.Duration(m) * time.Minute)}},
+			})
+		}
+		g.Expect(memoryStore.Upsert(context.Background(), node)).To(gomega.Succeed())
+	}
+
+	nodes, err := memoryStore.GetAll(context.Background())
+	g.Expect(err).To(gomega.BeNil())
+	replayer := services.NewReplayerWithStore(&sharedStore{Store: memoryStore, nodes: nodes})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 30)
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_, err := replayer.EffectiveAtSnapshot(context.Background(), t0.Add(5*time.Minute), nil)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := replayer.IntervalSnapshots(context.Background(), t0, t0.Add(10*time.Minute), 30, nil)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := replayer.EventfulSnapshots(context.Background(), t0, t0.Add(10*time.Minute), nil)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		g.Expect(err).To(gomega.BeNil())
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/simulator"
+)
+
+// SimulatePodFit returns whether the hypothetical pod would have fit on the cluster at the timestamp, and where
+func (r *replayer) SimulatePodFit(ctx context.Context, effectiveAt time.Time, input *model.HypotheticalPodInput) (*model.PodFitSimulation, error) {
+	pod, err := hypotheticalPod(input)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster, err := r.clusterAt(ctx, effectiveAt)
+	if err != nil {
+		return nil, err
+	}
+
+	placement, fits := cluster.Place(pod)
+	simulation := &model.PodFitSimulation{
+		Timestamp: effectiveAt,
+		Fits:      placement.NodeID != "",
+		Nodes:     []*model.NodeFit{},
+	}
+	if simulation.Fits {
+		simulation.NodeID, simulation.NodeName = &placement.NodeID, &placement.NodeName
+	}
+	for _, fit := range fits {
+		simulation.Nodes = append(simulation.Nodes, &model.NodeFit{
+			NodeID:   fit.NodeID,
+			NodeName: fit.NodeName,
+			Fits:     fit.Fits(),
+			Reasons:  fit.Reasons,
+			Score:    fit.Score,
+		})
+	}
+
+	return simulation, nil
+}
+
+// SimulateNodeDrain returns whether the pods of the node named nodeName could have been rescheduled onto the other
+// nodes had it been drained at the timestamp
+func (r *replayer) SimulateNodeDrain(ctx context.Context, effectiveAt time.Time, nodeName string) (*model.DrainSimulation, error) {
+	cluster, err := r.clusterAt(ctx, effectiveAt)
+	if err != nil {
+		return nil, err
+	}
+
+	drain, err := cluster.Drain(nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to drain node at %s: %v", effectiveAt.Format(time.RFC3339), err)
+	}
+
+	simulation := &model.DrainSimulation{
+		Timestamp:  effectiveAt,
+		NodeID:     drain.NodeID,
+		NodeName:   drain.NodeName,
+		Feasible:   drain.Feasible(),
+		Placements: []*model.PodPlacement{},
+	}
+	for _, placement := range drain.Placements {
+		p := &model.PodPlacement{
+			PodID:     placement.Pod.ID,
+			Namespace: placement.Pod.Namespace,
+			Name:      placement.Pod.Name,
+			Reasons:   placement.Reasons,
+		}
+		if placement.NodeID != "" {
+			p.NodeID, p.NodeName = &placement.NodeID, &placement.NodeName
+		}
+		simulation.Placements = append(simulation.Placements, p)
+	}
+
+	return simulation, nil
+}
+
+// clusterAt returns the cluster at the timestamp to simulate scheduling on
+func (r *replayer) clusterAt(ctx context.Context, effectiveAt time.Time) (*simulator.Cluster, error) {
+	nodes, err := r.store.GetAllBetween(ctx, effectiveAt, effectiveAt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get all nodes in cluster: %v", err)
+	}
+
+	return simulator.NewCluster(data.StateAt(nodes, effectiveAt)), nil
+}
+
+// hypotheticalPod returns the pod to place for the input
+func hypotheticalPod(input *model.HypotheticalPodInput) (*simulator.Pod, error) {
+	pod := &simulator.Pod{
+		ID:           "hypothetical",
+		Name:         input.Name,
+		Requests:     corev1.ResourceList{},
+		NodeSelector: map[string]string{},
+	}
+	if input.Namespace != nil {
+		pod.Namespace = *input.Namespace
+	}
+
+	if input.Requests != nil {
+		for name, value := range map[corev1.ResourceName]*string{
+			corev1.ResourceCPU:              input.Requests.CPU,
+			corev1.ResourceMemory:           input.Requests.Memory,
+			corev1.ResourceEphemeralStorage: input.Requests.EphemeralStorage,
+		} {
+			if value == nil {
+				continue
+			}
+
+			quantity, err := resource.ParseQuantity(*value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s request %q: %v", ErrInvalidPod, name, *value, err)
+			}
+			pod.Requests[name] = quantity
+		}
+	}
+
+	for _, toleration := range input.Tolerations {
+		t := corev1.Toleration{Operator: corev1.TolerationOpEqual}
+		if toleration.Key != nil {
+			t.Key = *toleration.Key
+		}
+		if toleration.Operator != nil {
+			t.Operator = corev1.TolerationOperator(*toleration.Operator)
+		}
+		if t.Operator != corev1.TolerationOpEqual && t.Operator != corev1.TolerationOpExists {
+			return nil, fmt.Errorf("%w: toleration operator %q, expected Equal or Exists", ErrInvalidPod, t.Operator)
+		}
+		if toleration.Value != nil {
+			t.Value = *toleration.Value
+		}
+		if toleration.Effect != nil {
+			t.Effect = corev1.TaintEffect(*toleration.Effect)
+		}
+		pod.Tolerations = append(pod.Tolerations, t)
+	}
+
+	for _, label := range input.NodeSelector {
+		pod.NodeSelector[label.Key] = label.Value
+	}
+
+	return pod, nil
+}
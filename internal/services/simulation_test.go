@@ -0,0 +1,58 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+func TestReplayer_Simulate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	ctx := context.Background()
+	store := repositories.NewMemoryStore()
+
+	for _, name := range []string{"a", "b"} {
+		err := store.Upsert(ctx, &data.NodeMeta{
+			ID:   "node-" + name,
+			Name: name,
+			Snapshots: data.NodeSnapshots{{Timestamp: t0, State: data.NodeState{
+				Allocatable: data.NodeCapacity{Cpu: "2", Memory: "4Gi", Pods: 110},
+				Taints:      []*data.Taint{{Key: "dedicated", Value: name, Effect: "NoSchedule"}},
+			}}},
+		})
+		g.Expect(err).To(gomega.BeNil())
+	}
+
+	replayer := services.NewReplayerWithStore(store)
+	key, value, cpu := "dedicated", "b", "1"
+	simulation, err := replayer.SimulatePodFit(ctx, t0, &model.HypotheticalPodInput{
+		Name:        "web-0",
+		Requests:    &model.ContainerResourceInput{CPU: &cpu},
+		Tolerations: []*model.TolerationInput{{Key: &key, Value: &value}},
+	})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(simulation.Fits).To(gomega.BeTrue())
+	g.Expect(*simulation.NodeName).To(gomega.Equal("b"))
+	g.Expect(simulation.Nodes[0].Fits).To(gomega.BeFalse())
+	// half the CPU and none of the memory
+	g.Expect(simulation.Nodes[1].Score).To(gomega.BeNumerically("~", 0.25))
+
+	cpu = "lots"
+	_, err = replayer.SimulatePodFit(ctx, t0, &model.HypotheticalPodInput{Name: "web-0", Requests: &model.ContainerResourceInput{CPU: &cpu}})
+	g.Expect(err).To(gomega.MatchError(services.ErrInvalidPod))
+
+	drain, err := replayer.SimulateNodeDrain(ctx, t0, "a")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(drain.NodeID).To(gomega.Equal("node-a"))
+	g.Expect(drain.Feasible).To(gomega.BeTrue())
+	g.Expect(drain.Placements).To(gomega.BeEmpty())
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/versions"
+)
+
+var components = map[model.NodeComponent]versions.Component{
+	model.NodeComponentKubelet:          versions.Kubelet,
+	model.NodeComponentKubeProxy:        versions.KubeProxy,
+	model.NodeComponentKernel:           versions.Kernel,
+	model.NodeComponentContainerRuntime: versions.ContainerRuntime,
+	model.NodeComponentOsImage:          versions.OSImage,
+}
+
+// VersionReport returns how many nodes ran each version of each component at effectiveAt, and the nodes whose kubelet
+// or kube-proxy is more than maxMinorSkew minor versions behind reference, the newest kubelet if nil
+func (r *replayer) VersionReport(ctx context.Context, effectiveAt time.Time, reference *string, maxMinorSkew int) (*model.VersionReport, error) {
+	if maxMinorSkew < 0 {
+		return nil, fmt.Errorf("%w: max minor skew must be >= 0, got %d", ErrInvalidVersion, maxMinorSkew)
+	}
+	if reference != nil {
+		if _, err := utilversion.ParseGeneric(*reference); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidVersion, err)
+		}
+	}
+
+	nodes, err := r.store.GetAllBetween(ctx, effectiveAt, effectiveAt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get all nodes in cluster: %v", err)
+	}
+	state := data.StateAt(nodes, effectiveAt)
+
+	report := &model.VersionReport{
+		Timestamp:    effectiveAt,
+		Reference:    versions.Newest(state, versions.Kubelet),
+		MaxMinorSkew: int32(maxMinorSkew),
+		Components:   []*model.ComponentVersions{},
+		Skewed:       []*model.VersionSkew{},
+	}
+	if reference != nil {
+		report.Reference = *reference
+	}
+
+	for _, component := range model.AllNodeComponent {
+		report.Components = append(report.Components, &model.ComponentVersions{
+			Component: component,
+			Versions:  versionCounts(versions.Distribution(state, components[component])),
+		})
+	}
+
+	if report.Reference == "" {
+		return report, nil
+	}
+	skews, err := versions.Skewed(state, report.Reference, maxMinorSkew)
+	if err != nil {
+		// the newest kubelet can't be parsed, so there's nothing to compare to
+		return report, nil
+	}
+	for _, skew := range skews {
+		report.Skewed = append(report.Skewed, &model.VersionSkew{
+			NodeID:       skew.NodeID,
+			NodeName:     skew.NodeName,
+			Component:    modelComponent(skew.Component),
+			Version:      skew.Version,
+			MinorsBehind: int32(skew.MinorsBehind),
+		})
+	}
+
+	return report, nil
+}
+
+// UpgradeProgress returns the progress of each group of nodes upgrading the component to target between beginAt and
+// endAt, or to the newest version at endAt if target is nil
+func (r *replayer) UpgradeProgress(ctx context.Context, beginAt, endAt time.Time, component model.NodeComponent, target *string) (*model.UpgradeProgress, error) {
+	states, err := r.eventStates(ctx, beginAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &model.UpgradeProgress{Component: component, Frames: []*model.UpgradeFrame{}}
+	if target != nil {
+		progress.Target = *target
+	} else {
+		// the states are swept again for the progress, rather than held on to
+		var last *data.ClusterState
+		for state := range states {
+			last = state
+		}
+		progress.Target = versions.Newest(last, components[component])
+	}
+
+	for _, frame := range versions.Progress(states, components[component], progress.Target) {
+		upgradeFrame := &model.UpgradeFrame{Timestamp: frame.Timestamp, Groups: []*model.NodeGroupUpgrade{}}
+		for _, group := range frame.Groups {
+			upgradeFrame.Groups = append(upgradeFrame.Groups, &model.NodeGroupUpgrade{
+				Roles:        group.Roles,
+				Architecture: group.Architecture,
+				Provider:     group.Provider,
+				InstanceType: group.InstanceType,
+				Nodes:        int32(group.Nodes),
+				Upgraded:     int32(group.Upgraded),
+				Versions:     versionCounts(group.Versions),
+			})
+		}
+		progress.Frames = append(progress.Frames, upgradeFrame)
+	}
+
+	return progress, nil
+}
+
+func versionCounts(counts []versions.Count) []*model.VersionCount {
+	versionCounts := make([]*model.VersionCount, 0, len(counts))
+	for _, count := range counts {
+		versionCounts = append(versionCounts, &model.VersionCount{Version: count.Version, Nodes: int32(count.Nodes)})
+	}
+
+	return versionCounts
+}
+
+func modelComponent(component versions.Component) model.NodeComponent {
+	for modelComponent, c := range components {
+		if c == component {
+			return modelComponent
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/simulator"
+	"github.com/ccpeng/kube-replay/internal/utils"
+)
+
+// WorkloadsAtTimestamp returns the pods at effectiveAt matching the filter grouped by the workload managing them,
+// ordered by namespace, name and kind
+func (r *replayer) WorkloadsAtTimestamp(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) ([]*model.WorkloadSummary, error) {
+	snapshotFilter, err := newSnapshotFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := r.store.GetAllBetween(ctx, effectiveAt, effectiveAt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get all nodes in cluster: %v", err)
+	}
+
+	type key struct {
+		namespace string
+		data.Workload
+	}
+	type summary struct {
+		model.WorkloadSummary
+		phases      map[model.PodPhase]int
+		cpu, memory resource.Quantity
+	}
+	summaries := map[key]*summary{}
+	for _, node := range snapshotFilter.apply(data.StateAt(nodes, effectiveAt)).Nodes {
+		for _, podAt := range node.Pods {
+			k := key{namespace: podAt.Meta.Namespace, Workload: podAt.Meta.Workload()}
+			s, ok := summaries[k]
+			if !ok {
+				s = &summary{
+					WorkloadSummary: model.WorkloadSummary{Namespace: k.namespace, Kind: k.Kind, Name: k.Name, NodeNames: []string{}},
+					phases:          map[model.PodPhase]int{},
+				}
+				summaries[k] = s
+			}
+
+			s.Pods++
+			s.phases[utils.TransformToModelPodPhase(podAt.Snapshot.Status)]++
+			if !slices.Contains(s.NodeNames, node.Meta.Name) {
+				s.NodeNames = append(s.NodeNames, node.Meta.Name)
+			}
+			if !simulator.Terminated(podAt) {
+				requests := simulator.NewPod(podAt).Requests
+				s.cpu.Add(requests[corev1.ResourceCPU])
+				s.memory.Add(requests[corev1.ResourceMemory])
+			}
+		}
+	}
+
+	workloads := make([]*model.WorkloadSummary, 0, len(summaries))
+	for _, s := range summaries {
+		workload := s.WorkloadSummary
+		workload.CPURequests, workload.MemoryRequests = s.cpu.String(), s.memory.String()
+		workload.Phases = []*model.PhaseCount{}
+		for _, phase := range model.AllPodPhase {
+			if s.phases[phase] > 0 {
+				workload.Phases = append(workload.Phases, &model.PhaseCount{Phase: phase, Pods: int32(s.phases[phase])})
+			}
+		}
+		sort.Strings(workload.NodeNames)
+		workloads = append(workloads, &workload)
+	}
+	sort.Slice(workloads, func(i, j int) bool {
+		a, b := workloads[i], workloads[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Kind < b.Kind
+	})
+
+	return workloads, nil
+}
@@ -0,0 +1,77 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+func TestReplayer_Workloads(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	store := repositories.NewMemoryStore()
+	g.Expect(store.Upsert(context.Background(), &data.NodeMeta{
+		ID: "node-a", Name: "a", Snapshots: data.NodeSnapshots{{Timestamp: t0}},
+	})).To(gomega.Succeed())
+
+	replayer := services.NewReplayerWithStore(store, services.WithPodAnnotations([]string{"example.com/*"}))
+
+	nodeID, namespace, controller := "node-a", "shop", true
+	pod := func(id, name, hash string, phase model.PodPhase) *model.PodSnapshotInput {
+		return &model.PodSnapshotInput{
+			ID: id, NodeID: &nodeID, Timestamp: t0, Name: name, Namespace: &namespace, Status: phase,
+			Containers: []*model.ContainerSnapshotInput{}, StartedAt: t0, QosClass: model.PodQOSClassBestEffort,
+			Labels: []*model.LabelInput{{Key: "app", Value: "web"}, {Key: "pod-template-hash", Value: hash}},
+			Annotations: []*model.LabelInput{
+				{Key: "example.com/team", Value: "storefront"},
+				{Key: "kubectl.kubernetes.io/last-applied-configuration", Value: "{}"},
+			},
+			OwnerReferences: []*model.OwnerReferenceInput{{Kind: "ReplicaSet", Name: "web-" + hash, UID: "rs-" + hash, Controller: &controller}},
+		}
+	}
+	g.Expect(replayer.RecordPodSnapshots(context.Background(), []*model.PodSnapshotInput{
+		pod("uid-1", "web-7d9f8b6c5d-x2vzq", "7d9f8b6c5d", model.PodPhaseRunning),
+		pod("uid-2", "web-5c8b7f9d6d-q8wzt", "5c8b7f9d6d", model.PodPhasePending),
+		{
+			ID: "uid-3", NodeID: &nodeID, Timestamp: t0, Name: "db-0", Namespace: &namespace, Status: model.PodPhaseRunning,
+			Containers: []*model.ContainerSnapshotInput{}, StartedAt: t0, QosClass: model.PodQOSClassBestEffort,
+		},
+	})).To(gomega.Succeed())
+
+	// only the selected annotations were recorded
+	selector := "app=web"
+	snapshots, err := replayer.EffectiveAtSnapshot(context.Background(), t0, &model.SnapshotFilter{
+		Pods: &model.PodFilter{LabelSelector: &selector},
+	})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(snapshots.Nodes[0].Pods).To(gomega.HaveLen(2))
+	g.Expect(snapshots.Nodes[0].Pods[0].Annotations).To(gomega.Equal([]*model.Label{{Key: "example.com/team", Value: "storefront"}}))
+	g.Expect(snapshots.Nodes[0].Pods[0].Workload).To(gomega.Equal(&model.Workload{Kind: "Deployment", Name: "web"}))
+
+	workloads, err := replayer.WorkloadsAtTimestamp(context.Background(), t0, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(workloads).To(gomega.HaveLen(2))
+	g.Expect(workloads[0].Name).To(gomega.Equal("db"))
+	g.Expect(workloads[0].Kind).To(gomega.BeEmpty())
+	g.Expect(workloads[1].Kind).To(gomega.Equal("Deployment"))
+	g.Expect(workloads[1].Pods).To(gomega.BeEquivalentTo(2))
+	g.Expect(workloads[1].Phases).To(gomega.Equal([]*model.PhaseCount{
+		{Phase: model.PodPhasePending, Pods: 1},
+		{Phase: model.PodPhaseRunning, Pods: 1},
+	}))
+	g.Expect(workloads[1].NodeNames).To(gomega.Equal([]string{"a"}))
+
+	invalid := "app in web"
+	_, err = replayer.WorkloadsAtTimestamp(context.Background(), t0, &model.SnapshotFilter{
+		Pods: &model.PodFilter{LabelSelector: &invalid},
+	})
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("invalid pod label selector")))
+}
@@ -0,0 +1,401 @@
+package simulator
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+// scheduledResources are the resources fit is checked against, besides the count of pods
+var scheduledResources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage}
+
+// Pod is a pod to place onto the cluster, by what the scheduler considers for it
+type Pod struct {
+	ID           string
+	Namespace    string
+	Name         string
+	Requests     corev1.ResourceList
+	Limits       corev1.ResourceList
+	Tolerations  []corev1.Toleration
+	NodeSelector map[string]string
+	NodeAffinity []labels.Selector // required node selector terms, any of which a node must match
+	NodeBound    bool              // a DaemonSet or mirror pod, left on its node when it's drained
+}
+
+// Constraint is a kind of reason a pod doesn't fit on a node
+type Constraint int
+
+const (
+	Cordoned Constraint = iota
+	Tainted
+	NodeSelector
+	InsufficientResources
+	TooManyPods
+)
+
+// NodeFit is whether a pod fits on a node, and if not why
+type NodeFit struct {
+	NodeID      string
+	NodeName    string
+	Reasons     []string
+	Constraints []Constraint // of each reason
+	Score       float64      // share of allocatable CPU and memory requested once the pod is placed, from 0 to 1
+}
+
+// Fits reports whether nothing keeps the pod off the node
+func (f *NodeFit) Fits() bool {
+	return len(f.Reasons) == 0
+}
+
+// Placement is the node a pod was placed onto, or why it couldn't be placed if none
+type Placement struct {
+	Pod      *Pod
+	NodeID   string
+	NodeName string
+	Reasons  []string
+}
+
+// Drain is where the pods of a drained node were placed
+type Drain struct {
+	NodeID     string
+	NodeName   string
+	Placements []*Placement
+}
+
+// Feasible reports whether every pod of the drained node was placed
+func (d *Drain) Feasible() bool {
+	for _, placement := range d.Placements {
+		if placement.NodeID == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// node is a node of the cluster with what's requested of it
+type node struct {
+	at          *data.NodeAt
+	allocatable corev1.ResourceList
+	requested   corev1.ResourceList
+	pods        []*Pod
+}
+
+// Cluster is the nodes of a cluster state and the pods bound to them, that pods are placed onto as the scheduler would
+// by their requests, taints and tolerations, and whether nodes are cordoned. Pods are packed onto the nodes that fit
+// them with the most CPU and memory requested, to find out whether the cluster could have done with fewer nodes.
+type Cluster struct {
+	nodes []*node
+}
+
+// NewCluster returns the cluster of the state. Pods that have terminated don't request anything, and pods recorded
+// before their tolerations were are taken to tolerate nothing.
+func NewCluster(state *data.ClusterState) *Cluster {
+	c := &Cluster{}
+	for _, nodeAt := range state.Nodes {
+		n := &node{
+			at:          nodeAt,
+			allocatable: Allocatable(nodeAt.Snapshot.State.Allocatable),
+			requested:   corev1.ResourceList{},
+		}
+		for _, podAt := range nodeAt.Pods {
+			if Terminated(podAt) {
+				continue
+			}
+			n.add(NewPod(podAt))
+		}
+		c.nodes = append(c.nodes, n)
+	}
+
+	return c
+}
+
+// Terminated reports whether the pod had run to completion, so it no longer requests anything of its node
+func Terminated(podAt *data.PodAt) bool {
+	return podAt.Snapshot.Status == data.PodPhaseSucceeded || podAt.Snapshot.Status == data.PodPhaseFailed
+}
+
+// NewPod returns the pod to place as it was bound to its node, requesting the most of either all its containers or
+// any of its init containers, which run one at a time before them. Required node affinity terms that can't be parsed
+// are left out.
+func NewPod(podAt *data.PodAt) *Pod {
+	pod := &Pod{
+		ID:           podAt.Meta.ID,
+		Namespace:    podAt.Meta.Namespace,
+		Name:         podAt.Meta.Name,
+		Requests:     effective(podAt.Snapshot, func(r data.ContainerResources) data.ContainerResource { return r.Requests }),
+		Limits:       effective(podAt.Snapshot, func(r data.ContainerResources) data.ContainerResource { return r.Limits }),
+		NodeSelector: podAt.Meta.NodeSelector,
+		NodeBound:    podAt.Meta.NodeBound(),
+	}
+	for _, toleration := range podAt.Meta.Tolerations {
+		pod.Tolerations = append(pod.Tolerations, corev1.Toleration{
+			Key:               toleration.Key,
+			Operator:          corev1.TolerationOperator(toleration.Operator),
+			Value:             toleration.Value,
+			Effect:            corev1.TaintEffect(toleration.Effect),
+			TolerationSeconds: toleration.TolerationSeconds,
+		})
+	}
+	if podAt.Meta.Affinity != nil {
+		// terms are validated when recorded, so only those of pods recorded before can fail to parse
+		for _, term := range podAt.Meta.Affinity.RequiredNodeTerms {
+			if selector, err := labels.Parse(term); err == nil {
+				pod.NodeAffinity = append(pod.NodeAffinity, selector)
+			}
+		}
+	}
+
+	return pod
+}
+
+// effective returns the sum of the resources of the containers of the pod, or those of its largest init container
+func effective(pod *data.PodSnapshot, resources func(data.ContainerResources) data.ContainerResource) corev1.ResourceList {
+	effective := corev1.ResourceList{}
+	for _, container := range pod.Containers {
+		for name, quantity := range quantities(resources(container.Resources)) {
+			sum := effective[name]
+			sum.Add(quantity)
+			effective[name] = sum
+		}
+	}
+	for _, container := range pod.InitContainers {
+		for name, quantity := range quantities(resources(container.Resources)) {
+			if current, ok := effective[name]; !ok || quantity.Cmp(current) > 0 {
+				effective[name] = quantity
+			}
+		}
+	}
+
+	return effective
+}
+
+// Fit returns whether the pod fits on each node, ordered by name
+func (c *Cluster) Fit(pod *Pod) []*NodeFit {
+	fits := make([]*NodeFit, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		fits = append(fits, n.fit(pod))
+	}
+	sort.SliceStable(fits, func(i, j int) bool {
+		return fits[i].NodeName < fits[j].NodeName
+	})
+
+	return fits
+}
+
+// Place places the pod onto the node fitting it with the highest score, returning the fit of every node
+func (c *Cluster) Place(pod *Pod) (*Placement, []*NodeFit) {
+	fits := c.Fit(pod)
+
+	var best *NodeFit
+	var reasons []string
+	for _, fit := range fits {
+		if !fit.Fits() {
+			for _, reason := range fit.Reasons {
+				reasons = append(reasons, fmt.Sprintf("node %s: %s", fit.NodeName, reason))
+			}
+			continue
+		}
+		if best == nil || fit.Score > best.Score {
+			best = fit
+		}
+	}
+
+	if best == nil {
+		if len(fits) == 0 {
+			reasons = append(reasons, "no nodes in the cluster")
+		}
+		return &Placement{Pod: pod, Reasons: reasons}, fits
+	}
+
+	for _, n := range c.nodes {
+		if n.at.Meta.ID == best.NodeID {
+			n.add(pod)
+		}
+	}
+
+	return &Placement{Pod: pod, NodeID: best.NodeID, NodeName: best.NodeName, Reasons: []string{}}, fits
+}
+
+// Remove removes the pod with the ID from the node it's bound to, reporting whether it was bound to any
+func (c *Cluster) Remove(podID string) bool {
+	for _, n := range c.nodes {
+		for i, pod := range n.pods {
+			if pod.ID != podID {
+				continue
+			}
+
+			for name, quantity := range pod.Requests {
+				sum := n.requested[name]
+				sum.Sub(quantity)
+				n.requested[name] = sum
+			}
+			n.pods = append(n.pods[:i:i], n.pods[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Drain removes the node named nodeName and places its pods onto the others, those requesting the most first. Like
+// kubectl drain, DaemonSet and mirror pods aren't moved.
+func (c *Cluster) Drain(nodeName string) (*Drain, error) {
+	var drained *node
+	remaining := make([]*node, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		if n.at.Meta.Name == nodeName {
+			drained = n
+		} else {
+			remaining = append(remaining, n)
+		}
+	}
+	if drained == nil {
+		return nil, fmt.Errorf("node %s isn't in the cluster", nodeName)
+	}
+	c.nodes = remaining
+
+	pods := make([]*Pod, 0, len(drained.pods))
+	for _, pod := range drained.pods {
+		if !pod.NodeBound {
+			pods = append(pods, pod)
+		}
+	}
+	sort.SliceStable(pods, func(i, j int) bool {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			a, b := pods[i].Requests[name], pods[j].Requests[name]
+			if cmp := a.Cmp(b); cmp != 0 {
+				return cmp > 0
+			}
+		}
+		return pods[i].Namespace+"/"+pods[i].Name < pods[j].Namespace+"/"+pods[j].Name
+	})
+
+	drain := &Drain{NodeID: drained.at.Meta.ID, NodeName: drained.at.Meta.Name}
+	for _, pod := range pods {
+		placement, _ := c.Place(pod)
+		drain.Placements = append(drain.Placements, placement)
+	}
+
+	return drain, nil
+}
+
+func (n *node) add(pod *Pod) {
+	for name, quantity := range pod.Requests {
+		sum := n.requested[name]
+		sum.Add(quantity)
+		n.requested[name] = sum
+	}
+	n.pods = append(n.pods, pod)
+}
+
+// fit returns whether the pod fits on the node, scored by how much of it would be requested once it's placed
+func (n *node) fit(pod *Pod) *NodeFit {
+	fit := &NodeFit{NodeID: n.at.Meta.ID, NodeName: n.at.Meta.Name, Reasons: []string{}}
+	unfit := func(constraint Constraint, format string, args ...interface{}) {
+		fit.Reasons = append(fit.Reasons, fmt.Sprintf(format, args...))
+		fit.Constraints = append(fit.Constraints, constraint)
+	}
+	state := n.at.Snapshot.State
+
+	if state.Unschedulable {
+		unfit(Cordoned, "node is unschedulable")
+	}
+
+	for _, taint := range state.Taints {
+		t := corev1.Taint{Key: taint.Key, Value: taint.Value, Effect: corev1.TaintEffect(taint.Effect)}
+		if t.Effect != corev1.TaintEffectNoSchedule && t.Effect != corev1.TaintEffectNoExecute {
+			continue
+		}
+		if !tolerates(pod.Tolerations, &t) {
+			unfit(Tainted, "untolerated taint %s", t.ToString())
+		}
+	}
+
+	for k, v := range pod.NodeSelector {
+		if n.at.Meta.Labels[k] != v {
+			unfit(NodeSelector, "node selector %s=%s doesn't match", k, v)
+		}
+	}
+
+	if len(pod.NodeAffinity) > 0 && !slices.ContainsFunc(pod.NodeAffinity, func(selector labels.Selector) bool {
+		return selector.Matches(labels.Set(n.at.Meta.Labels))
+	}) {
+		unfit(NodeSelector, "required node affinity doesn't match")
+	}
+
+	for _, name := range scheduledResources {
+		requested, ok := pod.Requests[name]
+		if !ok || requested.IsZero() {
+			continue
+		}
+
+		free := n.allocatable[name]
+		free.Sub(n.requested[name])
+		if requested.Cmp(free) > 0 {
+			unfit(InsufficientResources, "insufficient %s, %s requested but %s free", name, requested.String(), free.String())
+		}
+	}
+	if pods, ok := n.allocatable[corev1.ResourcePods]; ok && int64(len(n.pods)) >= pods.Value() {
+		unfit(TooManyPods, "too many pods, %d allowed", pods.Value())
+	}
+
+	var shares []float64
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		allocatable := n.allocatable[name]
+		if allocatable.IsZero() {
+			continue
+		}
+
+		requested := n.requested[name]
+		requested.Add(pod.Requests[name])
+		shares = append(shares, float64(requested.MilliValue())/float64(allocatable.MilliValue()))
+	}
+	for _, share := range shares {
+		fit.Score += share / float64(len(shares))
+	}
+
+	return fit
+}
+
+func tolerates(tolerations []corev1.Toleration, taint *corev1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Allocatable returns the quantities of the capacity that are set and can be parsed
+func Allocatable(capacity data.NodeCapacity) corev1.ResourceList {
+	resources := quantities(data.ContainerResource{Cpu: capacity.Cpu, Memory: capacity.Memory, EphemeralStorage: capacity.EphemeralStorage})
+	if capacity.Pods > 0 {
+		resources[corev1.ResourcePods] = *resource.NewQuantity(capacity.Pods, resource.DecimalSI)
+	}
+
+	return resources
+}
+
+// quantities returns the quantities of the resource that are set and can be parsed
+func quantities(r data.ContainerResource) corev1.ResourceList {
+	resources := corev1.ResourceList{}
+	for name, value := range map[corev1.ResourceName]string{
+		corev1.ResourceCPU:              r.Cpu,
+		corev1.ResourceMemory:           r.Memory,
+		corev1.ResourceEphemeralStorage: r.EphemeralStorage,
+	} {
+		if quantity, err := resource.ParseQuantity(value); value != "" && err == nil {
+			resources[name] = quantity
+		}
+	}
+
+	return resources
+}
@@ -0,0 +1,109 @@
+package simulator_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/simulator"
+)
+
+func TestCluster(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	pod := func(id string, cpu, memory string, phase data.PodPhase) *data.PodMeta {
+		return &data.PodMeta{ID: id, Name: id, Namespace: "shop", Snapshots: data.PodSnapshots{{
+			Timestamp:  t0,
+			Status:     phase,
+			Containers: []*data.ContainerSnapshot{{Resources: data.ContainerResources{Requests: data.ContainerResource{Cpu: cpu, Memory: memory}}}},
+		}}}
+	}
+	node := func(id string, state data.NodeState, pods ...*data.PodMeta) *data.NodeMeta {
+		state.Allocatable = data.NodeCapacity{Cpu: "2", Memory: "4Gi", Pods: 110}
+		return &data.NodeMeta{ID: id, Name: id, Labels: map[string]string{"zone": "a"}, Snapshots: data.NodeSnapshots{{Timestamp: t0, State: state}}, Pods: pods}
+	}
+
+	agent := pod("agent-a", "", "", data.PodPhaseRunning)
+	agent.Owners = []*data.OwnerReference{{Kind: "DaemonSet", Name: "agent", Controller: true}}
+	proxy := pod("kube-proxy-a", "", "", data.PodPhaseRunning)
+	proxy.Owners = []*data.OwnerReference{{Kind: "Node", Name: "a", Controller: true}}
+
+	// a is half full, b is nearly full but a finished pod doesn't count, c is cordoned and d is tainted
+	nodes := []*data.NodeMeta{
+		node("a", data.NodeState{}, pod("web-0", "1", "1Gi", data.PodPhaseRunning), agent, proxy),
+		node("b", data.NodeState{}, pod("web-1", "1500m", "2Gi", data.PodPhaseRunning), pod("job-0", "2", "1Gi", data.PodPhaseSucceeded)),
+		node("c", data.NodeState{Unschedulable: true}),
+		node("d", data.NodeState{Taints: []*data.Taint{{Key: "gpu", Value: "true", Effect: "NoSchedule"}}}),
+	}
+	for _, n := range nodes {
+		for _, p := range n.Pods {
+			p.SetDynamoAttributes(n.ID)
+		}
+	}
+	state := data.StateAt(nodes, t0)
+
+	small := &simulator.Pod{Name: "small", Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")}}
+	placement, fits := simulator.NewCluster(state).Place(small)
+	g.Expect(fits).To(gomega.HaveLen(4))
+	g.Expect(fits[0].Fits()).To(gomega.BeTrue())
+	g.Expect(fits[1].Fits()).To(gomega.BeTrue())
+	g.Expect(fits[2].Reasons).To(gomega.Equal([]string{"node is unschedulable"}))
+	g.Expect(fits[3].Reasons).To(gomega.Equal([]string{"untolerated taint gpu=true:NoSchedule"}))
+	g.Expect(fits[3].Constraints).To(gomega.Equal([]simulator.Constraint{simulator.Tainted}))
+	// packed onto the node with the most requested
+	g.Expect(placement.NodeName).To(gomega.Equal("b"))
+
+	large := &simulator.Pod{Name: "large", Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1500m")}}
+	placement, fits = simulator.NewCluster(state).Place(large)
+	g.Expect(placement.NodeID).To(gomega.BeEmpty())
+	g.Expect(fits[0].Reasons).To(gomega.Equal([]string{"insufficient cpu, 1500m requested but 1 free"}))
+	g.Expect(placement.Reasons).To(gomega.HaveLen(4))
+
+	// fits on a once web-0 is gone
+	cluster := simulator.NewCluster(state)
+	g.Expect(cluster.Remove("web-0")).To(gomega.BeTrue())
+	g.Expect(cluster.Remove("web-0")).To(gomega.BeFalse())
+	placement, _ = cluster.Place(large)
+	g.Expect(placement.NodeName).To(gomega.Equal("a"))
+
+	large.Tolerations = []corev1.Toleration{{Key: "gpu", Operator: corev1.TolerationOpExists}}
+	placement, _ = simulator.NewCluster(state).Place(large)
+	g.Expect(placement.NodeName).To(gomega.Equal("d"))
+
+	large.NodeSelector = map[string]string{"zone": "b"}
+	placement, _ = simulator.NewCluster(state).Place(large)
+	g.Expect(placement.NodeID).To(gomega.BeEmpty())
+	g.Expect(placement.Reasons).To(gomega.ContainElement("node d: node selector zone=b doesn't match"))
+
+	// recorded tolerations and required node affinity carry over to the simulated pod
+	recorded := pod("gpu-0", "250m", "", data.PodPhasePending)
+	recorded.Tolerations = []*data.Toleration{{Key: "gpu", Operator: "Equal", Value: "true", Effect: "NoSchedule"}}
+	recorded.Affinity = &data.Affinity{RequiredNodeTerms: []string{"zone in (b)", "zone=a,!spot"}}
+	fits = simulator.NewCluster(state).Fit(simulator.NewPod(&data.PodAt{Meta: recorded, Snapshot: recorded.Snapshots[0]}))
+	g.Expect(fits[3].Fits()).To(gomega.BeTrue())
+	recorded.Affinity.RequiredNodeTerms = []string{"zone in (b)"}
+	fits = simulator.NewCluster(state).Fit(simulator.NewPod(&data.PodAt{Meta: recorded, Snapshot: recorded.Snapshots[0]}))
+	g.Expect(fits[3].Reasons).To(gomega.Equal([]string{"required node affinity doesn't match"}))
+	g.Expect(fits[3].Constraints).To(gomega.Equal([]simulator.Constraint{simulator.NodeSelector}))
+
+	// web-0 fits on b alongside web-1 only by its memory, but not its CPU, while the DaemonSet and mirror pods stay
+	drain, err := simulator.NewCluster(state).Drain("a")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(drain.Feasible()).To(gomega.BeFalse())
+	g.Expect(drain.Placements).To(gomega.HaveLen(1))
+	g.Expect(drain.Placements[0].Pod.Name).To(gomega.Equal("web-0"))
+
+	nodes[2].Snapshots[0].State.Unschedulable = false
+	drain, err = simulator.NewCluster(data.StateAt(nodes, t0)).Drain("b")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(drain.Feasible()).To(gomega.BeTrue())
+	g.Expect(drain.Placements[0].NodeName).To(gomega.Equal("c"))
+
+	_, err = simulator.NewCluster(state).Drain("e")
+	g.Expect(err).To(gomega.MatchError("node e isn't in the cluster"))
+}
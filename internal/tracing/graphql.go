@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// graphQLTracer is a gqlgen extension starting a span for every operation, and a child span for every field resolved
+// by a resolver rather than read off its parent
+type graphQLTracer struct {
+	tracer trace.Tracer
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = &graphQLTracer{}
+
+func (t *graphQLTracer) ExtensionName() string {
+	return "Tracing"
+}
+
+func (t *graphQLTracer) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (t *graphQLTracer) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	name := "graphql"
+	if graphql.HasOperationContext(ctx) {
+		if op := graphql.GetOperationContext(ctx).Operation; op != nil {
+			name = fmt.Sprintf("%s %s", op.Operation, op.Name)
+		}
+	}
+
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
+	resp := next(ctx)
+	if resp != nil && len(resp.Errors) > 0 {
+		span.SetStatus(codes.Error, resp.Errors.Error())
+	}
+
+	return resp
+}
+
+func (t *graphQLTracer) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+
+	ctx, span := t.tracer.Start(ctx, fmt.Sprintf("%s.%s", fc.Object, fc.Field.Name), trace.WithAttributes(
+		attribute.String("graphql.path", fc.Path().String())))
+	res, err := next(ctx)
+	end(span, err)
+
+	return res, err
+}
+
+// NewGraphQLExtension returns a gqlgen extension tracing operations and resolvers with tracers from tp
+func NewGraphQLExtension(tp trace.TracerProvider) graphql.HandlerExtension {
+	return &graphQLTracer{tracer: tp.Tracer(instrumentationName)}
+}
@@ -0,0 +1,265 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ccpeng/kube-replay/graph/model"
+	"github.com/ccpeng/kube-replay/internal/manifests"
+	"github.com/ccpeng/kube-replay/internal/services"
+)
+
+// tracedReplayer starts a span for every call to the wrapped replayer, with the count of frames replayed
+type tracedReplayer struct {
+	replayer services.Replayer
+	tracer   trace.Tracer
+}
+
+func (r *tracedReplayer) RecordNodeSnapshot(ctx context.Context, snapshot *model.NodeSnapshotInput) (err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.RecordNodeSnapshot", trace.WithAttributes(attribute.String("node.id", snapshot.ID)))
+	defer func() { end(span, err) }()
+
+	return r.replayer.RecordNodeSnapshot(ctx, snapshot)
+}
+
+func (r *tracedReplayer) RecordPodSnapshots(ctx context.Context, snapshots []*model.PodSnapshotInput) (err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.RecordPodSnapshots", trace.WithAttributes(attribute.Int("items.pod_snapshots", len(snapshots))))
+	defer func() { end(span, err) }()
+
+	return r.replayer.RecordPodSnapshots(ctx, snapshots)
+}
+
+func (r *tracedReplayer) RecordNodeDeletion(ctx context.Context, deletion *model.NodeDeletionInput) (err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.RecordNodeDeletion", trace.WithAttributes(attribute.String("node.id", deletion.ID)))
+	defer func() { end(span, err) }()
+
+	return r.replayer.RecordNodeDeletion(ctx, deletion)
+}
+
+func (r *tracedReplayer) RecordPodDeletion(ctx context.Context, deletion *model.PodDeletionInput) (err error) {
+	attributes := []attribute.KeyValue{attribute.String("pod.id", deletion.ID)}
+	if deletion.NodeID != nil {
+		attributes = append(attributes, attribute.String("node.id", *deletion.NodeID))
+	}
+	ctx, span := r.tracer.Start(ctx, "Replayer.RecordPodDeletion", trace.WithAttributes(attributes...))
+	defer func() { end(span, err) }()
+
+	return r.replayer.RecordPodDeletion(ctx, deletion)
+}
+
+func (r *tracedReplayer) EventfulSnapshots(ctx context.Context, beginAt, endAt time.Time, filter *model.SnapshotFilter) (snapshots []*model.TimedNodeSnapshots, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.EventfulSnapshots", trace.WithAttributes(windowAttributes(beginAt, endAt)...))
+	defer func() { end(span, err) }()
+
+	snapshots, err = r.replayer.EventfulSnapshots(ctx, beginAt, endAt, filter)
+	span.SetAttributes(attribute.Int("frames", len(snapshots)))
+	return snapshots, err
+}
+
+func (r *tracedReplayer) IntervalSnapshots(ctx context.Context, beginAt, endAt time.Time, intervalInSec int64, filter *model.SnapshotFilter) (snapshots []*model.TimedNodeSnapshots, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.IntervalSnapshots", trace.WithAttributes(
+		append(windowAttributes(beginAt, endAt), attribute.Int64("window.step", intervalInSec))...))
+	defer func() { end(span, err) }()
+
+	snapshots, err = r.replayer.IntervalSnapshots(ctx, beginAt, endAt, intervalInSec, filter)
+	span.SetAttributes(attribute.Int("frames", len(snapshots)))
+	return snapshots, err
+}
+
+func (r *tracedReplayer) EffectiveAtSnapshot(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) (snapshot *model.TimedNodeSnapshots, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.EffectiveAtSnapshot", trace.WithAttributes(windowAttributes(effectiveAt, effectiveAt)...))
+	defer func() { end(span, err) }()
+
+	snapshot, err = r.replayer.EffectiveAtSnapshot(ctx, effectiveAt, filter)
+	if snapshot != nil {
+		span.SetAttributes(attribute.Int("items.node_snapshots", len(snapshot.Nodes)))
+	}
+	return snapshot, err
+}
+
+func (r *tracedReplayer) PodHistory(ctx context.Context, namespace, name string, beginAt, endAt time.Time) (history *model.PodHistory, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.PodHistory", trace.WithAttributes(append(windowAttributes(beginAt, endAt),
+		attribute.String("pod.namespace", namespace), attribute.String("pod.name", name))...))
+	defer func() { end(span, err) }()
+
+	return r.replayer.PodHistory(ctx, namespace, name, beginAt, endAt)
+}
+
+func (r *tracedReplayer) ClusterEvents(ctx context.Context, beginAt, endAt time.Time, filter *model.SnapshotFilter) (events []*model.ClusterEvent, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.ClusterEvents", trace.WithAttributes(windowAttributes(beginAt, endAt)...))
+	defer func() { end(span, err) }()
+
+	events, err = r.replayer.ClusterEvents(ctx, beginAt, endAt, filter)
+	span.SetAttributes(attribute.Int("events", len(events)))
+	return events, err
+}
+
+func (r *tracedReplayer) EffectiveAtManifests(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) (m *manifests.Manifests, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.EffectiveAtManifests", trace.WithAttributes(windowAttributes(effectiveAt, effectiveAt)...))
+	defer func() { end(span, err) }()
+
+	m, err = r.replayer.EffectiveAtManifests(ctx, effectiveAt, filter)
+	if m != nil {
+		span.SetAttributes(attribute.Int("items.nodes", len(m.Nodes.Items)), attribute.Int("items.pods", len(m.Pods.Items)))
+	}
+	return m, err
+}
+
+func (r *tracedReplayer) SimulatePodFit(ctx context.Context, effectiveAt time.Time, pod *model.HypotheticalPodInput) (simulation *model.PodFitSimulation, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.SimulatePodFit", trace.WithAttributes(windowAttributes(effectiveAt, effectiveAt)...))
+	defer func() { end(span, err) }()
+
+	simulation, err = r.replayer.SimulatePodFit(ctx, effectiveAt, pod)
+	if simulation != nil {
+		span.SetAttributes(attribute.Bool("fits", simulation.Fits), attribute.Int("items.nodes", len(simulation.Nodes)))
+	}
+	return simulation, err
+}
+
+func (r *tracedReplayer) SimulateNodeDrain(ctx context.Context, effectiveAt time.Time, nodeName string) (simulation *model.DrainSimulation, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.SimulateNodeDrain", trace.WithAttributes(append(windowAttributes(effectiveAt, effectiveAt),
+		attribute.String("node.name", nodeName))...))
+	defer func() { end(span, err) }()
+
+	simulation, err = r.replayer.SimulateNodeDrain(ctx, effectiveAt, nodeName)
+	if simulation != nil {
+		span.SetAttributes(attribute.Bool("feasible", simulation.Feasible), attribute.Int("items.pods", len(simulation.Placements)))
+	}
+	return simulation, err
+}
+
+func (r *tracedReplayer) CapacityPlan(ctx context.Context, beginAt, endAt time.Time) (plan *model.CapacityPlan, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.CapacityPlan", trace.WithAttributes(windowAttributes(beginAt, endAt)...))
+	defer func() { end(span, err) }()
+
+	plan, err = r.replayer.CapacityPlan(ctx, beginAt, endAt)
+	if plan != nil {
+		span.SetAttributes(attribute.Int("groups", len(plan.Groups)))
+	}
+	return plan, err
+}
+
+func (r *tracedReplayer) CostReport(ctx context.Context, beginAt, endAt time.Time) (report *model.CostReport, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.CostReport", trace.WithAttributes(windowAttributes(beginAt, endAt)...))
+	defer func() { end(span, err) }()
+
+	report, err = r.replayer.CostReport(ctx, beginAt, endAt)
+	if report != nil {
+		span.SetAttributes(attribute.Int("items.namespaces", len(report.Namespaces)), attribute.Int("unpriced", len(report.UnpricedInstanceTypes)))
+	}
+	return report, err
+}
+
+func (r *tracedReplayer) ImagesAtTimestamp(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) (containerImages []*model.ContainerImage, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.ImagesAtTimestamp", trace.WithAttributes(windowAttributes(effectiveAt, effectiveAt)...))
+	defer func() { end(span, err) }()
+
+	containerImages, err = r.replayer.ImagesAtTimestamp(ctx, effectiveAt, filter)
+	span.SetAttributes(attribute.Int("items.images", len(containerImages)))
+	return containerImages, err
+}
+
+func (r *tracedReplayer) ImageSightings(ctx context.Context, image string, beginAt, endAt time.Time) (sightings *model.ImageSightings, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.ImageSightings", trace.WithAttributes(append(windowAttributes(beginAt, endAt),
+		attribute.String("image", image))...))
+	defer func() { end(span, err) }()
+
+	sightings, err = r.replayer.ImageSightings(ctx, image, beginAt, endAt)
+	if sightings != nil {
+		span.SetAttributes(attribute.Int("items.pods", len(sightings.Pods)))
+	}
+	return sightings, err
+}
+
+func (r *tracedReplayer) RolloutTimeline(ctx context.Context, namespace, workload string, beginAt, endAt time.Time) (timeline []*model.RolloutFrame, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.RolloutTimeline", trace.WithAttributes(append(windowAttributes(beginAt, endAt),
+		attribute.String("pod.namespace", namespace), attribute.String("workload", workload))...))
+	defer func() { end(span, err) }()
+
+	timeline, err = r.replayer.RolloutTimeline(ctx, namespace, workload, beginAt, endAt)
+	span.SetAttributes(attribute.Int("frames", len(timeline)))
+	return timeline, err
+}
+
+func (r *tracedReplayer) VersionReport(ctx context.Context, effectiveAt time.Time, reference *string, maxMinorSkew int) (report *model.VersionReport, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.VersionReport", trace.WithAttributes(append(windowAttributes(effectiveAt, effectiveAt),
+		attribute.Int("max_minor_skew", maxMinorSkew))...))
+	defer func() { end(span, err) }()
+
+	report, err = r.replayer.VersionReport(ctx, effectiveAt, reference, maxMinorSkew)
+	if report != nil {
+		span.SetAttributes(attribute.String("reference", report.Reference), attribute.Int("items.skewed", len(report.Skewed)))
+	}
+	return report, err
+}
+
+func (r *tracedReplayer) UpgradeProgress(ctx context.Context, beginAt, endAt time.Time, component model.NodeComponent, target *string) (progress *model.UpgradeProgress, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.UpgradeProgress", trace.WithAttributes(append(windowAttributes(beginAt, endAt),
+		attribute.String("component", component.String()))...))
+	defer func() { end(span, err) }()
+
+	progress, err = r.replayer.UpgradeProgress(ctx, beginAt, endAt, component, target)
+	if progress != nil {
+		span.SetAttributes(attribute.String("target", progress.Target), attribute.Int("frames", len(progress.Frames)))
+	}
+	return progress, err
+}
+
+func (r *tracedReplayer) RestartAnomalies(ctx context.Context, beginAt, endAt time.Time, namespace *string) (restartAnomalies []*model.RestartAnomaly, err error) {
+	attributes := windowAttributes(beginAt, endAt)
+	if namespace != nil {
+		attributes = append(attributes, attribute.String("pod.namespace", *namespace))
+	}
+	ctx, span := r.tracer.Start(ctx, "Replayer.RestartAnomalies", trace.WithAttributes(attributes...))
+	defer func() { end(span, err) }()
+
+	restartAnomalies, err = r.replayer.RestartAnomalies(ctx, beginAt, endAt, namespace)
+	span.SetAttributes(attribute.Int("items.anomalies", len(restartAnomalies)))
+	return restartAnomalies, err
+}
+
+func (r *tracedReplayer) NodeIncidents(ctx context.Context, beginAt, endAt time.Time, nodeName *string) (nodeIncidents []*model.NodeIncident, err error) {
+	attributes := windowAttributes(beginAt, endAt)
+	if nodeName != nil {
+		attributes = append(attributes, attribute.String("node.name", *nodeName))
+	}
+	ctx, span := r.tracer.Start(ctx, "Replayer.NodeIncidents", trace.WithAttributes(attributes...))
+	defer func() { end(span, err) }()
+
+	nodeIncidents, err = r.replayer.NodeIncidents(ctx, beginAt, endAt, nodeName)
+	span.SetAttributes(attribute.Int("items.incidents", len(nodeIncidents)))
+	return nodeIncidents, err
+}
+
+func (r *tracedReplayer) PendingPods(ctx context.Context, beginAt, endAt time.Time, minPending time.Duration, namespace *string) (pendingPods []*model.PendingPod, err error) {
+	attributes := append(windowAttributes(beginAt, endAt), attribute.Int64("min_pending_seconds", int64(minPending.Seconds())))
+	if namespace != nil {
+		attributes = append(attributes, attribute.String("pod.namespace", *namespace))
+	}
+	ctx, span := r.tracer.Start(ctx, "Replayer.PendingPods", trace.WithAttributes(attributes...))
+	defer func() { end(span, err) }()
+
+	pendingPods, err = r.replayer.PendingPods(ctx, beginAt, endAt, minPending, namespace)
+	span.SetAttributes(attribute.Int("items.pods", len(pendingPods)))
+	return pendingPods, err
+}
+
+func (r *tracedReplayer) WorkloadsAtTimestamp(ctx context.Context, effectiveAt time.Time, filter *model.SnapshotFilter) (workloads []*model.WorkloadSummary, err error) {
+	ctx, span := r.tracer.Start(ctx, "Replayer.WorkloadsAtTimestamp", trace.WithAttributes(windowAttributes(effectiveAt, effectiveAt)...))
+	defer func() { end(span, err) }()
+
+	workloads, err = r.replayer.WorkloadsAtTimestamp(ctx, effectiveAt, filter)
+	span.SetAttributes(attribute.Int("items.workloads", len(workloads)))
+	return workloads, err
+}
+
+// NewTracedReplayer returns a Replayer tracing the calls to the given replayer with tracers from tp
+func NewTracedReplayer(replayer services.Replayer, tp trace.TracerProvider) services.Replayer {
+	return &tracedReplayer{
+		replayer: replayer,
+		tracer:   tp.Tracer(instrumentationName),
+	}
+}
@@ -0,0 +1,172 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+)
+
+// tracedStore starts a span for every call to the wrapped store, with the count of items read or written per kind
+type tracedStore struct {
+	store  repositories.Store
+	tracer trace.Tracer
+}
+
+func (s *tracedStore) Ping(ctx context.Context) error {
+	return s.store.Ping(ctx)
+}
+
+func (s *tracedStore) ListNodeIDs(ctx context.Context) (nodeIDs []string, err error) {
+	ctx, span := s.tracer.Start(ctx, "Store.ListNodeIDs")
+	defer func() { end(span, err) }()
+
+	nodeIDs, err = s.store.ListNodeIDs(ctx)
+	span.SetAttributes(attribute.Int("items.node_metas", len(nodeIDs)))
+	return nodeIDs, err
+}
+
+func (s *tracedStore) GetAll(ctx context.Context) (nodeMetas []*data.NodeMeta, err error) {
+	ctx, span := s.tracer.Start(ctx, "Store.GetAll")
+	defer func() { end(span, err) }()
+
+	nodeMetas, err = s.store.GetAll(ctx)
+	span.SetAttributes(treeAttributes(nodeMetas...)...)
+	return nodeMetas, err
+}
+
+func (s *tracedStore) Get(ctx context.Context, nodeID string) (nodeMeta *data.NodeMeta, err error) {
+	ctx, span := s.tracer.Start(ctx, "Store.Get", trace.WithAttributes(attribute.String("node.id", nodeID)))
+	defer func() { end(span, err) }()
+
+	nodeMeta, err = s.store.Get(ctx, nodeID)
+	span.SetAttributes(treeAttributes(nodeMeta)...)
+	return nodeMeta, err
+}
+
+func (s *tracedStore) GetAllBetween(ctx context.Context, beginAt, endAt time.Time) (nodeMetas []*data.NodeMeta, err error) {
+	ctx, span := s.tracer.Start(ctx, "Store.GetAllBetween", trace.WithAttributes(windowAttributes(beginAt, endAt)...))
+	defer func() { end(span, err) }()
+
+	nodeMetas, err = s.store.GetAllBetween(ctx, beginAt, endAt)
+	span.SetAttributes(treeAttributes(nodeMetas...)...)
+	return nodeMetas, err
+}
+
+func (s *tracedStore) GetBetween(ctx context.Context, nodeID string, beginAt, endAt time.Time) (nodeMeta *data.NodeMeta, err error) {
+	ctx, span := s.tracer.Start(ctx, "Store.GetBetween", trace.WithAttributes(
+		append(windowAttributes(beginAt, endAt), attribute.String("node.id", nodeID))...))
+	defer func() { end(span, err) }()
+
+	nodeMeta, err = s.store.GetBetween(ctx, nodeID, beginAt, endAt)
+	span.SetAttributes(treeAttributes(nodeMeta)...)
+	return nodeMeta, err
+}
+
+func (s *tracedStore) GetPodMetasByName(ctx context.Context, namespace, name string) (podMetas []*data.PodMeta, err error) {
+	ctx, span := s.tracer.Start(ctx, "Store.GetPodMetasByName", trace.WithAttributes(
+		attribute.String("pod.namespace", namespace), attribute.String("pod.name", name)))
+	defer func() { end(span, err) }()
+
+	podMetas, err = s.store.GetPodMetasByName(ctx, namespace, name)
+	span.SetAttributes(podAttributes(podMetas)...)
+	return podMetas, err
+}
+
+func (s *tracedStore) Upsert(ctx context.Context, nodeMeta *data.NodeMeta) (err error) {
+	ctx, span := s.tracer.Start(ctx, "Store.Upsert", trace.WithAttributes(attribute.String("node.id", nodeMeta.ID)))
+	span.SetAttributes(treeAttributes(nodeMeta)...)
+	defer func() { end(span, err) }()
+
+	return s.store.Upsert(ctx, nodeMeta)
+}
+
+func (s *tracedStore) UpsertNodeSnapshots(ctx context.Context, nodeID string, nodeSnapshots []*data.NodeSnapshot) (err error) {
+	ctx, span := s.tracer.Start(ctx, "Store.UpsertNodeSnapshots", trace.WithAttributes(
+		attribute.String("node.id", nodeID), attribute.Int("items.node_snapshots", len(nodeSnapshots))))
+	defer func() { end(span, err) }()
+
+	return s.store.UpsertNodeSnapshots(ctx, nodeID, nodeSnapshots)
+}
+
+func (s *tracedStore) UpsertPodMetas(ctx context.Context, nodeID string, podMetas []*data.PodMeta) (err error) {
+	ctx, span := s.tracer.Start(ctx, "Store.UpsertPodMetas", trace.WithAttributes(attribute.String("node.id", nodeID)))
+	span.SetAttributes(podAttributes(podMetas)...)
+	defer func() { end(span, err) }()
+
+	return s.store.UpsertPodMetas(ctx, nodeID, podMetas)
+}
+
+func (s *tracedStore) UpsertPodSnapshots(ctx context.Context, nodeID string, podID string, podSnapshots []*data.PodSnapshot) (err error) {
+	ctx, span := s.tracer.Start(ctx, "Store.UpsertPodSnapshots", trace.WithAttributes(
+		attribute.String("node.id", nodeID), attribute.String("pod.id", podID), attribute.Int("items.pod_snapshots", len(podSnapshots))))
+	defer func() { end(span, err) }()
+
+	return s.store.UpsertPodSnapshots(ctx, nodeID, podID, podSnapshots)
+}
+
+func (s *tracedStore) UpdateNodeMetaAttributes(ctx context.Context, nodeID string, updates map[string]interface{}) (err error) {
+	ctx, span := s.tracer.Start(ctx, "Store.UpdateNodeMetaAttributes", trace.WithAttributes(attribute.String("node.id", nodeID)))
+	defer func() { end(span, err) }()
+
+	return s.store.UpdateNodeMetaAttributes(ctx, nodeID, updates)
+}
+
+func (s *tracedStore) UpdatePodMetaAttributes(ctx context.Context, nodeID, podID string, updates map[string]interface{}) (err error) {
+	ctx, span := s.tracer.Start(ctx, "Store.UpdatePodMetaAttributes", trace.WithAttributes(
+		attribute.String("node.id", nodeID), attribute.String("pod.id", podID)))
+	defer func() { end(span, err) }()
+
+	return s.store.UpdatePodMetaAttributes(ctx, nodeID, podID, updates)
+}
+
+func windowAttributes(beginAt, endAt time.Time) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("window.begin", beginAt.Format(time.RFC3339)),
+		attribute.String("window.end", endAt.Format(time.RFC3339)),
+	}
+}
+
+// treeAttributes counts the items of the node trees per kind, skipping nil trees
+func treeAttributes(nodeMetas ...*data.NodeMeta) []attribute.KeyValue {
+	var metas, snapshots int
+	var podMetas []*data.PodMeta
+	for _, nodeMeta := range nodeMetas {
+		if nodeMeta == nil {
+			continue
+		}
+
+		metas++
+		snapshots += len(nodeMeta.Snapshots)
+		podMetas = append(podMetas, nodeMeta.Pods...)
+	}
+
+	return append([]attribute.KeyValue{
+		attribute.Int("items.node_metas", metas),
+		attribute.Int("items.node_snapshots", snapshots),
+	}, podAttributes(podMetas)...)
+}
+
+func podAttributes(podMetas []*data.PodMeta) []attribute.KeyValue {
+	var snapshots int
+	for _, podMeta := range podMetas {
+		snapshots += len(podMeta.Snapshots)
+	}
+
+	return []attribute.KeyValue{
+		attribute.Int("items.pod_metas", len(podMetas)),
+		attribute.Int("items.pod_snapshots", snapshots),
+	}
+}
+
+// NewTracedStore returns a Store tracing the calls to the given store with tracers from tp
+func NewTracedStore(store repositories.Store, tp trace.TracerProvider) repositories.Store {
+	return &tracedStore{
+		store:  store,
+		tracer: tp.Tracer(instrumentationName),
+	}
+}
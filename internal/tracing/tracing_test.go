@@ -0,0 +1,91 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/ccpeng/kube-replay/graph"
+	"github.com/ccpeng/kube-replay/internal/auth"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+	"github.com/ccpeng/kube-replay/internal/services"
+	"github.com/ccpeng/kube-replay/internal/tracing"
+)
+
+func TestTracing(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	memoryStore := repositories.NewMemoryStore()
+	err := memoryStore.Upsert(context.Background(), &data.NodeMeta{
+		ID:        "node-a",
+		Snapshots: data.NodeSnapshots{{Timestamp: t0}},
+		Pods:      []*data.PodMeta{{ID: "pod-a", Snapshots: data.PodSnapshots{{Timestamp: t0}}}},
+	})
+	g.Expect(err).To(gomega.BeNil())
+
+	replayer := services.NewReplayerWithStore(tracing.NewTracedStore(memoryStore, tp))
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers: &graph.Resolver{Replayer: tracing.NewTracedReplayer(replayer, tp)},
+	}))
+	srv.AddTransport(transport.POST{})
+	srv.Use(tracing.NewGraphQLExtension(tp))
+	c := client.New(auth.Middleware(auth.Disabled)(srv))
+
+	var resp map[string]interface{}
+	err = c.Post(`query Range { nodeStatesRange(start: "2025-04-27T00:00:00Z", end: "2025-04-27T00:10:00Z", step: 60) { timestamp nodes { id } } }`, &resp)
+	g.Expect(err).To(gomega.BeNil())
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range sr.Ended() {
+		spans[span.Name()] = span
+	}
+	g.Expect(spans).To(gomega.HaveKey("query Range"))
+	g.Expect(spans).To(gomega.HaveKey("Query.nodeStatesRange"))
+	g.Expect(spans).To(gomega.HaveKey("Replayer.IntervalSnapshots"))
+	g.Expect(spans).To(gomega.HaveKey("Store.GetAllBetween"))
+	g.Expect(spans).NotTo(gomega.HaveKey("NodeSnapshot.id"))
+
+	// every span is a child of the one before
+	parent := spans["query Range"]
+	for _, name := range []string{"Query.nodeStatesRange", "Replayer.IntervalSnapshots", "Store.GetAllBetween"} {
+		g.Expect(spans[name].Parent().SpanID()).To(gomega.Equal(parent.SpanContext().SpanID()), name)
+		parent = spans[name]
+	}
+
+	g.Expect(spans["Replayer.IntervalSnapshots"].Attributes()).To(gomega.ContainElement(attribute.Int("frames", 11)))
+	g.Expect(spans["Store.GetAllBetween"].Attributes()).To(gomega.ContainElements(
+		attribute.Int("items.node_metas", 1),
+		attribute.Int("items.pod_snapshots", 1),
+	))
+}
+
+func TestNewTracerProvider(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	ctx := context.Background()
+	var buf bytes.Buffer
+	tp, err := tracing.NewTracerProvider(ctx, tracing.ExporterStdout, &buf)
+	g.Expect(err).To(gomega.BeNil())
+
+	_, span := tp.Tracer("test").Start(ctx, "Store.Get")
+	span.End()
+	g.Expect(tp.Shutdown(ctx)).To(gomega.Succeed())
+	g.Expect(buf.String()).To(gomega.ContainSubstring(`"Name":"Store.Get"`))
+
+	_, err = tracing.NewTracerProvider(ctx, "zipkin", &buf)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("unknown trace exporter")))
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"sort"
+
 	"github.com/ccpeng/kube-replay/graph/model"
 	"github.com/ccpeng/kube-replay/internal/data"
 )
@@ -22,6 +24,8 @@ func TransformToModelPodPhase(phase data.PodPhase) model.PodPhase {
 		return model.PodPhaseRunning
 	case data.PodPhasePending:
 		return model.PodPhasePending
+	case data.PodPhaseSucceeded:
+		return model.PodPhaseSucceeded
 	case data.PodPhaseFailed:
 		return model.PodPhaseFailed
 	default:
@@ -42,19 +46,103 @@ func TransformToModelPodQOSClass(class data.PodQOSClass) model.PodQOSClass {
 	}
 }
 
+func TransformToModelLabels(labels map[string]string) []*model.Label {
+	transformed := make([]*model.Label, 0, len(labels))
+	for key, value := range labels {
+		transformed = append(transformed, &model.Label{Key: key, Value: value})
+	}
+
+	sort.Slice(transformed, func(i, j int) bool {
+		return transformed[i].Key < transformed[j].Key
+	})
+
+	return transformed
+}
+
+func TransformToModelOwners(owners []*data.OwnerReference) []*model.OwnerReference {
+	transformed := make([]*model.OwnerReference, len(owners))
+	for i, owner := range owners {
+		transformed[i] = &model.OwnerReference{
+			Kind:       owner.Kind,
+			Name:       owner.Name,
+			UID:        owner.UID,
+			Controller: owner.Controller,
+		}
+	}
+
+	return transformed
+}
+
+func TransformToModelTolerations(tolerations []*data.Toleration) []*model.Toleration {
+	transformed := make([]*model.Toleration, len(tolerations))
+	for i, toleration := range tolerations {
+		transformed[i] = &model.Toleration{
+			Key:               toleration.Key,
+			Operator:          toleration.Operator,
+			Value:             toleration.Value,
+			Effect:            toleration.Effect,
+			TolerationSeconds: toleration.TolerationSeconds,
+		}
+	}
+
+	return transformed
+}
+
+// TransformToModelAffinity returns nil for a pod without affinity
+func TransformToModelAffinity(affinity *data.Affinity) *model.Affinity {
+	if affinity == nil {
+		return nil
+	}
+
+	terms := func(terms []*data.AffinityTerm) []*model.AffinityTerm {
+		transformed := make([]*model.AffinityTerm, len(terms))
+		for i, term := range terms {
+			transformed[i] = &model.AffinityTerm{LabelSelector: term.LabelSelector, TopologyKey: term.TopologyKey, Required: term.Required}
+		}
+		return transformed
+	}
+
+	return &model.Affinity{
+		RequiredNodeTerms:  append([]string{}, affinity.RequiredNodeTerms...),
+		PreferredNodeTerms: append([]string{}, affinity.PreferredNodeTerms...),
+		PodAffinity:        terms(affinity.PodAffinity),
+		PodAntiAffinity:    terms(affinity.PodAntiAffinity),
+	}
+}
+
+func TransformToDataLabels(untransformed []*model.LabelInput) map[string]string {
+	transformed := make(map[string]string, len(untransformed))
+	for _, label := range untransformed {
+		transformed[label.Key] = label.Value
+	}
+
+	return transformed
+}
+
 func TransformToDataPods(untransformed []*model.PodSnapshotInput) []*data.PodMeta {
 	transformed := make([]*data.PodMeta, len(untransformed))
 
 	for i, pod := range untransformed {
 		transformed[i] = &data.PodMeta{
-			ID:         pod.ID,
-			Name:       pod.Name,
-			Namespace:  *pod.Namespace,
-			StartedAt:  pod.StartedAt,
-			DeletedAt:  *pod.DeletedAt,
-			FinishedAt: *pod.FinishedAt,
-			DeletedBy:  *pod.DeletedBy,
-			QOSClass:   data.StringToPodQOSClass(pod.QosClass.String()),
+			ID:          pod.ID,
+			Name:        pod.Name,
+			Namespace:   valueOf(pod.Namespace),
+			StartedAt:   pod.StartedAt,
+			DeletedAt:   valueOf(pod.DeletedAt),
+			FinishedAt:  valueOf(pod.FinishedAt),
+			DeletedBy:   valueOf(pod.DeletedBy),
+			QOSClass:    data.StringToPodQOSClass(pod.QosClass.String()),
+			Labels:      TransformToDataLabels(pod.Labels),
+			Annotations: TransformToDataLabels(pod.Annotations),
+			Owners:      transformToDataOwners(pod.OwnerReferences),
+
+			Tolerations:       transformToDataTolerations(pod.Tolerations),
+			NodeSelector:      TransformToDataLabels(pod.NodeSelector),
+			Affinity:          transformToDataAffinity(pod.Affinity),
+			Priority:          pod.Priority,
+			PriorityClassName: valueOf(pod.PriorityClassName),
+			PreemptionPolicy:  valueOf(pod.PreemptionPolicy),
+
 			Snapshots: data.PodSnapshots{
 				{
 					Timestamp:           pod.Timestamp,
@@ -70,6 +158,63 @@ func TransformToDataPods(untransformed []*model.PodSnapshotInput) []*data.PodMet
 	return transformed
 }
 
+func transformToDataOwners(untransformed []*model.OwnerReferenceInput) []*data.OwnerReference {
+	transformed := make([]*data.OwnerReference, len(untransformed))
+	for i, owner := range untransformed {
+		transformed[i] = &data.OwnerReference{
+			Kind:       owner.Kind,
+			Name:       owner.Name,
+			UID:        owner.UID,
+			Controller: valueOf(owner.Controller),
+		}
+	}
+
+	return transformed
+}
+
+func transformToDataTolerations(untransformed []*model.TolerationInput) []*data.Toleration {
+	transformed := make([]*data.Toleration, len(untransformed))
+	for i, toleration := range untransformed {
+		transformed[i] = &data.Toleration{
+			Key:               valueOf(toleration.Key),
+			Operator:          valueOf(toleration.Operator),
+			Value:             valueOf(toleration.Value),
+			Effect:            valueOf(toleration.Effect),
+			TolerationSeconds: toleration.TolerationSeconds,
+		}
+		if transformed[i].Operator == "" {
+			transformed[i].Operator = "Equal"
+		}
+	}
+
+	return transformed
+}
+
+func transformToDataAffinity(untransformed *model.AffinityInput) *data.Affinity {
+	if untransformed == nil {
+		return nil
+	}
+
+	terms := func(untransformed []*model.AffinityTermInput) []*data.AffinityTerm {
+		transformed := make([]*data.AffinityTerm, len(untransformed))
+		for i, term := range untransformed {
+			transformed[i] = &data.AffinityTerm{
+				LabelSelector: term.LabelSelector,
+				TopologyKey:   term.TopologyKey,
+				Required:      valueOf(term.Required),
+			}
+		}
+		return transformed
+	}
+
+	return &data.Affinity{
+		RequiredNodeTerms:  untransformed.RequiredNodeTerms,
+		PreferredNodeTerms: untransformed.PreferredNodeTerms,
+		PodAffinity:        terms(untransformed.PodAffinity),
+		PodAntiAffinity:    terms(untransformed.PodAntiAffinity),
+	}
+}
+
 func transformToDataContainers(untransformed []*model.ContainerSnapshotInput) []*data.ContainerSnapshot {
 	transformed := make([]*data.ContainerSnapshot, len(untransformed))
 
@@ -112,3 +257,13 @@ func transformToDataContainers(untransformed []*model.ContainerSnapshotInput) []
 
 	return transformed
 }
+
+// valueOf returns the value pointed to, or the zero value when an optional input is left out
+func valueOf[T any](p *T) T {
+	var v T
+	if p != nil {
+		v = *p
+	}
+
+	return v
+}
@@ -0,0 +1,198 @@
+package versions
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+	"sort"
+	"time"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/ccpeng/kube-replay/internal/capacity"
+	"github.com/ccpeng/kube-replay/internal/data"
+)
+
+// Component is something running on nodes whose version is recorded
+type Component string
+
+const (
+	Kubelet          Component = "kubelet"
+	KubeProxy        Component = "kube-proxy"
+	Kernel           Component = "kernel"
+	ContainerRuntime Component = "container-runtime"
+	OSImage          Component = "os-image"
+)
+
+// Components are every component, in the order they're reported
+var Components = []Component{Kubelet, KubeProxy, Kernel, ContainerRuntime, OSImage}
+
+// skewed are the components whose minor versions are held to the reference, as Kubernetes' version skew policy does
+var skewed = []Component{Kubelet, KubeProxy}
+
+// Of returns the version of the component of the versions
+func (c Component) Of(versions data.NodeVersions) string {
+	switch c {
+	case Kubelet:
+		return versions.KubeletVersion
+	case KubeProxy:
+		return versions.KubeProxyVersion
+	case Kernel:
+		return versions.KernelVersion
+	case ContainerRuntime:
+		return versions.ContainerRuntimeVersion
+	case OSImage:
+		return versions.OsImage
+	}
+
+	return ""
+}
+
+// Count is how many nodes ran a version
+type Count struct {
+	Version string
+	Nodes   int
+}
+
+// Distribution returns how many nodes of the cluster state ran each version of the component, oldest first
+func Distribution(state *data.ClusterState, component Component) []Count {
+	return distribution(state.Nodes, component)
+}
+
+func distribution(nodes []*data.NodeAt, component Component) []Count {
+	counts := map[string]int{}
+	for _, node := range nodes {
+		counts[component.Of(node.Versions())]++
+	}
+
+	distribution := make([]Count, 0, len(counts))
+	for version, nodes := range counts {
+		distribution = append(distribution, Count{Version: version, Nodes: nodes})
+	}
+	sort.Slice(distribution, func(i, j int) bool {
+		return compare(distribution[i].Version, distribution[j].Version) < 0
+	})
+
+	return distribution
+}
+
+// Newest returns the newest version of the component across the nodes of the cluster state, or empty if none has one
+func Newest(state *data.ClusterState, component Component) string {
+	distribution := Distribution(state, component)
+	if len(distribution) == 0 {
+		return ""
+	}
+
+	return distribution[len(distribution)-1].Version
+}
+
+// Skew is a node running a component more minor versions behind the reference than allowed
+type Skew struct {
+	NodeID       string
+	NodeName     string
+	Component    Component
+	Version      string
+	MinorsBehind int
+}
+
+// Skewed returns the nodes of the cluster state whose kubelet or kube-proxy is more than maxMinorSkew minor versions
+// behind reference, ordered by node name. Versions that can't be parsed, or of another major version, aren't compared.
+func Skewed(state *data.ClusterState, reference string, maxMinorSkew int) ([]*Skew, error) {
+	ref, err := utilversion.ParseGeneric(reference)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse version %q: %v", reference, err)
+	}
+
+	var skews []*Skew
+	for _, node := range state.Nodes {
+		for _, component := range skewed {
+			version := component.Of(node.Versions())
+			v, err := utilversion.ParseGeneric(version)
+			if err != nil || v.Major() != ref.Major() {
+				continue
+			}
+
+			if behind := int(ref.Minor()) - int(v.Minor()); behind > maxMinorSkew {
+				skews = append(skews, &Skew{
+					NodeID:       node.Meta.ID,
+					NodeName:     node.Meta.Name,
+					Component:    component,
+					Version:      version,
+					MinorsBehind: behind,
+				})
+			}
+		}
+	}
+
+	return skews, nil
+}
+
+// GroupProgress is how many nodes of a group ran each version of a component, and how many were upgraded to the target
+type GroupProgress struct {
+	capacity.Group
+	Nodes    int
+	Upgraded int // nodes running the target or newer
+	Versions []Count
+}
+
+// Frame is the progress of each group of nodes from an instant on, ordered as capacity plans are
+type Frame struct {
+	Timestamp time.Time
+	Groups    []*GroupProgress
+}
+
+// Progress returns the progress of the upgrade of the component to target of each group of nodes at the cluster
+// states, leaving out those where it didn't change
+func Progress(frames iter.Seq[*data.ClusterState], component Component, target string) []*Frame {
+	var progress []*Frame
+	for state := range frames {
+		groups := map[capacity.Group][]*data.NodeAt{}
+		for _, node := range state.Nodes {
+			group := capacity.GroupOf(node.Meta)
+			groups[group] = append(groups[group], node)
+		}
+
+		frame := &Frame{Timestamp: state.Timestamp, Groups: []*GroupProgress{}}
+		for group, nodes := range groups {
+			groupProgress := &GroupProgress{Group: group, Nodes: len(nodes), Versions: distribution(nodes, component)}
+			for _, count := range groupProgress.Versions {
+				if compare(count.Version, target) >= 0 {
+					groupProgress.Upgraded += count.Nodes
+				}
+			}
+			frame.Groups = append(frame.Groups, groupProgress)
+		}
+		sort.Slice(frame.Groups, func(i, j int) bool {
+			return frame.Groups[i].Group.Less(frame.Groups[j].Group)
+		})
+
+		if len(progress) > 0 && reflect.DeepEqual(progress[len(progress)-1].Groups, frame.Groups) {
+			continue
+		}
+		progress = append(progress, frame)
+	}
+
+	return progress
+}
+
+// compare orders versions semantically when both can be parsed, and as strings otherwise
+func compare(a, b string) int {
+	va, errA := utilversion.ParseGeneric(a)
+	vb, errB := utilversion.ParseGeneric(b)
+	if errA == nil && errB == nil {
+		switch {
+		case va.LessThan(vb):
+			return -1
+		case va.GreaterThan(vb):
+			return 1
+		}
+	}
+
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
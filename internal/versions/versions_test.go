@@ -0,0 +1,66 @@
+package versions_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ccpeng/kube-replay/internal/capacity"
+	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/versions"
+)
+
+func TestVersions(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	t0, _ := time.Parse(time.RFC3339, "2025-04-27T00:00:00Z")
+	kubelet := func(at time.Time, version string) *data.NodeSnapshot {
+		return &data.NodeSnapshot{Timestamp: at, State: data.NodeState{Versions: data.NodeVersions{KubeletVersion: version, KubeProxyVersion: version}}}
+	}
+	worker := func(id string, snapshots ...*data.NodeSnapshot) *data.NodeMeta {
+		return &data.NodeMeta{ID: id, Name: id, Roles: []string{"worker"}, Snapshots: snapshots}
+	}
+
+	// a and b are upgraded from 1.29 to 1.31 an hour apart, while c was recorded before versions were snapshotted
+	nodes := []*data.NodeMeta{
+		worker("a", kubelet(t0, "v1.29.3-eks-1"), kubelet(t0.Add(time.Hour), "v1.31.0-eks-1")),
+		worker("b", kubelet(t0, "v1.29.3-eks-1"), kubelet(t0.Add(2*time.Hour), "v1.31.0-eks-1")),
+		{ID: "c", Name: "c", Roles: []string{"control-plane"}, KubeletVersion: "v1.31.0-eks-1", Snapshots: data.NodeSnapshots{{Timestamp: t0}}},
+	}
+
+	state := data.StateAt(nodes, t0.Add(time.Hour))
+	g.Expect(versions.Distribution(state, versions.Kubelet)).To(gomega.Equal([]versions.Count{
+		{Version: "v1.29.3-eks-1", Nodes: 1},
+		{Version: "v1.31.0-eks-1", Nodes: 2},
+	}))
+	g.Expect(versions.Newest(state, versions.Kubelet)).To(gomega.Equal("v1.31.0-eks-1"))
+
+	skews, err := versions.Skewed(state, "v1.31.0", 1)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(skews).To(gomega.HaveLen(2))
+	g.Expect(skews[0].NodeName).To(gomega.Equal("b"))
+	g.Expect(skews[0].Component).To(gomega.Equal(versions.Kubelet))
+	g.Expect(skews[0].MinorsBehind).To(gomega.Equal(2))
+	// b's kube-proxy too, while c's wasn't recorded so isn't compared
+	g.Expect(skews[1].NodeName).To(gomega.Equal("b"))
+	g.Expect(skews[1].Component).To(gomega.Equal(versions.KubeProxy))
+
+	skews, err = versions.Skewed(state, "v1.31.0", 2)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(skews).To(gomega.BeEmpty())
+	_, err = versions.Skewed(state, "latest", 1)
+	g.Expect(err).NotTo(gomega.BeNil())
+
+	times := []time.Time{t0, t0.Add(30 * time.Minute), t0.Add(time.Hour), t0.Add(2 * time.Hour)}
+	progress := versions.Progress(data.SweepSeq(nodes, times), versions.Kubelet, "v1.31.0")
+	g.Expect(progress).To(gomega.HaveLen(3))
+	g.Expect(progress[0].Groups[0].Group).To(gomega.Equal(capacity.Group{Roles: "control-plane"}))
+	workers := []int{}
+	for _, frame := range progress {
+		g.Expect(frame.Groups[1].Nodes).To(gomega.Equal(2))
+		workers = append(workers, frame.Groups[1].Upgraded)
+	}
+	g.Expect(workers).To(gomega.Equal([]int{0, 1, 2}))
+	g.Expect(progress[1].Timestamp).To(gomega.Equal(t0.Add(time.Hour)))
+}
@@ -1,9 +1,17 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
@@ -13,17 +21,61 @@ import (
 	"github.com/vektah/gqlparser/v2/ast"
 
 	"github.com/ccpeng/kube-replay/graph"
+	"github.com/ccpeng/kube-replay/internal/auth"
+	"github.com/ccpeng/kube-replay/internal/config"
+	"github.com/ccpeng/kube-replay/internal/cost"
+	"github.com/ccpeng/kube-replay/internal/health"
+	"github.com/ccpeng/kube-replay/internal/metrics"
+	"github.com/ccpeng/kube-replay/internal/policy"
+	"github.com/ccpeng/kube-replay/internal/repositories"
+	"github.com/ccpeng/kube-replay/internal/services"
+	"github.com/ccpeng/kube-replay/internal/tracing"
 )
 
-const defaultPort = "8080"
-
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = defaultPort
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("unable to load config: %v", err)
+	}
+
+	reg := metrics.NewRegistry()
+
+	tp, err := tracing.NewTracerProvider(context.TODO(), cfg.TracesExporter, os.Stdout)
+	if err != nil {
+		log.Fatalf("unable to create tracer provider: %v", err)
 	}
 
-	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: &graph.Resolver{}}))
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.TODO(), awsconfig.WithRegion("us-west-2"), awsconfig.WithRetryer(metrics.NewRetryer(reg)))
+	if err != nil {
+		log.Fatalf("unable to load SDK config, %v", err)
+	}
+
+	store := tracing.NewTracedStore(metrics.NewInstrumentedStore(repositories.NewStore(awsCfg, cfg.ClusterName), reg), tp)
+	if cfg.CacheSize > 0 {
+		cachedStore, err := repositories.NewCachedStore(store, cfg.CacheSize)
+		if err != nil {
+			log.Fatalf("unable to create store cache: %v", err)
+		}
+		metrics.RegisterCacheStats(cachedStore, reg)
+		store = cachedStore
+	}
+
+	opts := []services.Option{services.WithMaxFrames(cfg.MaxRangeFrames), services.WithMaxEventFrames(cfg.MaxEventFrames), services.WithPodAnnotations(cfg.PodAnnotations)}
+	if cfg.PriceTableFile != "" {
+		prices, err := cost.LoadPrices(cfg.PriceTableFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, services.WithPrices(prices))
+	}
+
+	replayer := services.NewReplayerWithStore(store, opts...)
+	replayer = policy.NewReplayer(tracing.NewTracedReplayer(replayer, tp))
+
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  &graph.Resolver{Replayer: replayer, ClusterName: cfg.ClusterName},
+		Complexity: graph.NewComplexityRoot(),
+	}))
 
 	srv.AddTransport(transport.Options{})
 	srv.AddTransport(transport.GET{})
@@ -31,14 +83,119 @@ func main() {
 
 	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
 
-	srv.Use(extension.Introspection{})
+	srv.Use(metrics.NewGraphQLExtension(reg))
+	srv.Use(tracing.NewGraphQLExtension(tp))
+	srv.Use(extension.FixedComplexityLimit(cfg.QueryComplexityLimit))
+	if cfg.EnablePlayground {
+		srv.Use(extension.Introspection{})
+	}
 	srv.Use(extension.AutomaticPersistedQuery{
 		Cache: lru.New[string](100),
 	})
 
-	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", srv)
+	probes := health.NewProbes(store)
+	mux := http.NewServeMux()
+	if cfg.EnablePlayground {
+		mux.Handle("/", playground.Handler("GraphQL playground", "/query"))
+	}
+	mux.Handle("/query", http.MaxBytesHandler(auth.Middleware(newAuthenticator(cfg))(srv), cfg.MaxBodyBytes))
+	mux.Handle("/metrics", metrics.Handler(reg))
+	mux.HandleFunc("/healthz", probes.Healthz)
+	mux.HandleFunc("/readyz", probes.Readyz)
+
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           mux,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		var err error
+		if cfg.TLSCertFile != "" {
+			server.TLSConfig = newTLSConfig(cfg)
+			log.Printf("listening on https://localhost:%s/", cfg.Port)
+			err = server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			log.Printf("listening on http://localhost:%s/", cfg.Port)
+			err = server.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("unable to serve: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Printf("draining requests in flight for up to %s", cfg.ShutdownTimeout)
+	probes.Drain()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("unable to drain requests in flight: %v", err)
+	}
+	if err := tp.Shutdown(shutdownCtx); err != nil {
+		log.Printf("unable to flush traces: %v", err)
+	}
+}
+
+// newAuthenticator returns an authenticator for every kind of credentials configured, or auth.Disabled when
+// authentication is disabled
+func newAuthenticator(cfg *config.Config) auth.Authenticator {
+	if cfg.AuthDisabled {
+		log.Printf("authentication is disabled, every request to /query may read and record snapshots")
+		return auth.Disabled
+	}
+
+	var authenticators []auth.Authenticator
+	if cfg.AuthClientCertsFile != "" {
+		authenticator, err := auth.NewCertificateAuthenticator(cfg.AuthClientCertsFile)
+		if err != nil {
+			log.Fatalf("unable to create client certificate authenticator: %v", err)
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+	if cfg.AuthJWKSFile != "" {
+		authenticator, err := auth.NewJWTAuthenticator(cfg.AuthJWKSFile, cfg.AuthJWTIssuer, cfg.AuthJWTAudience)
+		if err != nil {
+			log.Fatalf("unable to create JWT authenticator: %v", err)
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+	if cfg.AuthAPIKeysFile != "" {
+		authenticator, err := auth.NewAPIKeyAuthenticator(cfg.AuthAPIKeysFile)
+		if err != nil {
+			log.Fatalf("unable to create API key authenticator: %v", err)
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+
+	return auth.Chain(authenticators...)
+}
+
+// newTLSConfig returns the TLS config verifying client certificates given against the configured client CA
+func newTLSConfig(cfg *config.Config) *tls.Config {
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TLSClientCAFile == "" {
+		return tlsCfg
+	}
+
+	b, err := os.ReadFile(cfg.TLSClientCAFile)
+	if err != nil {
+		log.Fatalf("unable to read client CA: %v", err)
+	}
+
+	tlsCfg.ClientCAs = x509.NewCertPool()
+	if !tlsCfg.ClientCAs.AppendCertsFromPEM(b) {
+		log.Fatalf("no certificates found in client CA %s", cfg.TLSClientCAFile)
+	}
+	tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
 
-	log.Printf("connect to http://localhost:%s/ for GraphQL playground", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	return tlsCfg
 }
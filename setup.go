@@ -9,6 +9,7 @@ import (
 	"github.com/guregu/dynamo/v2"
 
 	"github.com/ccpeng/kube-replay/internal/data"
+	"github.com/ccpeng/kube-replay/internal/repositories"
 )
 
 func main() {
@@ -37,11 +38,51 @@ func main() {
 
 	if create {
 		fmt.Printf("creating table %s\n", table)
-		if err := db.CreateTable(table, data.NodeMeta{}).Wait(context.Background()); err != nil {
+		if err := db.CreateTable(table, data.NodeMeta{}).Index(repositories.PodIndex).Wait(context.Background()); err != nil {
 			panic(fmt.Errorf("error when creating table %s: %w", table, err))
 		}
 	}
 
+	fmt.Printf("checking for table %s index setup...\n", repositories.PodIndex.Name)
+	description, err := db.Table(table).Describe().Run(context.Background())
+	if err != nil {
+		panic(fmt.Errorf("error when describing table %s: %w", table, err))
+	}
+	hasPodIndex := false
+	for _, index := range description.GSI {
+		if index.Name == repositories.PodIndex.Name {
+			hasPodIndex = true
+		}
+	}
+	if !hasPodIndex {
+		fmt.Printf("table needs index %s to be set up...\n", repositories.PodIndex.Name)
+		if _, err := db.Table(table).UpdateTable().CreateIndex(repositories.PodIndex).Run(context.Background()); err != nil {
+			panic(fmt.Errorf("error when creating index %s for table %s: %w", repositories.PodIndex.Name, table, err))
+		}
+	}
+
+	// pod metas written before PodIndex existed have no PodKey, so they're missing from the index until it's set
+	fmt.Printf("checking for pod metas missing from index %s...\n", repositories.PodIndex.Name)
+	var podMetas []data.PodMeta
+	err = db.Table(table).Scan().
+		Filter("'Type' = ? AND attribute_not_exists(PodKey)", "pod_meta").
+		Project("ID", "TreePath", "Namespace", "Name").
+		All(context.Background(), &podMetas)
+	if err != nil {
+		panic(fmt.Errorf("error when scanning pod metas of table %s: %w", table, err))
+	}
+	for _, podMeta := range podMetas {
+		err := db.Table(table).Update("ID", podMeta.ID).Range("TreePath", podMeta.TreePath).
+			Set("PodKey", data.PodKey(podMeta.Namespace, podMeta.Name)).
+			Run(context.Background())
+		if err != nil {
+			panic(fmt.Errorf("error when setting PodKey of pod meta %s: %w", podMeta.ID, err))
+		}
+	}
+	if len(podMetas) > 0 {
+		fmt.Printf("added %d pod metas to index %s\n", len(podMetas), repositories.PodIndex.Name)
+	}
+
 	fmt.Printf("checking for table TTL setup...\n")
 	result, err := db.Table(table).DescribeTTL().Run(context.Background())
 	if err != nil {